@@ -0,0 +1,3 @@
+-- パスワードのハッシュ値を、アルゴリズム・パラメータ・ソルトを含むエンコード済みの形式で格納できるようにします。
+-- 旧形式(SHA-256の64桁の16進数)のハッシュ値は、次回サインイン時に新しい形式へ移行されます。
+alter table users modify password varchar(255) not null;
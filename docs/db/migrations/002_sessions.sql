@@ -0,0 +1,11 @@
+-- セッションを永続化し、サーバを再起動してもサインイン状態を維持できるようにします。
+-- トークンそのものは保存せず、SHA-256のハッシュ値(64桁の16進数)をキーにします。
+create table sessions (
+    token_hash char(64) not null primary key,
+    user_id int not null,
+    created_at datetime(6) not null,
+    last_used_at datetime(6) not null,
+    index sessions_user_id_index (user_id),
+    index sessions_last_used_at_index (last_used_at),
+    foreign key (user_id) references users(id) on delete cascade
+) default charset = utf8mb4;
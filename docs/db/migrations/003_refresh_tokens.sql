@@ -0,0 +1,11 @@
+-- アクセストークンとリフレッシュトークンを同じsessionsテーブルで管理します。
+-- 一度のサインインで発行されたトークンと、それらをリフレッシュして発行されたトークンは、同じfamily_idをもちます。
+-- 既存のセッションはリフレッシュトークンをもたないため、削除します。（利用者は再度サインインする必要があります。）
+delete from sessions;
+alter table sessions
+    add column kind varchar(16) not null after user_id,
+    add column family_id char(36) not null after kind,
+    add column expires_at datetime(6) not null after last_used_at,
+    add column used boolean not null default false after expires_at,
+    add index sessions_family_id_index (family_id),
+    add index sessions_expires_at_index (expires_at);
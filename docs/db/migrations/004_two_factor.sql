@@ -0,0 +1,6 @@
+-- TOTPによる二要素認証の状態を保存します。
+-- totp_secretは登録中、または有効な場合にのみ設定され、totp_last_stepは同じコードの再使用を防ぐために使用します。
+alter table users
+    add column totp_secret varchar(64) not null default '',
+    add column totp_enabled boolean not null default false,
+    add column totp_last_step bigint not null default 0;
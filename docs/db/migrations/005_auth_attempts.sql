@@ -0,0 +1,8 @@
+-- 認証に失敗した記録を保存し、サーバを再起動してもロックアウトが維持されるようにします。
+-- attempt_keyは、サインインIDまたはリモートアドレスのSHA-256のハッシュ値です。
+create table auth_attempts (
+    attempt_key char(64) not null primary key,
+    failures int not null,
+    last_failure_at datetime(6) not null,
+    index auth_attempts_last_failure_at_index (last_failure_at)
+);
@@ -0,0 +1,4 @@
+-- 利用者が自身のセッションを確認できるよう、トークンを要求したクライアントのリモートアドレスとUser-Agentを保存します。
+alter table sessions
+    add column remote_addr varchar(45) not null default '' after used,
+    add column user_agent varchar(255) not null default '' after remote_addr;
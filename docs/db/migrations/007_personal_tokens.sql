@@ -0,0 +1,17 @@
+-- スクリプトなどからバックアップデータを保存できるよう、ユーザが発行したパーソナルアクセストークンを保存します。
+-- トークンそのものは保存せず、SHA-256のハッシュ値(64桁の16進数)をキーにします。スコープは空白区切りで保存します。
+-- 有効期限が無期限の場合と、まだ利用されていない場合は、expires_atとlast_used_atがnullになります。
+create table personal_tokens (
+    token_hash char(64) not null primary key,
+    id char(36) not null,
+    user_id int not null,
+    name varchar(50) not null,
+    scopes varchar(255) not null,
+    created_at datetime(6) not null,
+    expires_at datetime(6) null,
+    last_used_at datetime(6) null,
+    unique index personal_tokens_id_index (id),
+    index personal_tokens_user_id_index (user_id),
+    index personal_tokens_expires_at_index (expires_at),
+    foreign key (user_id) references users(id) on delete cascade
+) default charset = utf8mb4;
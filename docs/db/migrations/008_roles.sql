@@ -0,0 +1,6 @@
+-- 管理用のAPIを使用できる管理者と、一般のユーザを区別するための役割と、管理者がアカウントを停止するための列を追加します。
+-- 既存のユーザは、すべて一般のユーザになります。管理者は、次のように直接更新して設定します。
+--   update users set role = 'admin' where sign_in_id = '...';
+alter table users
+    add column role varchar(16) not null default 'user' after totp_last_step,
+    add column suspended boolean not null default false after role;
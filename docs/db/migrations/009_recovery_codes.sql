@@ -0,0 +1,8 @@
+-- パスワードを忘れた場合に、パスワードを再設定するためのリカバリーコードを保存します。
+-- コードそのものは保存せず、SHA-256のハッシュ値(64桁の16進数)を保存します。使用したコードは削除します。
+create table recovery_codes (
+    user_id int not null,
+    code_hash char(64) not null,
+    primary key (user_id, code_hash),
+    foreign key (user_id) references users(id) on delete cascade
+) default charset = utf8mb4;
@@ -0,0 +1,17 @@
+-- サインインの成功・失敗、パスワードの変更、アカウントの削除、バックアップデータのダウンロード・削除などのセキュリティ上のイベントを記録します。
+-- アカウントを削除した後も追跡できるよう、usersテーブルへの外部キーは設定しません。
+-- ユーザを特定できないイベント(存在しないサインインIDでのサインインなど)は、user_idとactor_idがnullになります。
+create table audit_events (
+    id bigint not null auto_increment primary key,
+    type varchar(32) not null,
+    user_id int null,
+    actor_id int null,
+    sign_in_id varchar(255) not null default '',
+    remote_addr varchar(45) not null default '',
+    user_agent varchar(255) not null default '',
+    detail varchar(255) not null default '',
+    created_at datetime(6) not null,
+    index audit_events_user_id_index (user_id, created_at),
+    index audit_events_type_index (type, created_at),
+    index audit_events_created_at_index (created_at)
+) default charset = utf8mb4;
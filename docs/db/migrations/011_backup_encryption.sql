@@ -0,0 +1,8 @@
+-- バックアップデータを暗号化して保存するため、バックアップごとのデータ鍵と、データ鍵をラップしたマスター鍵のIDを保存します。
+-- データ鍵は、マスター鍵でラップ(暗号化)して保存します。暗号化する前に保存されたバックアップデータは、どちらもnullになります。
+-- 既存のバックアップデータは、次のコマンドで暗号化できます。
+--   FrogNote_database rotate-master-key
+alter table backups
+    add column data_key varbinary(128) null,
+    add column master_key_id varchar(64) null,
+    add index backups_master_key_id_index (master_key_id);
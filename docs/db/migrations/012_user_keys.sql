@@ -0,0 +1,11 @@
+-- アカウントを削除した際に、バックアップデータを復号できなくする(暗号学的消去)ため、ユーザごとの鍵を保存します。
+-- ユーザの鍵はマスター鍵でラップして保存し、バックアップごとのデータ鍵はユーザの鍵でラップして保存します。
+-- アカウントを削除すると、ユーザの鍵も削除されます。
+create table user_keys (
+    user_id int not null primary key,
+    wrapped_key varbinary(128) not null,
+    master_key_id varchar(64) not null,
+    created_at datetime(6) not null,
+    index user_keys_master_key_id_index (master_key_id),
+    foreign key (user_id) references users(id) on delete cascade
+) default charset = utf8mb4;
@@ -0,0 +1,4 @@
+-- プロフィールで返却するために、ユーザを作成した時刻を記録する列を追加します。
+-- 既存のユーザの作成時刻は分からないため、nullのままにします。
+alter table users
+    add column created_at datetime(6) null after suspended;
@@ -0,0 +1,16 @@
+-- 相互TLS認証で使用できるよう、ユーザが登録したクライアント証明書を保存します。
+-- 証明書そのものは保存せず、match_typeが"subject"の場合はサブジェクトの識別名、"fingerprint"の場合はSHA-256のフィンガープリント(64桁の16進数)をmatch_valueに保存します。
+-- スコープは空白区切りで保存します。まだ利用されていない場合は、last_used_atがnullになります。
+create table client_certificates (
+    id char(36) not null primary key,
+    user_id int not null,
+    name varchar(50) not null,
+    match_type varchar(16) not null,
+    match_value varchar(255) not null,
+    scopes varchar(255) not null,
+    created_at datetime(6) not null,
+    last_used_at datetime(6) null,
+    unique index client_certificates_match_index (match_type, match_value),
+    index client_certificates_user_id_index (user_id),
+    foreign key (user_id) references users(id) on delete cascade
+) default charset = utf8mb4;
@@ -0,0 +1,4 @@
+-- 署名付きのアクセストークンは有効期限まで検証にストアを参照しないため、パスワードの変更などでユーザのトークンをすべて無効化した時刻を記録する列を追加します。
+-- この時刻より前に発行された署名付きのアクセストークンは、有効期限内でも認証されません。
+alter table users
+    add column tokens_revoked_at datetime(6) null after created_at;
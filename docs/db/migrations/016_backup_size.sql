@@ -0,0 +1,6 @@
+-- 暗号化したバックアップデータはノンスと認証タグの分だけ大きくなるため、使用容量の計算に使う、暗号化する前の大きさを記録する列を追加します。
+-- 既存のバックアップデータは、暗号化していなければそのままの大きさ、暗号化していればノンス(12バイト)と認証タグ(16バイト)を除いた大きさを記録します。
+alter table backups
+    add column size bigint null;
+update backups set size = length(backup) where data_key is null;
+update backups set size = length(backup) - 28 where data_key is not null;
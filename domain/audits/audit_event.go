@@ -0,0 +1,36 @@
+package audits
+
+import (
+	"FrogNote_database/domain/users"
+	"time"
+)
+
+// AuditEvent は、監査ログに記録するセキュリティ上のイベントを表現する構造体です。
+// アカウントを削除しても追跡できるよう、ユーザIDに加えて、サインインIDも記録します。
+type AuditEvent struct {
+	Id   int64
+	Type EventType
+	// UserId は、イベントの対象のユーザです。存在しないサインインIDでのサインインなど、ユーザを特定できない場合はnilです。
+	UserId *users.UserId
+	// ActorId は、リクエストを認証したユーザです。管理者が操作した場合は、UserIdと異なります。サインインなど、認証前のリクエストではnilです。
+	ActorId *users.UserId
+	// SignInId は、サインインで入力されたサインインID、またはイベントの時点の対象のユーザのサインインIDです。記録する時点でわからない場合は空です。
+	SignInId   string
+	RemoteAddr string
+	UserAgent  string
+	// Detail は、バックアップIDなど、イベントの補足情報です。
+	Detail    string
+	CreatedAt time.Time
+}
+
+// Query は、監査ログを検索する条件を表現する構造体です。ゼロ値の項目は、条件に含めません。
+type Query struct {
+	UserId *users.UserId
+	Type   EventType
+	// Since は、この時刻以降のイベントに絞り込みます。
+	Since time.Time
+	// Until は、この時刻より前のイベントに絞り込みます。
+	Until time.Time
+	// Limit は、取得するイベントの最大の数です。
+	Limit int
+}
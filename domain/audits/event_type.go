@@ -0,0 +1,59 @@
+package audits
+
+import "fmt"
+
+// EventType は、監査ログに記録するイベントの種類を表現する型です。
+type EventType string
+
+const (
+	// EventSignInSucceeded は、サインインに成功したことを表します。
+	EventSignInSucceeded EventType = "sign_in_succeeded"
+	// EventSignInFailed は、パスワードや二要素認証のコードの誤りなどで、サインインに失敗したことを表します。
+	EventSignInFailed EventType = "sign_in_failed"
+	// EventSignOut は、サインアウトしたことを表します。
+	EventSignOut EventType = "sign_out"
+	// EventPasswordChanged は、ユーザ情報の編集でパスワードを変更したことを表します。
+	EventPasswordChanged EventType = "password_changed"
+	// EventPasswordReset は、リカバリーコードでパスワードを再設定したことを表します。
+	EventPasswordReset EventType = "password_reset"
+	// EventPasswordResetFailed は、リカバリーコードの誤りなどで、パスワードの再設定に失敗したことを表します。
+	EventPasswordResetFailed EventType = "password_reset_failed"
+	// EventAccountDeleted は、アカウントを削除したことを表します。
+	EventAccountDeleted EventType = "account_deleted"
+	// EventAccountSuspended は、管理者がアカウントを停止したことを表します。
+	EventAccountSuspended EventType = "account_suspended"
+	// EventAccountUnsuspended は、管理者がアカウントの停止を解除したことを表します。
+	EventAccountUnsuspended EventType = "account_unsuspended"
+	// EventTwoFactorFailed は、二要素認証の有効化や無効化で、認証アプリのコードを誤ったことを表します。
+	EventTwoFactorFailed EventType = "two_factor_failed"
+	// EventBackupDownloaded は、バックアップデータをダウンロードしたことを表します。
+	EventBackupDownloaded EventType = "backup_downloaded"
+	// EventBackupDeleted は、バックアップデータを削除したことを表します。
+	EventBackupDeleted EventType = "backup_deleted"
+)
+
+// eventTypes は、既知のイベントの種類の一覧です。
+var eventTypes = []EventType{
+	EventSignInSucceeded,
+	EventSignInFailed,
+	EventSignOut,
+	EventPasswordChanged,
+	EventPasswordReset,
+	EventPasswordResetFailed,
+	EventAccountDeleted,
+	EventAccountSuspended,
+	EventAccountUnsuspended,
+	EventTwoFactorFailed,
+	EventBackupDownloaded,
+	EventBackupDeleted,
+}
+
+// NewEventType は、文字列からEventTypeを初期化し、返却します。未知の種類の場合は、エラーを返却します。
+func NewEventType(value string) (eventType EventType, err error) {
+	for _, known := range eventTypes {
+		if EventType(value) == known {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type: %s", value)
+}
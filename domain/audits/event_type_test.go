@@ -0,0 +1,24 @@
+package audits_test
+
+import (
+	"FrogNote_database/domain/audits"
+	"testing"
+)
+
+func TestNewEventType(t *testing.T) {
+	t.Run("有効値", func(t *testing.T) {
+		for _, value := range []string{"sign_in_succeeded", "sign_in_failed", "password_reset", "two_factor_failed", "backup_deleted"} {
+			eventType, err := audits.NewEventType(value)
+			if err != nil || string(eventType) != value {
+				t.Error(value)
+			}
+		}
+	})
+	t.Run("無効値", func(t *testing.T) {
+		for _, value := range []string{"", "SIGN_OUT", "sign-out", "login"} {
+			if _, err := audits.NewEventType(value); err == nil {
+				t.Error(value)
+			}
+		}
+	})
+}
@@ -0,0 +1,12 @@
+package backups
+
+import "FrogNote_database/domain/users"
+
+// StorageUsage は、ユーザがバックアップデータの保存に使用している容量を表現する構造体です。
+type StorageUsage struct {
+	UserId users.UserId
+	// BackupCount は、保存しているバックアップデータの数です。
+	BackupCount int
+	// TotalBytes は、保存しているバックアップデータ（本体）の合計のバイト数です。暗号化して保存している場合も、暗号化する前の大きさで計算します。
+	TotalBytes int64
+}
@@ -0,0 +1,42 @@
+package users
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// PasswordDenylist は、よく使われるために禁止するパスワードの集合です。大文字と小文字は区別しません。
+type PasswordDenylist map[string]struct{}
+
+// Contains は、パスワードが禁止されている場合にtrueを返却します。
+func (denylist PasswordDenylist) Contains(password string) bool {
+	_, exists := denylist[strings.ToLower(password)]
+	return exists
+}
+
+// NewPasswordDenylist は、パスワードのスライスからPasswordDenylistを初期化し、返却します。
+func NewPasswordDenylist(passwords []string) PasswordDenylist {
+	denylist := make(PasswordDenylist, len(passwords))
+	for _, password := range passwords {
+		denylist[strings.ToLower(password)] = struct{}{}
+	}
+	return denylist
+}
+
+// ReadPasswordDenylist は、1行に1つのパスワードが書かれたテキストからPasswordDenylistを読み込みます。空行と#から始まる行は無視します。
+func ReadPasswordDenylist(reader io.Reader) (denylist PasswordDenylist, err error) {
+	var passwords []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords = append(passwords, line)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewPasswordDenylist(passwords), nil
+}
@@ -0,0 +1,146 @@
+package users
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// CharacterClass は、パスワードに含まれる文字の種類を表現する型です。
+type CharacterClass string
+
+const (
+	// CharacterClassLower は、英小文字です。
+	CharacterClassLower CharacterClass = "lower"
+	// CharacterClassUpper は、英大文字です。
+	CharacterClassUpper CharacterClass = "upper"
+	// CharacterClassDigit は、数字です。
+	CharacterClassDigit CharacterClass = "digit"
+	// CharacterClassSymbol は、英数字以外の文字です。
+	CharacterClassSymbol CharacterClass = "symbol"
+)
+
+// minSignInIdLengthToForbid は、パスワードに含まれていないかを確認するサインインIDの最小の文字数です。短すぎるサインインIDは、偶然含まれることが多いためです。
+const minSignInIdLengthToForbid = 3
+
+// NewCharacterClass は、文字列からCharacterClassを初期化し、返却します。
+func NewCharacterClass(value string) (class CharacterClass, err error) {
+	class = CharacterClass(value)
+	switch class {
+	case CharacterClassLower, CharacterClassUpper, CharacterClassDigit, CharacterClassSymbol:
+		return class, nil
+	}
+	return "", fmt.Errorf("unknown character class: %s", value)
+}
+
+// PasswordPolicy は、パスワードのポリシーを表現する構造体です。文字数は、バイト数ではなく文字の数です。
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// MinCharacterClasses は、含まれている必要がある文字種の数です。
+	MinCharacterClasses int
+	// RequiredCharacterClasses は、必ず含まれている必要がある文字種です。
+	RequiredCharacterClasses []CharacterClass
+	// Denylist は、禁止するパスワードです。
+	Denylist PasswordDenylist
+	// ForbidSignInId は、パスワードにサインインIDが含まれることを禁止するかを表します。
+	ForbidSignInId bool
+}
+
+// SignInIdPolicy は、サインインIDのポリシーを表現する構造体です。文字数は、バイト数ではなく文字の数です。
+type SignInIdPolicy struct {
+	MinLength int
+	MaxLength int
+	// Pattern は、サインインIDが一致する必要がある正規表現です。nilの場合は確認しません。
+	Pattern *regexp.Regexp
+}
+
+// Policy は、パスワードとサインインIDのポリシーを表現する構造体です。
+// NewUserやNewSignInIdが確認する最低限の制約に加えて、ユーザの作成時やパスワードの変更時に確認します。
+type Policy struct {
+	Password PasswordPolicy
+	SignInId SignInIdPolicy
+}
+
+// Validate は、サインインIDとパスワードがポリシーを満たすかを確認します。違反している場合は、すべての違反を含む*PolicyViolationErrorを返却します。
+func (policy *Policy) Validate(signInId string, password string) error {
+	violations := append(policy.ValidateSignInId(signInId), policy.ValidatePassword(password, signInId)...)
+	if len(violations) != 0 {
+		return &PolicyViolationError{Violations: violations}
+	}
+	return nil
+}
+
+// ValidateSignInId は、サインインIDのポリシーへの違反をすべて返却します。
+func (policy *Policy) ValidateSignInId(signInId string) (violations []Violation) {
+	rules := &policy.SignInId
+	violations = validateLength("signInId", signInId, rules.MinLength, rules.MaxLength)
+	if rules.Pattern != nil && !rules.Pattern.MatchString(signInId) {
+		violations = append(violations, Violation{Field: "signInId", Rule: RulePattern})
+	}
+	return violations
+}
+
+// ValidatePassword は、パスワードのポリシーへの違反をすべて返却します。
+func (policy *Policy) ValidatePassword(password string, signInId string) (violations []Violation) {
+	rules := &policy.Password
+	violations = validateLength("password", password, rules.MinLength, rules.MaxLength)
+
+	classes := getCharacterClasses(password)
+	if len(classes) < rules.MinCharacterClasses {
+		violations = append(violations, Violation{Field: "password", Rule: RuleCharacterClasses, Limit: rules.MinCharacterClasses})
+	}
+	for _, class := range rules.RequiredCharacterClasses {
+		if !classes[class] {
+			violations = append(violations, Violation{Field: "password", Rule: RuleRequiredCharacterClass, CharacterClass: class})
+		}
+	}
+
+	if rules.Denylist.Contains(password) {
+		violations = append(violations, Violation{Field: "password", Rule: RuleDenylisted})
+	}
+	if rules.ForbidSignInId && utf8.RuneCountInString(signInId) >= minSignInIdLengthToForbid &&
+		strings.Contains(strings.ToLower(password), strings.ToLower(signInId)) {
+		violations = append(violations, Violation{Field: "password", Rule: RuleContainsSignInId})
+	}
+	return violations
+}
+
+// validateLength は、文字数が最小値以上、最大値以下かを確認します。最大値が0の場合は、最大値を確認しません。
+func validateLength(field string, value string, minLength int, maxLength int) (violations []Violation) {
+	length := utf8.RuneCountInString(value)
+	if length < minLength {
+		violations = append(violations, Violation{Field: field, Rule: RuleMinLength, Limit: minLength})
+	}
+	if maxLength > 0 && length > maxLength {
+		violations = append(violations, Violation{Field: field, Rule: RuleMaxLength, Limit: maxLength})
+	}
+	return violations
+}
+
+// getCharacterClasses は、文字列に含まれている文字種を返却します。
+func getCharacterClasses(value string) map[CharacterClass]bool {
+	classes := make(map[CharacterClass]bool)
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z':
+			classes[CharacterClassLower] = true
+		case r >= 'A' && r <= 'Z':
+			classes[CharacterClassUpper] = true
+		case r >= '0' && r <= '9':
+			classes[CharacterClassDigit] = true
+		default:
+			classes[CharacterClassSymbol] = true
+		}
+	}
+	return classes
+}
+
+// DefaultPolicy は、既定のポリシーを返却します。パスワードは8文字以上64文字以内で、サインインIDを含むことはできません。サインインIDは1文字以上30文字以内です。
+func DefaultPolicy() *Policy {
+	return &Policy{
+		Password: PasswordPolicy{MinLength: 8, MaxLength: 64, Denylist: PasswordDenylist{}, ForbidSignInId: true},
+		SignInId: SignInIdPolicy{MinLength: 1, MaxLength: 30},
+	}
+}
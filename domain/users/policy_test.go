@@ -0,0 +1,96 @@
+package users_test
+
+import (
+	"FrogNote_database/domain/users"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// hasViolation は、違反の一覧に指定した規則の違反が含まれているかを返却します。
+func hasViolation(violations []users.Violation, field string, rule users.ViolationRule) bool {
+	for _, violation := range violations {
+		if violation.Field == field && violation.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPolicyValidatePassword(t *testing.T) {
+	policy := users.DefaultPolicy()
+	policy.Password.MinCharacterClasses = 2
+	policy.Password.RequiredCharacterClasses = []users.CharacterClass{users.CharacterClassDigit}
+	policy.Password.Denylist = users.NewPasswordDenylist([]string{"Password123"})
+	t.Run("正常", func(t *testing.T) {
+		if violations := policy.ValidatePassword("correct horse 1", "frog"); len(violations) != 0 {
+			t.Error(violations)
+		}
+		// マルチバイト文字は1文字として数える
+		if violations := policy.ValidatePassword("かえるのうた12", "frog"); len(violations) != 0 {
+			t.Error(violations)
+		}
+	})
+	t.Run("異常", func(t *testing.T) {
+		type testCase struct {
+			testName string
+			password string
+			rule     users.ViolationRule
+		}
+		testCases := []testCase{
+			{testName: "7文字のパスワード", password: "abcdef1", rule: users.RuleMinLength},
+			{testName: "65文字のパスワード", password: strings.Repeat("a", 64) + "1", rule: users.RuleMaxLength},
+			{testName: "文字種が足りないパスワード", password: "12345678", rule: users.RuleCharacterClasses},
+			{testName: "数字を含まないパスワード", password: "abcdefgh!", rule: users.RuleRequiredCharacterClass},
+			{testName: "禁止されたパスワード", password: "password123", rule: users.RuleDenylisted},
+			{testName: "サインインIDを含むパスワード", password: "myFROG1234", rule: users.RuleContainsSignInId},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.testName, func(t *testing.T) {
+				if !hasViolation(policy.ValidatePassword(testCase.password, "frog"), "password", testCase.rule) {
+					t.Error()
+				}
+			})
+		}
+	})
+	t.Run("違反をすべて返却する", func(t *testing.T) {
+		violations := policy.ValidatePassword("frog", "frog")
+		if len(violations) != 4 {
+			t.Error(violations)
+		}
+	})
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := users.DefaultPolicy()
+	policy.SignInId.Pattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+	t.Run("正常", func(t *testing.T) {
+		if err := policy.Validate("frog_note", "correct horse"); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("異常", func(t *testing.T) {
+		err := policy.Validate("Frog-Note", "short")
+		var violationErr *users.PolicyViolationError
+		if !errors.As(err, &violationErr) {
+			t.Error(err)
+			return
+		}
+		if !hasViolation(violationErr.Violations, "signInId", users.RulePattern) ||
+			!hasViolation(violationErr.Violations, "password", users.RuleMinLength) {
+			t.Error(violationErr.Violations)
+		}
+	})
+}
+
+func TestReadPasswordDenylist(t *testing.T) {
+	denylist, err := users.ReadPasswordDenylist(strings.NewReader("# よく使われるパスワード\n123456\n\n  qwerty  \n"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !denylist.Contains("123456") || !denylist.Contains("QWERTY") || denylist.Contains("# よく使われるパスワード") {
+		t.Error()
+	}
+}
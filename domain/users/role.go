@@ -0,0 +1,22 @@
+package users
+
+import "fmt"
+
+// Role は、ユーザの役割を表現する型です。役割によって、使用できるAPIが決まります。
+type Role string
+
+const (
+	// RoleUser は、自身のデータのみを操作できる一般のユーザです。
+	RoleUser Role = "user"
+	// RoleAdmin は、管理用のAPIで、すべてのユーザを管理できる管理者です。
+	RoleAdmin Role = "admin"
+)
+
+// NewRole は、文字列からRoleを初期化し、返却します。未知の役割の場合は、エラーを返却します。
+func NewRole(value string) (role Role, err error) {
+	switch Role(value) {
+	case RoleUser, RoleAdmin:
+		return Role(value), nil
+	}
+	return "", fmt.Errorf("unknown role: %s", value)
+}
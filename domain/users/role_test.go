@@ -0,0 +1,24 @@
+package users_test
+
+import (
+	"FrogNote_database/domain/users"
+	"testing"
+)
+
+func TestNewRole(t *testing.T) {
+	t.Run("有効値", func(t *testing.T) {
+		for _, value := range []string{"user", "admin"} {
+			role, err := users.NewRole(value)
+			if err != nil || string(role) != value {
+				t.Error(value)
+			}
+		}
+	})
+	t.Run("無効値", func(t *testing.T) {
+		for _, value := range []string{"", "Admin", "root"} {
+			if _, err := users.NewRole(value); err == nil {
+				t.Error(value)
+			}
+		}
+	})
+}
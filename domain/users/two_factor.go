@@ -0,0 +1,16 @@
+package users
+
+// TwoFactor は、TOTPによる二要素認証の状態を表現する構造体です。
+type TwoFactor struct {
+	// Secret は、TOTPの共有鍵(Base32)です。登録中、または有効な場合に設定されます。
+	Secret string
+	// Enabled は、二要素認証が有効かを表します。登録してからコードを確認するまではfalseです。
+	Enabled bool
+	// LastUsedStep は、最後に使用されたコードの時間ステップです。同じコードが再び使用されるのを防ぐために使用します。
+	LastUsedStep int64
+}
+
+// IsEnrolling は、二要素認証を登録中で、コードの確認を待っている場合にtrueを返却します。
+func (twoFactor *TwoFactor) IsEnrolling() bool {
+	return twoFactor.Secret != "" && !twoFactor.Enabled
+}
@@ -1,6 +1,10 @@
 package users
 
-import "errors"
+import (
+	"errors"
+	"time"
+	"unicode/utf8"
+)
 
 // User は、ユーザ情報を表現する構造体です。
 type User struct {
@@ -8,17 +12,50 @@ type User struct {
 	SignInId   SignInId
 	ScreenName string
 	Password   string
+	// TwoFactor は、二要素認証の状態です。
+	TwoFactor TwoFactor
+	// Role は、ユーザの役割です。
+	Role Role
+	// Suspended は、管理者によってアカウントが停止されているかを表します。停止中はサインインできません。
+	Suspended bool
+	// CreatedAt は、ユーザを作成した時刻です。作成時刻を記録する前に作成されたユーザの場合は、ゼロ値です。
+	CreatedAt time.Time
+}
+
+// IsAdmin は、管理者の場合にtrueを返却します。
+func (user *User) IsAdmin() bool {
+	return user.Role == RoleAdmin
 }
 
 // NewUser は、User構造体を初期化し、返却します。screenNameは1文字以上30文字以内、passwordは1文字以上64文字以内です。
 func NewUser(id UserId, screenName string, signInId SignInId, password string) (user *User, err error) {
-	screenNameLen := len(screenName)
-	passwordLen := len(password)
-	if screenNameLen > 30 || screenNameLen < 1 {
-		return nil, errors.New("'screenName' must be between 1 to 30 characters")
+	if err = validateScreenName(screenName); err != nil {
+		return nil, err
 	}
+	// ポリシーと同様に、バイト数ではなく文字数で数える。
+	passwordLen := utf8.RuneCountInString(password)
 	if passwordLen > 64 || passwordLen < 1 {
 		return nil, errors.New("'password' must be between 1 to 64 characters")
 	}
-	return &User{Id: id, ScreenName: screenName, SignInId: signInId, Password: password}, nil
+	return &User{Id: id, ScreenName: screenName, SignInId: signInId, Password: password, Role: RoleUser}, nil
+}
+
+// NewUserWithPasswordHash は、ハッシュ化済みのパスワードをもつUser構造体を初期化し、返却します。screenNameは1文字以上30文字以内です。
+func NewUserWithPasswordHash(id UserId, screenName string, signInId SignInId, passwordHash string) (user *User, err error) {
+	if err = validateScreenName(screenName); err != nil {
+		return nil, err
+	}
+	if passwordHash == "" {
+		return nil, errors.New("'passwordHash' must not be empty")
+	}
+	return &User{Id: id, ScreenName: screenName, SignInId: signInId, Password: passwordHash, Role: RoleUser}, nil
+}
+
+// validateScreenName は、スクリーンネームが1文字以上30文字以内かを検証します。
+func validateScreenName(screenName string) error {
+	screenNameLen := len(screenName)
+	if screenNameLen > 30 || screenNameLen < 1 {
+		return errors.New("'screenName' must be between 1 to 30 characters")
+	}
+	return nil
 }
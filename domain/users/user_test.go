@@ -55,3 +55,24 @@ func TestNewUser(t *testing.T) {
 		}
 	})
 }
+
+func TestNewUserWithPasswordHash(t *testing.T) {
+	signInId, _ := users.NewSignInId("aiueo")
+	userId := *users.NewUserId(1)
+	// ハッシュ値は64文字を超えることがある
+	passwordHash := "$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"
+	t.Run("正常", func(t *testing.T) {
+		user, err := users.NewUserWithPasswordHash(userId, "screenName", *signInId, passwordHash)
+		if err != nil || user.Password != passwordHash {
+			t.Error(err)
+		}
+	})
+	t.Run("異常", func(t *testing.T) {
+		if _, err := users.NewUserWithPasswordHash(userId, "", *signInId, passwordHash); err == nil {
+			t.Error()
+		}
+		if _, err := users.NewUserWithPasswordHash(userId, "screenName", *signInId, ""); err == nil {
+			t.Error()
+		}
+	})
+}
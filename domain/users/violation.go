@@ -0,0 +1,73 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ViolationRule は、違反したポリシーの規則を表現する型です。
+type ViolationRule string
+
+const (
+	// RuleMinLength は、文字数が最小値に満たないことを表します。
+	RuleMinLength ViolationRule = "minLength"
+	// RuleMaxLength は、文字数が最大値を超えていることを表します。
+	RuleMaxLength ViolationRule = "maxLength"
+	// RuleCharacterClasses は、含まれる文字種の数が足りないことを表します。
+	RuleCharacterClasses ViolationRule = "characterClasses"
+	// RuleRequiredCharacterClass は、必須の文字種が含まれていないことを表します。
+	RuleRequiredCharacterClass ViolationRule = "requiredCharacterClass"
+	// RuleDenylisted は、よく使われるパスワードとして禁止されていることを表します。
+	RuleDenylisted ViolationRule = "denylisted"
+	// RuleContainsSignInId は、パスワードにサインインIDが含まれていることを表します。
+	RuleContainsSignInId ViolationRule = "containsSignInId"
+	// RulePattern は、使用できない文字が含まれていることを表します。
+	RulePattern ViolationRule = "pattern"
+)
+
+// Violation は、ポリシーへの違反を表現する構造体です。
+type Violation struct {
+	// Field は、違反した項目です。"password"または"signInId"です。
+	Field string
+	// Rule は、違反した規則です。
+	Rule ViolationRule
+	// Limit は、文字数や文字種の数の規則に違反した場合の、規則の値です。
+	Limit int
+	// CharacterClass は、必須の文字種の規則に違反した場合の、含まれていない文字種です。
+	CharacterClass CharacterClass
+}
+
+// Message は、違反の内容を説明する文字列を返却します。
+func (violation *Violation) Message() string {
+	switch violation.Rule {
+	case RuleMinLength:
+		return fmt.Sprintf("'%s' must be at least %d characters", violation.Field, violation.Limit)
+	case RuleMaxLength:
+		return fmt.Sprintf("'%s' must be at most %d characters", violation.Field, violation.Limit)
+	case RuleCharacterClasses:
+		return fmt.Sprintf("'%s' must contain at least %d of lowercase letters, uppercase letters, digits and symbols", violation.Field, violation.Limit)
+	case RuleRequiredCharacterClass:
+		return fmt.Sprintf("'%s' must contain %s characters", violation.Field, violation.CharacterClass)
+	case RuleDenylisted:
+		return fmt.Sprintf("'%s' is too common", violation.Field)
+	case RuleContainsSignInId:
+		return fmt.Sprintf("'%s' must not contain the sign-in ID", violation.Field)
+	case RulePattern:
+		return fmt.Sprintf("'%s' contains characters that are not allowed", violation.Field)
+	}
+	return fmt.Sprintf("'%s' is invalid", violation.Field)
+}
+
+// PolicyViolationError は、ポリシーに違反していることを表すエラーです。違反をすべて含みます。
+type PolicyViolationError struct {
+	Violations []Violation
+}
+
+// Error は、違反の内容をまとめた文字列を返却します。
+func (err *PolicyViolationError) Error() string {
+	messages := make([]string, len(err.Violations))
+	for i, violation := range err.Violations {
+		messages[i] = violation.Message()
+	}
+	return strings.Join(messages, ", ")
+}
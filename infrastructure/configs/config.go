@@ -0,0 +1,463 @@
+package configs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config は、サーバの設定を表現する構造体です。
+type Config struct {
+	// Port は、サーバが待ち受けるポート番号です。
+	Port int `json:"port"`
+	// Sessions は、セッションに関する設定です。
+	Sessions SessionsConfig `json:"sessions"`
+	// Auth は、サインイン時の認証に関する設定です。
+	Auth AuthConfig `json:"auth"`
+	// Policy は、パスワードとサインインIDのポリシーに関する設定です。
+	Policy PolicyConfig `json:"policy"`
+	// PersonalTokens は、パーソナルアクセストークンに関する設定です。
+	PersonalTokens PersonalTokensConfig `json:"personalTokens"`
+	// Encryption は、バックアップデータの暗号化に関する設定です。
+	Encryption EncryptionConfig `json:"encryption"`
+	// RateLimit は、リクエスト数の制限に関する設定です。
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// TLS は、HTTPSでサーブするための設定です。
+	TLS TLSConfig `json:"tls"`
+	// CORS は、ブラウザのクライアントからのオリジン間リソース共有に関する設定です。
+	CORS CORSConfig `json:"cors"`
+	// SecurityHeaders は、セキュリティに関するレスポンスヘッダの設定です。
+	SecurityHeaders SecurityHeadersConfig `json:"securityHeaders"`
+}
+
+// SecurityHeadersConfig は、セキュリティに関するレスポンスヘッダの設定を表現する構造体です。
+type SecurityHeadersConfig struct {
+	// ContentSecurityPolicy は、HTMLのレスポンスに設定するContent-Security-Policyヘッダの値です。空文字列の場合は設定しません。
+	ContentSecurityPolicy string `json:"contentSecurityPolicy"`
+	// ReferrerPolicy は、Referrer-Policyヘッダの値です。空文字列の場合は設定しません。
+	ReferrerPolicy string `json:"referrerPolicy"`
+	// StripHeaders は、サーバを特定できる情報を含むため、レスポンスから削除するヘッダです。
+	StripHeaders []string `json:"stripHeaders"`
+}
+
+// CORSConfig は、オリジン間リソース共有(CORS)に関する設定を表現する構造体です。
+type CORSConfig struct {
+	// AllowedOrigins は、許可するオリジンです。"https://*.example.com"のように、サブドメインをワイルドカードで指定できます。
+	// "*"はすべてのオリジンを許可しますが、allowCredentialsと同時には指定できません。
+	AllowedOrigins []string `json:"allowedOrigins"`
+	// AllowedMethods は、許可するHTTPメソッドです。
+	AllowedMethods []string `json:"allowedMethods"`
+	// AllowedHeaders は、許可するリクエストヘッダです。
+	AllowedHeaders []string `json:"allowedHeaders"`
+	// ExposedHeaders は、JavaScriptから読み取れるようにするレスポンスヘッダです。
+	ExposedHeaders []string `json:"exposedHeaders"`
+	// AllowCredentials は、クッキーを含むリクエストを許可するかを表します。クッキーモードでは、trueにする必要があります。
+	AllowCredentials bool `json:"allowCredentials"`
+	// MaxAge は、プリフライトリクエストの結果をブラウザがキャッシュできる時間です。
+	MaxAge Duration `json:"maxAge"`
+}
+
+// TLSConfig は、HTTPSでサーブするための設定を表現する構造体です。
+type TLSConfig struct {
+	// Enabled は、HTTPSでサーブするかを表します。falseの場合は、HTTPでサーブします。
+	Enabled bool `json:"enabled"`
+	// CertFile は、PEM形式の証明書(中間証明書を含む)のファイルのパスです。
+	CertFile string `json:"certFile"`
+	// KeyFile は、PEM形式の秘密鍵のファイルのパスです。
+	KeyFile string `json:"keyFile"`
+	// SelfSigned は、証明書のファイルが存在しない場合に、開発用の自己署名証明書を生成するかを表します。本番環境ではfalseにします。
+	SelfSigned bool `json:"selfSigned"`
+	// RedirectPort は、HTTPのリクエストをHTTPSにリダイレクトするポート番号です。0の場合はリダイレクトしません。
+	RedirectPort int `json:"redirectPort"`
+	// HSTS は、Strict-Transport-Securityヘッダに関する設定です。
+	HSTS HSTSConfig `json:"hsts"`
+	// ReloadInterval は、証明書のファイルの更新を確認し、読み込み直す間隔です。0の場合は読み込み直しません。
+	ReloadInterval Duration `json:"reloadInterval"`
+	// ClientAuth は、クライアント証明書による相互TLS認証に関する設定です。
+	ClientAuth ClientAuthConfig `json:"clientAuth"`
+}
+
+// ClientAuthConfig は、クライアント証明書による相互TLS認証に関する設定を表現する構造体です。
+type ClientAuthConfig struct {
+	// Port は、クライアント証明書を要求するHTTPSで待ち受けるポート番号です。0の場合は待ち受けません。
+	Port int `json:"port"`
+	// CAFile は、クライアント証明書を検証するための、PEM形式の認証局の証明書のファイルのパスです。
+	CAFile string `json:"caFile"`
+	// Store は、ユーザが登録したクライアント証明書の保存先です。"memory"または"database"を指定します。
+	Store string `json:"store"`
+}
+
+// HSTSConfig は、Strict-Transport-Securityヘッダに関する設定を表現する構造体です。
+type HSTSConfig struct {
+	// MaxAge は、ブラウザがHTTPSでのみ接続する期間です。0の場合はヘッダを設定しません。
+	MaxAge Duration `json:"maxAge"`
+	// IncludeSubDomains は、サブドメインにも適用するかを表します。
+	IncludeSubDomains bool `json:"includeSubDomains"`
+}
+
+// RateLimitConfig は、リクエスト数の制限に関する設定を表現する構造体です。
+// 制限は、認証されたリクエストはユーザIDごと、それ以外はリモートアドレスごとに適用されます。
+type RateLimitConfig struct {
+	// Default は、Routesに含まれないハンドラの制限です。それらのハンドラへのリクエストは、まとめて数えられます。
+	Default RateLimitRuleConfig `json:"default"`
+	// Routes は、ハンドラのパターンごとの制限です。既定のルートの制限を解除する場合は、requestsに0を指定します。
+	Routes map[string]RateLimitRuleConfig `json:"routes"`
+}
+
+// RateLimitRuleConfig は、リクエスト数の制限の設定を表現する構造体です。
+type RateLimitRuleConfig struct {
+	// Requests は、Perの間に受け付けるリクエストの数です。0の場合は制限しません。
+	Requests int `json:"requests"`
+	// Per は、Requestsの数のリクエストを受け付ける時間です。
+	Per Duration `json:"per"`
+	// Burst は、連続して受け付けられるリクエストの数です。0の場合は、Requestsと同じになります。
+	Burst int `json:"burst"`
+}
+
+// validate は、制限の設定値が有効かを検証します。
+func (rule RateLimitRuleConfig) validate() error {
+	if rule.Requests < 0 || rule.Burst < 0 {
+		return errors.New("rate limit requests and burst must not be negative")
+	}
+	if rule.Requests > 0 && rule.Per.Duration <= 0 {
+		return errors.New("rate limit period must be positive")
+	}
+	return nil
+}
+
+// EncryptionConfig は、バックアップデータの暗号化に関する設定を表現する構造体です。
+type EncryptionConfig struct {
+	// MasterKeys は、バックアップデータを暗号化した鍵をラップするマスター鍵です。空の場合は、バックアップデータを暗号化しません。
+	MasterKeys []MasterKeyConfig `json:"masterKeys"`
+	// ActiveMasterKeyId は、新しい鍵のラップに使用するマスター鍵のIDです。
+	ActiveMasterKeyId string `json:"activeMasterKeyId"`
+}
+
+// MasterKeyConfig は、マスター鍵の設定を表現する構造体です。
+type MasterKeyConfig struct {
+	// Id は、鍵のIDです。ラップした鍵とともに保存し、復号時に鍵を選択するために使用します。
+	Id string `json:"id"`
+	// Path は、32バイトの鍵をそのまま書き込んだファイルのパスです。
+	Path string `json:"path"`
+}
+
+// PersonalTokensConfig は、パーソナルアクセストークンに関する設定を表現する構造体です。
+type PersonalTokensConfig struct {
+	// Store は、パーソナルアクセストークンの保存先です。"memory"または"database"を指定します。
+	Store string `json:"store"`
+	// MaxLifetime は、発行できるトークンの有効期限の上限です。0の場合は、無期限のトークンも発行できます。
+	MaxLifetime Duration `json:"maxLifetime"`
+}
+
+// PolicyConfig は、パスワードとサインインIDのポリシーに関する設定を表現する構造体です。
+type PolicyConfig struct {
+	Password PasswordPolicyConfig `json:"password"`
+	SignInId SignInIdPolicyConfig `json:"signInId"`
+}
+
+// PasswordPolicyConfig は、パスワードのポリシーに関する設定を表現する構造体です。
+type PasswordPolicyConfig struct {
+	// MinLength は、パスワードの最小の文字数です。
+	MinLength int `json:"minLength"`
+	// MaxLength は、パスワードの最大の文字数です。64以下を指定します。
+	MaxLength int `json:"maxLength"`
+	// MinCharacterClasses は、英小文字、英大文字、数字、記号のうち、含まれている必要がある文字種の数です。
+	MinCharacterClasses int `json:"minCharacterClasses"`
+	// RequiredCharacterClasses は、必ず含まれている必要がある文字種です。"lower"、"upper"、"digit"、"symbol"を指定します。
+	RequiredCharacterClasses []string `json:"requiredCharacterClasses"`
+	// DenylistPath は、禁止するパスワードを1行に1つずつ書いたファイルのパスです。空文字列の場合は使用しません。
+	DenylistPath string `json:"denylistPath"`
+	// ForbidSignInId は、パスワードにサインインIDが含まれることを禁止するかを表します。
+	ForbidSignInId bool `json:"forbidSignInId"`
+}
+
+// SignInIdPolicyConfig は、サインインIDのポリシーに関する設定を表現する構造体です。
+type SignInIdPolicyConfig struct {
+	// MinLength は、サインインIDの最小の文字数です。1以上を指定します。
+	MinLength int `json:"minLength"`
+	// MaxLength は、サインインIDの最大の文字数です。30以下を指定します。
+	MaxLength int `json:"maxLength"`
+	// Pattern は、サインインIDが一致する必要がある正規表現です。空文字列の場合は確認しません。
+	Pattern string `json:"pattern"`
+}
+
+// AuthConfig は、サインイン時の認証に関する設定を表現する構造体です。
+type AuthConfig struct {
+	// ChallengeTimeout は、チャレンジ&レスポンス認証のナンスの発行から無効になるまでの時間です。
+	ChallengeTimeout Duration `json:"challengeTimeout"`
+	// TwoFactorTimeout は、パスワードの照合後、二要素認証のコードを入力するまでの制限時間です。
+	TwoFactorTimeout Duration `json:"twoFactorTimeout"`
+	// Lockout は、認証の失敗によるロックアウトに関する設定です。
+	Lockout LockoutConfig `json:"lockout"`
+	// Cookie は、ブラウザのクライアントのために、トークンをクッキーで受け渡すクッキーモードに関する設定です。
+	Cookie CookieConfig `json:"cookie"`
+}
+
+// CookieConfig は、クッキーモードで発行するクッキーの属性に関する設定を表現する構造体です。
+type CookieConfig struct {
+	// Secure は、HTTPSの場合のみクッキーを送信するかを表します。HTTPで開発する場合のみfalseにします。
+	Secure bool `json:"secure"`
+	// SameSite は、クッキーのSameSite属性です。"strict"、"lax"、"none"のいずれかを指定します。"none"の場合は、secureをtrueにする必要があります。
+	SameSite string `json:"sameSite"`
+}
+
+// クッキーのSameSite属性です。
+const (
+	SameSiteStrict = "strict"
+	SameSiteLax    = "lax"
+	SameSiteNone   = "none"
+)
+
+// LockoutConfig は、認証の失敗によるロックアウトに関する設定を表現する構造体です。
+type LockoutConfig struct {
+	// Store は、認証の失敗の記録の保存先です。"memory"または"database"を指定します。
+	Store string `json:"store"`
+	// MaxFailuresPerSignInId は、サインインIDごとに、待ち時間なしで認証に失敗できる回数です。
+	MaxFailuresPerSignInId int `json:"maxFailuresPerSignInId"`
+	// MaxFailuresPerAddress は、リモートアドレスごとに、待ち時間なしで認証に失敗できる回数です。
+	MaxFailuresPerAddress int `json:"maxFailuresPerAddress"`
+	// BaseDelay は、失敗できる回数を超えた際の最初の待ち時間です。以降は失敗するたびに2倍になります。
+	BaseDelay Duration `json:"baseDelay"`
+	// MaxDelay は、待ち時間の上限です。
+	MaxDelay Duration `json:"maxDelay"`
+	// ResetAfter は、最後の失敗から失敗回数がリセットされるまでの時間です。
+	ResetAfter Duration `json:"resetAfter"`
+}
+
+// セッションや認証の失敗の記録、パーソナルアクセストークンの保存先の種類です。
+const (
+	// StoreMemory は、サーバのメモリ上に保存します。サーバを再起動すると破棄されます。
+	StoreMemory = "memory"
+	// StoreDatabase は、データベースに保存します。サーバを再起動しても維持されます。
+	StoreDatabase = "database"
+)
+
+// アクセストークンの形式です。
+const (
+	// AccessTokenModeOpaque は、ランダムなトークンをストアに保存し、ストアを参照して検証します。
+	AccessTokenModeOpaque = "opaque"
+	// AccessTokenModeSigned は、ユーザIDと有効期限を含む署名付きのトークンを発行し、ストアを参照せずに検証します。
+	AccessTokenModeSigned = "signed"
+)
+
+// SessionsConfig は、セッションに関する設定を表現する構造体です。
+type SessionsConfig struct {
+	// Store は、セッションの保存先です。"memory"または"database"を指定します。
+	Store string `json:"store"`
+	// AbsoluteTimeout は、サインインからセッションが無効になるまでの時間です。
+	AbsoluteTimeout Duration `json:"absoluteTimeout"`
+	// IdleTimeout は、トークンが最後に利用されてから無効になるまでの時間です。リフレッシュトークンの有効期限にもなります。
+	IdleTimeout Duration `json:"idleTimeout"`
+	// AccessTokenTimeout は、アクセストークンの発行から無効になるまでの時間です。
+	AccessTokenTimeout Duration `json:"accessTokenTimeout"`
+	// AccessTokenMode は、アクセストークンの形式です。"opaque"または"signed"を指定します。
+	AccessTokenMode string `json:"accessTokenMode"`
+	// SigningKeys は、署名付きのアクセストークンの署名・検証に使用する鍵です。
+	SigningKeys []SigningKeyConfig `json:"signingKeys"`
+	// ActiveSigningKeyId は、署名に使用する鍵のIDです。
+	ActiveSigningKeyId string `json:"activeSigningKeyId"`
+	// ReapInterval は、期限切れのセッションを破棄する間隔です。
+	ReapInterval Duration `json:"reapInterval"`
+}
+
+// SigningKeyConfig は、アクセストークンの署名・検証に使用する鍵の設定を表現する構造体です。
+type SigningKeyConfig struct {
+	// Id は、鍵のIDです。トークンのヘッダに含まれ、検証時に鍵を選択するために使用します。
+	Id string `json:"id"`
+	// Algorithm は、署名アルゴリズムです。"HS256"または"EdDSA"を指定します。
+	Algorithm string `json:"algorithm"`
+	// Path は、鍵のファイルのパスです。
+	Path string `json:"path"`
+}
+
+// GetConfigPath は、設定ファイルのパスを返却します。環境変数FROGNOTE_CONFIGが設定されていない場合は、"config.json"です。
+func GetConfigPath() string {
+	path := os.Getenv("FROGNOTE_CONFIG")
+	if path == "" {
+		return "config.json"
+	}
+	return path
+}
+
+// Load は、設定ファイルを読み込みます。ファイルが存在しない場合や、記述されていない項目は既定値になります。
+func Load(path string) (config *Config, err error) {
+	config = Default()
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, config)
+	if err != nil {
+		return nil, err
+	}
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validate は、設定値が有効かを検証します。
+func (config *Config) validate() error {
+	if config.Sessions.Store != StoreMemory && config.Sessions.Store != StoreDatabase {
+		return fmt.Errorf("unknown session store: %s", config.Sessions.Store)
+	}
+	// 0以下の場合は、作成したセッションがすぐに失効したり、破棄する間隔のタイマーを作成できなくなる。
+	if sessions := config.Sessions; sessions.AbsoluteTimeout.Duration <= 0 || sessions.IdleTimeout.Duration <= 0 || sessions.AccessTokenTimeout.Duration <= 0 {
+		return errors.New("session timeouts must be positive")
+	}
+	if config.Sessions.ReapInterval.Duration <= 0 {
+		return errors.New("session reap interval must be positive")
+	}
+	if config.Auth.Lockout.Store != StoreMemory && config.Auth.Lockout.Store != StoreDatabase {
+		return fmt.Errorf("unknown lockout store: %s", config.Auth.Lockout.Store)
+	}
+	if config.PersonalTokens.Store != StoreMemory && config.PersonalTokens.Store != StoreDatabase {
+		return fmt.Errorf("unknown personal token store: %s", config.PersonalTokens.Store)
+	}
+	if config.PersonalTokens.MaxLifetime.Duration < 0 {
+		return errors.New("personal token max lifetime must not be negative")
+	}
+	if config.Auth.Lockout.MaxFailuresPerSignInId < 1 || config.Auth.Lockout.MaxFailuresPerAddress < 1 {
+		return errors.New("lockout max failures must be positive")
+	}
+	// ユーザの作成時に確認するポリシーは、保存できる範囲内である必要がある。
+	if config.Policy.Password.MaxLength < 1 || config.Policy.Password.MaxLength > 64 {
+		return errors.New("password max length must be between 1 to 64")
+	}
+	if config.Policy.SignInId.MinLength < 1 || config.Policy.SignInId.MaxLength > 30 {
+		return errors.New("sign-in ID length must be between 1 to 30")
+	}
+	switch config.Auth.Cookie.SameSite {
+	case SameSiteStrict, SameSiteLax:
+	case SameSiteNone:
+		if !config.Auth.Cookie.Secure {
+			return errors.New("cookies with SameSite=None must be secure")
+		}
+	default:
+		return fmt.Errorf("unknown SameSite: %s", config.Auth.Cookie.SameSite)
+	}
+	if config.Auth.ChallengeTimeout.Duration <= 0 || config.Auth.TwoFactorTimeout.Duration <= 0 {
+		return errors.New("auth timeouts must be positive")
+	}
+	if (config.Encryption.ActiveMasterKeyId == "") != (len(config.Encryption.MasterKeys) == 0) {
+		return errors.New("encryption requires both master keys and an active master key ID")
+	}
+	if config.TLS.Enabled {
+		if config.TLS.CertFile == "" || config.TLS.KeyFile == "" {
+			return errors.New("TLS requires a certificate file and a key file")
+		}
+		if config.TLS.RedirectPort < 0 || config.TLS.RedirectPort > 65535 || config.TLS.RedirectPort == config.Port {
+			return errors.New("TLS redirect port must be between 0 to 65535 and differ from the port")
+		}
+		if config.TLS.HSTS.MaxAge.Duration < 0 || config.TLS.ReloadInterval.Duration < 0 {
+			return errors.New("HSTS max age and certificate reload interval must not be negative")
+		}
+	}
+	if clientAuth := config.TLS.ClientAuth; clientAuth.Port != 0 {
+		if !config.TLS.Enabled || clientAuth.CAFile == "" {
+			return errors.New("client certificate authentication requires TLS and a CA file")
+		}
+		if clientAuth.Port < 0 || clientAuth.Port > 65535 || clientAuth.Port == config.Port || clientAuth.Port == config.TLS.RedirectPort {
+			return errors.New("client auth port must be between 1 to 65535 and differ from the other ports")
+		}
+	}
+	if config.TLS.ClientAuth.Store != StoreMemory && config.TLS.ClientAuth.Store != StoreDatabase {
+		return fmt.Errorf("unknown client certificate store: %s", config.TLS.ClientAuth.Store)
+	}
+	for _, origin := range config.CORS.AllowedOrigins {
+		if origin == "*" && config.CORS.AllowCredentials {
+			return errors.New("CORS must not allow credentials from any origin")
+		}
+		if origin != "*" && !strings.Contains(origin, "://") {
+			return fmt.Errorf("CORS origin must include a scheme: %s", origin)
+		}
+	}
+	if config.CORS.MaxAge.Duration < 0 {
+		return errors.New("CORS max age must not be negative")
+	}
+	if err := config.RateLimit.Default.validate(); err != nil {
+		return err
+	}
+	for pattern, rule := range config.RateLimit.Routes {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid rate limit for %s: %w", pattern, err)
+		}
+	}
+	switch config.Sessions.AccessTokenMode {
+	case AccessTokenModeOpaque:
+	case AccessTokenModeSigned:
+		if config.Sessions.ActiveSigningKeyId == "" || len(config.Sessions.SigningKeys) == 0 {
+			return errors.New("signed access tokens require signing keys")
+		}
+	default:
+		return fmt.Errorf("unknown access token mode: %s", config.Sessions.AccessTokenMode)
+	}
+	return nil
+}
+
+// Default は、既定の設定を返却します。
+func Default() *Config {
+	return &Config{
+		Port: 8080,
+		Sessions: SessionsConfig{
+			Store:              StoreMemory,
+			AbsoluteTimeout:    Duration{30 * 24 * time.Hour},
+			IdleTimeout:        Duration{7 * 24 * time.Hour},
+			AccessTokenTimeout: Duration{15 * time.Minute},
+			AccessTokenMode:    AccessTokenModeOpaque,
+			ReapInterval:       Duration{time.Minute},
+		},
+		PersonalTokens: PersonalTokensConfig{
+			Store: StoreMemory,
+		},
+		Policy: PolicyConfig{
+			Password: PasswordPolicyConfig{MinLength: 8, MaxLength: 64, ForbidSignInId: true},
+			SignInId: SignInIdPolicyConfig{MinLength: 1, MaxLength: 30},
+		},
+		Auth: AuthConfig{
+			ChallengeTimeout: Duration{time.Minute},
+			TwoFactorTimeout: Duration{5 * time.Minute},
+			Cookie:           CookieConfig{Secure: true, SameSite: SameSiteStrict},
+			Lockout: LockoutConfig{
+				Store:                  StoreDatabase,
+				MaxFailuresPerSignInId: 5,
+				MaxFailuresPerAddress:  20,
+				BaseDelay:              Duration{time.Second},
+				MaxDelay:               Duration{15 * time.Minute},
+				ResetAfter:             Duration{time.Hour},
+			},
+		},
+		TLS: TLSConfig{
+			HSTS:           HSTSConfig{MaxAge: Duration{365 * 24 * time.Hour}},
+			ReloadInterval: Duration{time.Minute},
+			ClientAuth:     ClientAuthConfig{Store: StoreDatabase},
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:5173"},
+			AllowedMethods: []string{"GET", "POST", "DELETE", "PATCH", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-CSRF-Token"},
+			ExposedHeaders: []string{"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset"},
+			MaxAge:         Duration{10 * time.Minute},
+		},
+		SecurityHeaders: SecurityHeadersConfig{
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'",
+			ReferrerPolicy:        "no-referrer",
+			StripHeaders:          []string{"Server", "X-Powered-By"},
+		},
+		RateLimit: RateLimitConfig{
+			Default: RateLimitRuleConfig{Requests: 300, Per: Duration{time.Minute}},
+			Routes: map[string]RateLimitRuleConfig{
+				"/user/create":  {Requests: 5, Per: Duration{time.Hour}},
+				"/user/recover": {Requests: 10, Per: Duration{time.Hour}},
+				"/backup/save":  {Requests: 30, Per: Duration{time.Minute}},
+			},
+		},
+	}
+}
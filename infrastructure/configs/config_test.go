@@ -0,0 +1,193 @@
+package configs_test
+
+import (
+	"FrogNote_database/infrastructure/configs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("ファイルが存在しない場合は既定値になる", func(t *testing.T) {
+		config, err := configs.Load(filepath.Join(t.TempDir(), "notfound.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Port != configs.Default().Port {
+			t.Error(config.Port)
+		}
+	})
+
+	t.Run("記述された項目のみ上書きされる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		content := `{"port": 8081, "sessions": {"idleTimeout": "15m"}}`
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := configs.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if config.Port != 8081 || config.Sessions.IdleTimeout.Duration != 15*time.Minute {
+			t.Error(config)
+		}
+		if config.Sessions.AbsoluteTimeout != configs.Default().Sessions.AbsoluteTimeout {
+			t.Error(config.Sessions.AbsoluteTimeout)
+		}
+	})
+
+	t.Run("未知のセッションの保存先はエラーになる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"sessions": {"store": "redis"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("セッションの有効期限や破棄する間隔は正の値である必要がある", func(t *testing.T) {
+		for _, sessions := range []string{`{"reapInterval": "0s"}`, `{"absoluteTimeout": "0s"}`, `{"idleTimeout": "-1m"}`, `{"accessTokenTimeout": "0s"}`} {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(`{"sessions": `+sessions+`}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := configs.Load(path); err == nil {
+				t.Error(sessions)
+			}
+		}
+	})
+
+	t.Run("未知のロックアウトの記録の保存先はエラーになる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"auth": {"lockout": {"store": "redis"}}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("未知のパーソナルアクセストークンの保存先はエラーになる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"personalTokens": {"store": "redis"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("SameSite=Noneのクッキーはsecureである必要がある", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"auth": {"cookie": {"secure": false, "sameSite": "none"}}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("署名付きのアクセストークンには鍵が必要", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"sessions": {"accessTokenMode": "signed"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("マスター鍵を設定する場合は有効な鍵のIDが必要", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"encryption": {"masterKeys": [{"id": "2023-04", "path": "keys/2023-04.key"}]}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("ルートごとの制限は既定のルートに追加される", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		content := `{"rateLimit": {"routes": {"/backup/list": {"requests": 60, "per": "1m"}, "/user/create": {"requests": 0}}}}`
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := configs.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		routes := config.RateLimit.Routes
+		if routes["/backup/list"].Requests != 60 || routes["/user/create"].Requests != 0 || routes["/user/recover"].Requests != 10 {
+			t.Error(routes)
+		}
+	})
+
+	t.Run("制限する場合は期間が必要", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"rateLimit": {"routes": {"/backup/list": {"requests": 60}}}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("HTTPSでサーブする場合は証明書と秘密鍵のファイルが必要", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"tls": {"enabled": true, "certFile": "keys/cert.pem"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("相互TLS認証にはHTTPSと認証局の証明書が必要", func(t *testing.T) {
+		cases := []string{
+			`{"tls": {"clientAuth": {"port": 8444, "caFile": "keys/client-ca.pem"}}}`,
+			`{"tls": {"enabled": true, "certFile": "keys/cert.pem", "keyFile": "keys/key.pem", "clientAuth": {"port": 8444}}}`,
+			`{"port": 8444, "tls": {"enabled": true, "certFile": "keys/cert.pem", "keyFile": "keys/key.pem", "clientAuth": {"port": 8444, "caFile": "keys/client-ca.pem"}}}`,
+		}
+		for _, value := range cases {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := configs.Load(path); err == nil {
+				t.Error(value)
+			}
+		}
+		path := filepath.Join(t.TempDir(), "config.json")
+		value := `{"tls": {"enabled": true, "certFile": "keys/cert.pem", "keyFile": "keys/key.pem", "clientAuth": {"port": 8444, "caFile": "keys/client-ca.pem"}}}`
+		if err := os.WriteFile(path, []byte(value), 0600); err != nil {
+			t.Fatal(err)
+		}
+		config, err := configs.Load(path)
+		if err != nil || config.TLS.ClientAuth.Store != configs.StoreDatabase {
+			t.Error(err)
+		}
+	})
+
+	t.Run("すべてのオリジンにクッキーを含むリクエストは許可できない", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"cors": {"allowedOrigins": ["*"], "allowCredentials": true}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+
+	t.Run("時間の書式が不正な場合はエラーになる", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"sessions": {"idleTimeout": "15"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := configs.Load(path); err == nil {
+			t.Error()
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package configs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Duration は、設定ファイル内で"30m"のような文字列で時間を表現するための型です。
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON は、"30m"や"24h"のような文字列をDurationにアンマーシャルします。
+func (d *Duration) UnmarshalJSON(bytes []byte) (err error) {
+	var str string
+	if err = json.Unmarshal(bytes, &str); err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(str)
+	return err
+}
+
+// MarshalJSON は、Durationを"30m0s"のような文字列にマーシャルします。
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
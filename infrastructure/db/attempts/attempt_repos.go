@@ -0,0 +1,89 @@
+package attempts
+
+import (
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/security"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AttemptRepository は、認証に失敗した記録をデータベースに永続化する構造体です。security.IAttemptStoreを実装しています。
+// サーバを再起動してもロックアウトが維持されます。
+type AttemptRepository struct {
+	connector db.IDBConnector
+}
+
+// Find は、キーの記録を取得します。存在しない場合は、失敗回数が0の記録を返却します。
+func (repos *AttemptRepository) Find(key string) (attempt *security.Attempt, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row := db.QueryRow("select failures, last_failure_at from auth_attempts where auth_attempts.attempt_key = ?", key)
+	return mapAttempt(row)
+}
+
+// AddFailure は、キーの失敗回数を1増やし、更新後の記録を返却します。最後の失敗がresetBefore以前の場合は、失敗回数を1からやりなおします。
+func (repos *AttemptRepository) AddFailure(key string, now time.Time, resetBefore time.Time) (attempt *security.Attempt, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	// 一つの文で増やすことで、同時に呼び出されても失敗回数が失われない。failuresは、last_failure_atを更新する前の値で判定するため、先に代入する。
+	_, err = db.Exec(`insert into auth_attempts (attempt_key, failures, last_failure_at) values (?, 1, ?)
+		on duplicate key update failures = if(last_failure_at <= ?, 1, failures + 1), last_failure_at = values(last_failure_at)`,
+		key, now.UTC(), resetBefore.UTC())
+	if err != nil {
+		return nil, err
+	}
+	row := db.QueryRow("select failures, last_failure_at from auth_attempts where auth_attempts.attempt_key = ?", key)
+	return mapAttempt(row)
+}
+
+// Delete は、キーの記録を削除します。
+func (repos *AttemptRepository) Delete(key string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from auth_attempts where auth_attempts.attempt_key = ?", key)
+	return err
+}
+
+// DeleteBefore は、最後の失敗がlastFailureBefore以前の記録をすべて削除します。
+func (repos *AttemptRepository) DeleteBefore(lastFailureBefore time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from auth_attempts where last_failure_at <= ?", lastFailureBefore.UTC())
+	return err
+}
+
+// mapAttempt は、rowから記録を読み取ります。存在しない場合は、失敗回数が0の記録を返却します。
+func mapAttempt(row *sql.Row) (attempt *security.Attempt, err error) {
+	var lastFailureAtStr string
+	attempt = &security.Attempt{}
+	err = row.Scan(&attempt.Failures, &lastFailureAtStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &security.Attempt{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	attempt.LastFailureAt, err = db.ParseDateTime(lastFailureAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// NewAttemptRepository は、AttemptRepository構造体を初期化し、返却します。
+func NewAttemptRepository(connector db.IDBConnector) (repos *AttemptRepository) {
+	return &AttemptRepository{connector: connector}
+}
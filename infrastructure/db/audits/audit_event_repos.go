@@ -0,0 +1,127 @@
+package audits
+
+import (
+	"FrogNote_database/domain/audits"
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	"database/sql"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// auditEventColumns は、監査ログを復元する際に取得する列です。mapAuditEventで読み取る順番と一致させる必要があります。
+const auditEventColumns = "id, type, user_id, actor_id, sign_in_id, remote_addr, user_agent, detail, created_at"
+
+// AuditEventRepository は、監査ログを永続化・検索する構造体です。
+type AuditEventRepository struct {
+	connector db.IDBConnector
+}
+
+// Create は、監査ログにイベントを追記します。
+func (repos *AuditEventRepository) Create(event *audits.AuditEvent) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("insert into audit_events (type, user_id, actor_id, sign_in_id, remote_addr, user_agent, detail, created_at) values (?, ?, ?, ?, ?, ?, ?, ?)",
+		string(event.Type), toNullUserId(event.UserId), toNullUserId(event.ActorId), event.SignInId, event.RemoteAddr, event.UserAgent, event.Detail, event.CreatedAt.UTC())
+	return
+}
+
+// Find は、条件に一致するイベントを新しい順に取得します。
+func (repos *AuditEventRepository) Find(query *audits.Query) (events []*audits.AuditEvent, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var conditions []string
+	var args []any
+	if query.UserId != nil {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, query.UserId.GetValue())
+	}
+	if query.Type != "" {
+		conditions = append(conditions, "type = ?")
+		args = append(args, string(query.Type))
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, query.Until.UTC())
+	}
+	statement := "select " + auditEventColumns + " from audit_events"
+	if len(conditions) > 0 {
+		statement += " where " + strings.Join(conditions, " and ")
+	}
+	statement += " order by created_at desc, id desc limit ?"
+	args = append(args, query.Limit)
+
+	rows, err := db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := mapAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// rowScanner は、*sql.Rowと*sql.Rowsに共通する、1行を読み取るためのインターフェースです。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// mapAuditEvent は、rowからイベントを読み取ります。
+func mapAuditEvent(row rowScanner) (event *audits.AuditEvent, err error) {
+	var eventType string
+	var userIdValue sql.NullInt64
+	var actorIdValue sql.NullInt64
+	var createdAtStr string
+	event = &audits.AuditEvent{}
+	err = row.Scan(&event.Id, &eventType, &userIdValue, &actorIdValue, &event.SignInId, &event.RemoteAddr, &event.UserAgent, &event.Detail, &createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	// 古いバージョンで記録された未知の種類も、そのまま返却する。
+	event.Type = audits.EventType(eventType)
+	event.UserId = parseNullUserId(userIdValue)
+	event.ActorId = parseNullUserId(actorIdValue)
+	event.CreatedAt, err = db.ParseDateTime(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// toNullUserId は、nilのユーザIDをnullとして保存するために変換します。
+func toNullUserId(userId *users.UserId) sql.NullInt64 {
+	if userId == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(userId.GetValue()), Valid: true}
+}
+
+// parseNullUserId は、nullの場合はnilを返却します。
+func parseNullUserId(value sql.NullInt64) *users.UserId {
+	if !value.Valid {
+		return nil
+	}
+	return users.NewUserId(int(value.Int64))
+}
+
+// NewAuditEventRepository は、AuditEventRepository構造体を初期化し、返却します。
+func NewAuditEventRepository(connector db.IDBConnector) (repos *AuditEventRepository) {
+	return &AuditEventRepository{connector: connector}
+}
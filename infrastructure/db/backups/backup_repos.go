@@ -4,14 +4,25 @@ import (
 	"FrogNote_database/domain/backups"
 	"FrogNote_database/domain/users"
 	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/db/userkeys"
+	"FrogNote_database/infrastructure/security"
 	"database/sql"
+	"errors"
+	"strconv"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// ErrEncryptionDisabled は、暗号化されたバックアップデータを、マスター鍵を設定せずに復号しようとしたことを表すエラーです。
+var ErrEncryptionDisabled = errors.New("backup is encrypted but no master key is configured")
+
 // BackupRepository は、バックアップを永続化・復元する構造体です。
+// キーリングが設定されている場合は、バックアップデータ(本体)を暗号化して保存し、取得する際に復号します。
+// バックアップごとのデータ鍵は、所有者の鍵でラップして保存します。アカウントを削除して所有者の鍵を破棄すると、復号できなくなります。
 type BackupRepository struct {
 	connector db.IDBConnector
+	keyRing   *security.KeyRing
+	userKeys  *userkeys.UserKeyRepository
 }
 
 // FindBackupMetas は、バックアップのメタデータのスライスを取得します。（バックアップの本体がない状態で返却されます。）
@@ -25,7 +36,7 @@ func (repos *BackupRepository) FindBackupMetas(userId *users.UserId) (backupSlic
 	if err != nil {
 		return nil, err
 	}
-	backupSlice, err = mapBackups(rows, true)
+	backupSlice, err = mapBackupMetas(rows)
 	if err != nil {
 		return nil, err
 	}
@@ -39,17 +50,70 @@ func (repos *BackupRepository) FindByBackupId(backupId *backups.BackupId) (backu
 		return nil, err
 	}
 	defer db.Close()
-	rows, err := db.Query("select * from backups where backups.id = ?", backupId.GetValue())
+	var backupIdValue int
+	var userIdValue int
+	var dataKey []byte
+	var masterKeyId sql.NullString
+	backup = &backups.Backup{}
+	row := db.QueryRow("select id, user_id, backup, saved_at, data_key, master_key_id from backups where backups.id = ?", backupId.GetValue())
+	err = row.Scan(&backupIdValue, &userIdValue, &backup.Backup, &backup.SavedAt, &dataKey, &masterKeyId)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	backup.BackupId = *backups.NewBackupId(backupIdValue)
+	backup.UserId = *users.NewUserId(userIdValue)
 
-	backupSlice, err := mapBackups(rows, false)
+	// 暗号化する前に保存されたバックアップデータは、そのまま返却する。
+	if dataKey == nil {
+		return backup, nil
+	}
+	backup.Backup, err = repos.open(&backup.UserId, backup.Backup, dataKey, masterKeyId)
 	if err != nil {
 		return nil, err
 	}
-	return backupSlice[0], nil
+	return backup, nil
+}
+
+// FindStorageUsage は、ユーザがバックアップデータの保存に使用している容量を取得します。容量は、暗号化する前の大きさの合計です。
+// 大きさが記録されていないバックアップデータは、保存されている大きさで計算します。
+func (repos *BackupRepository) FindStorageUsage(userId *users.UserId) (usage *backups.StorageUsage, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	usage = &backups.StorageUsage{UserId: *userId}
+	row := db.QueryRow("select count(*), coalesce(sum(coalesce(size, length(backup))), 0) from backups where backups.user_id = ?", userId.GetValue())
+	err = row.Scan(&usage.BackupCount, &usage.TotalBytes)
+	if err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// FindStorageUsages は、バックアップデータを保存しているすべてのユーザの使用容量を、使用容量が多い順に取得します。
+func (repos *BackupRepository) FindStorageUsages() (usages []*backups.StorageUsage, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("select user_id, count(*), coalesce(sum(coalesce(size, length(backup))), 0) as total_bytes from backups group by user_id order by total_bytes desc")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userIdValue int
+		usage := &backups.StorageUsage{}
+		err = rows.Scan(&userIdValue, &usage.BackupCount, &usage.TotalBytes)
+		if err != nil {
+			return nil, err
+		}
+		usage.UserId = *users.NewUserId(userIdValue)
+		usages = append(usages, usage)
+	}
+	return usages, rows.Err()
 }
 
 // DeleteByBackupId は、バックアップIDをもとにバックアップを削除します。
@@ -63,30 +127,191 @@ func (repos *BackupRepository) DeleteByBackupId(backupId *backups.BackupId) (err
 	return err
 }
 
-// Create は、バックアップを新規保存します。
+// Create は、バックアップを新規保存します。キーリングが設定されている場合は、バックアップデータを暗号化して保存します。
+// 使用容量を計算するため、暗号化する前の大きさも保存します。
 func (repos *BackupRepository) Create(userId *users.UserId, backupBin []byte) error {
-
+	if repos.keyRing == nil {
+		db, err := repos.connector.Connect()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		_, err = db.Exec("insert into backups (user_id, backup, size) values (?, ?, ?)", userId.GetValue(), backupBin, len(backupBin))
+		return err
+	}
+	sealed, dataKey, err := repos.seal(userId, backupBin)
+	if err != nil {
+		return err
+	}
 	db, err := repos.connector.Connect()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	_, err = db.Exec("insert into backups values (default, ?, ?, default)", userId.GetValue(), backupBin)
+	_, err = db.Exec("insert into backups (user_id, backup, data_key, size) values (?, ?, ?, ?)", userId.GetValue(), sealed, dataKey, len(backupBin))
 	return err
 }
 
-// mapBackupsは、複数のバックアップデータをマップして返却します。
-func mapBackups(rows *sql.Rows, isMetaOnly bool) (backupSlice []*backups.Backup, err error) {
+// RotationResult は、マスター鍵を切り替えた結果を表現する構造体です。
+type RotationResult struct {
+	// RewrappedUserKeys は、有効なマスター鍵でラップし直したユーザの鍵の数です。
+	RewrappedUserKeys int
+	// RewrappedDataKeys は、マスター鍵で直接ラップされていたデータ鍵を、所有者の鍵でラップし直した数です。
+	RewrappedDataKeys int
+	// EncryptedBackups は、暗号化する前に保存されていたバックアップデータを、暗号化した数です。
+	EncryptedBackups int
+}
+
+// RotateMasterKey は、有効なマスター鍵以外でラップされたユーザの鍵を、すべて有効なマスター鍵でラップし直します。バックアップデータは暗号化し直しません。
+// マスター鍵で直接ラップされたデータ鍵は所有者の鍵でラップし直し、暗号化する前に保存されたバックアップデータは暗号化して保存し直します。
+func (repos *BackupRepository) RotateMasterKey() (result *RotationResult, err error) {
+	if repos.keyRing == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	result = &RotationResult{}
+	result.RewrappedUserKeys, err = repos.userKeys.RotateMasterKey()
+	if err != nil {
+		return result, err
+	}
+
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return result, err
+	}
+	defer db.Close()
+
+	// 更新しながら読み取らないよう、対象のバックアップデータを先にすべて取得する。
+	type target struct {
+		id          int
+		userId      *users.UserId
+		dataKey     []byte
+		masterKeyId sql.NullString
+	}
+	rows, err := db.Query("select id, user_id, data_key, master_key_id from backups where data_key is null or master_key_id is not null")
+	if err != nil {
+		return result, err
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		var userIdValue int
+		if err = rows.Scan(&t.id, &userIdValue, &t.dataKey, &t.masterKeyId); err != nil {
+			rows.Close()
+			return result, err
+		}
+		t.userId = users.NewUserId(userIdValue)
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return result, err
+	}
+
+	for _, t := range targets {
+		if t.masterKeyId.Valid {
+			additionalData := backupAdditionalData(t.userId)
+			dataKey, err := repos.keyRing.Unwrap(t.dataKey, t.masterKeyId.String, additionalData)
+			if err != nil {
+				return result, err
+			}
+			wrappedKey, err := repos.wrapDataKey(t.userId, dataKey)
+			if err != nil {
+				return result, err
+			}
+			// 同時に更新された場合は、上書きしない。
+			_, err = db.Exec("update backups set data_key = ?, master_key_id = null where id = ? and master_key_id = ?", wrappedKey, t.id, t.masterKeyId.String)
+			if err != nil {
+				return result, err
+			}
+			result.RewrappedDataKeys++
+			continue
+		}
+
+		var backupBin []byte
+		err = db.QueryRow("select backup from backups where id = ? and data_key is null", t.id).Scan(&backupBin)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+		sealed, wrappedKey, err := repos.seal(t.userId, backupBin)
+		if err != nil {
+			return result, err
+		}
+		_, err = db.Exec("update backups set backup = ?, data_key = ?, size = ? where id = ? and data_key is null", sealed, wrappedKey, len(backupBin), t.id)
+		if err != nil {
+			return result, err
+		}
+		result.EncryptedBackups++
+	}
+	return result, nil
+}
+
+// seal は、バックアップごとに生成したデータ鍵でバックアップデータを暗号化し、データ鍵を所有者の鍵でラップします。
+func (repos *BackupRepository) seal(userId *users.UserId, backupBin []byte) (sealed []byte, wrappedKey []byte, err error) {
+	dataKey, err := security.NewDataKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	sealed, err = security.SealWithKey(dataKey, backupBin, backupAdditionalData(userId))
+	if err != nil {
+		return nil, nil, err
+	}
+	wrappedKey, err = repos.wrapDataKey(userId, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sealed, wrappedKey, nil
+}
+
+// wrapDataKey は、データ鍵を所有者の鍵でラップします。所有者の鍵が存在しない場合は、生成します。
+func (repos *BackupRepository) wrapDataKey(userId *users.UserId, dataKey []byte) (wrappedKey []byte, err error) {
+	userKey, err := repos.userKeys.FindOrCreate(userId)
+	if err != nil {
+		return nil, err
+	}
+	return security.SealWithKey(userKey, dataKey, backupAdditionalData(userId))
+}
+
+// open は、ラップされたデータ鍵を取り出し、バックアップデータを復号します。
+// 所有者の鍵が破棄されている場合は、userkeys.ErrUserKeyNotFoundを返却します。
+func (repos *BackupRepository) open(userId *users.UserId, sealed []byte, wrappedKey []byte, masterKeyId sql.NullString) (backupBin []byte, err error) {
+	if repos.keyRing == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	additionalData := backupAdditionalData(userId)
+	var dataKey []byte
+	if masterKeyId.Valid {
+		// 所有者の鍵を導入する前に保存された、マスター鍵で直接ラップされたデータ鍵。
+		dataKey, err = repos.keyRing.Unwrap(wrappedKey, masterKeyId.String, additionalData)
+	} else {
+		var userKey []byte
+		userKey, err = repos.userKeys.Find(userId)
+		if err != nil {
+			return nil, err
+		}
+		dataKey, err = security.OpenWithKey(userKey, wrappedKey, additionalData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return security.OpenWithKey(dataKey, sealed, additionalData)
+}
+
+// backupAdditionalData は、暗号化の付加データを返却します。所有者のユーザIDを含めることで、他のユーザの行に移されたバックアップデータは復号できなくなります。
+func backupAdditionalData(userId *users.UserId) []byte {
+	return []byte("backup:" + strconv.Itoa(userId.GetValue()))
+}
+
+// mapBackupMetas は、複数のバックアップデータのメタデータをマップして返却します。
+func mapBackupMetas(rows *sql.Rows) (backupSlice []*backups.Backup, err error) {
 	backupSlice = make([]*backups.Backup, 0)
 	for rows.Next() {
 		backup := &backups.Backup{}
 		var userIdValue int
 		var backupIdValue int
-		if isMetaOnly {
-			rows.Scan(&backupIdValue, &userIdValue, &backup.SavedAt)
-		} else {
-			rows.Scan(&backupIdValue, &userIdValue, &backup.Backup, &backup.SavedAt)
-		}
+		rows.Scan(&backupIdValue, &userIdValue, &backup.SavedAt)
 		backupId := backups.NewBackupId(backupIdValue)
 		userId := users.NewUserId(userIdValue)
 		backup.UserId = *userId
@@ -100,7 +325,11 @@ func mapBackups(rows *sql.Rows, isMetaOnly bool) (backupSlice []*backups.Backup,
 	return backupSlice, nil
 }
 
-// NewBackupRepository は、BackupRepository構造体を初期化し、返却します。
-func NewBackupRepository(connector db.IDBConnector) (repos *BackupRepository) {
-	return &BackupRepository{connector: connector}
+// NewBackupRepository は、BackupRepository構造体を初期化し、返却します。keyRingがnilの場合は、バックアップデータを暗号化せずに保存します。
+func NewBackupRepository(connector db.IDBConnector, keyRing *security.KeyRing) (repos *BackupRepository) {
+	repos = &BackupRepository{connector: connector, keyRing: keyRing}
+	if keyRing != nil {
+		repos.userKeys = userkeys.NewUserKeyRepository(connector, keyRing)
+	}
+	return repos
 }
@@ -0,0 +1,170 @@
+package clientcertificates
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/security"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrDuplicateEntry は、一意制約に違反した際のMySQLのエラー番号です。
+const mysqlErrDuplicateEntry = 1062
+
+// clientCertificateColumns は、クライアント証明書の登録を復元する際に取得する列です。mapClientCertificateで読み取る順番と一致させる必要があります。
+const clientCertificateColumns = "id, user_id, name, match_type, match_value, scopes, created_at, last_used_at"
+
+// ClientCertificateRepository は、クライアント証明書の登録をデータベースに永続化する構造体です。security.IClientCertificateStoreを実装しています。
+type ClientCertificateRepository struct {
+	connector db.IDBConnector
+}
+
+// Create は、クライアント証明書の登録を新規保存します。同じ方法と値の登録がすでに存在する場合は、security.ErrClientCertificateExistsを返却します。
+func (repos *ClientCertificateRepository) Create(certificate *security.ClientCertificate) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	scopes := make([]string, len(certificate.Scopes))
+	for i, scope := range certificate.Scopes {
+		scopes[i] = string(scope)
+	}
+	_, err = db.Exec("insert into client_certificates (id, user_id, name, match_type, match_value, scopes, created_at, last_used_at) values (?, ?, ?, ?, ?, ?, ?, ?)",
+		certificate.Id, certificate.UserId.GetValue(), certificate.Name, string(certificate.Match), certificate.Value, strings.Join(scopes, " "), certificate.CreatedAt.UTC(), toNullTime(certificate.LastUsedAt))
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return security.ErrClientCertificateExists
+	}
+	return err
+}
+
+// Find は、対応付ける方法と値をもとにクライアント証明書の登録を取得します。存在しない場合は、security.ErrClientCertificateNotFoundを返却します。
+func (repos *ClientCertificateRepository) Find(match security.CertificateMatch, value string) (certificate *security.ClientCertificate, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row := db.QueryRow("select "+clientCertificateColumns+" from client_certificates where client_certificates.match_type = ? and client_certificates.match_value = ?", string(match), value)
+	certificate, err = mapClientCertificate(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, security.ErrClientCertificateNotFound
+	}
+	return certificate, err
+}
+
+// FindByUserId は、ユーザのクライアント証明書の登録をすべて取得します。
+func (repos *ClientCertificateRepository) FindByUserId(userId *users.UserId) (certificates []*security.ClientCertificate, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("select "+clientCertificateColumns+" from client_certificates where client_certificates.user_id = ?", userId.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		certificate, err := mapClientCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	return certificates, rows.Err()
+}
+
+// Touch は、クライアント証明書の登録の最終利用時刻を更新します。
+func (repos *ClientCertificateRepository) Touch(id string, lastUsedAt time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update client_certificates set last_used_at = ? where id = ?", lastUsedAt.UTC(), id)
+	return err
+}
+
+// Delete は、IDを指定してユーザのクライアント証明書の登録を削除します。存在しない場合は、security.ErrClientCertificateNotFoundを返却します。
+func (repos *ClientCertificateRepository) Delete(userId *users.UserId, id string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	result, err := db.Exec("delete from client_certificates where client_certificates.user_id = ? and client_certificates.id = ?", userId.GetValue(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return security.ErrClientCertificateNotFound
+	}
+	return nil
+}
+
+// DeleteByUserId は、ユーザのクライアント証明書の登録をすべて削除します。
+func (repos *ClientCertificateRepository) DeleteByUserId(userId *users.UserId) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from client_certificates where client_certificates.user_id = ?", userId.GetValue())
+	return err
+}
+
+// rowScanner は、*sql.Rowと*sql.Rowsに共通する、1行を読み取るためのインターフェースです。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// mapClientCertificate は、rowからクライアント証明書の登録を読み取ります。
+func mapClientCertificate(row rowScanner) (certificate *security.ClientCertificate, err error) {
+	var userIdValue int
+	var match string
+	var scopes string
+	var createdAtStr string
+	var lastUsedAtStr sql.NullString
+	certificate = &security.ClientCertificate{}
+	err = row.Scan(&certificate.Id, &userIdValue, &certificate.Name, &match, &certificate.Value, &scopes, &createdAtStr, &lastUsedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate.UserId = *users.NewUserId(userIdValue)
+	certificate.Match = security.CertificateMatch(match)
+	for _, scope := range strings.Fields(scopes) {
+		certificate.Scopes = append(certificate.Scopes, security.Scope(scope))
+	}
+	certificate.CreatedAt, err = db.ParseDateTime(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	if lastUsedAtStr.Valid {
+		certificate.LastUsedAt, err = db.ParseDateTime(lastUsedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return certificate, nil
+}
+
+// toNullTime は、ゼロ値の時刻をnullとして保存するために変換します。
+func toNullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value.UTC(), Valid: !value.IsZero()}
+}
+
+// NewClientCertificateRepository は、ClientCertificateRepository構造体を初期化し、返却します。
+func NewClientCertificateRepository(connector db.IDBConnector) (repos *ClientCertificateRepository) {
+	return &ClientCertificateRepository{connector: connector}
+}
@@ -0,0 +1,11 @@
+package db
+
+import "time"
+
+// dateTimeLayout は、DATETIME型の列を文字列として読み取った際の書式です。
+const dateTimeLayout = "2006-01-02 15:04:05.999999"
+
+// ParseDateTime は、DATETIME型の列を文字列として読み取った値をUTCの時刻に変換します。
+func ParseDateTime(value string) (time.Time, error) {
+	return time.ParseInLocation(dateTimeLayout, value, time.UTC)
+}
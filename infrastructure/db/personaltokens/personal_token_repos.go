@@ -0,0 +1,190 @@
+package personaltokens
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/security"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrDuplicateEntry は、一意制約に違反した際のMySQLのエラー番号です。
+const mysqlErrDuplicateEntry = 1062
+
+// personalTokenColumns は、パーソナルアクセストークンを復元する際に取得する列です。mapPersonalTokenで読み取る順番と一致させる必要があります。
+const personalTokenColumns = "id, user_id, name, scopes, created_at, expires_at, last_used_at"
+
+// PersonalTokenRepository は、パーソナルアクセストークンをデータベースに永続化する構造体です。security.IPersonalTokenStoreを実装しています。
+type PersonalTokenRepository struct {
+	connector db.IDBConnector
+}
+
+// Create は、パーソナルアクセストークンを新規保存します。同じキーのトークンがすでに存在する場合は、security.ErrPersonalTokenExistsを返却します。
+func (repos *PersonalTokenRepository) Create(key string, token *security.PersonalToken) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	scopes := make([]string, len(token.Scopes))
+	for i, scope := range token.Scopes {
+		scopes[i] = string(scope)
+	}
+	_, err = db.Exec("insert into personal_tokens (token_hash, id, user_id, name, scopes, created_at, expires_at, last_used_at) values (?, ?, ?, ?, ?, ?, ?, ?)",
+		key, token.Id, token.UserId.GetValue(), token.Name, strings.Join(scopes, " "), token.CreatedAt.UTC(), toNullTime(token.ExpiresAt), toNullTime(token.LastUsedAt))
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return security.ErrPersonalTokenExists
+	}
+	return err
+}
+
+// Find は、キーに紐づけられたパーソナルアクセストークンを取得します。存在しない場合は、security.ErrPersonalTokenNotFoundを返却します。
+func (repos *PersonalTokenRepository) Find(key string) (token *security.PersonalToken, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row := db.QueryRow("select "+personalTokenColumns+" from personal_tokens where personal_tokens.token_hash = ?", key)
+	token, err = mapPersonalToken(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, security.ErrPersonalTokenNotFound
+	}
+	return token, err
+}
+
+// FindByUserId は、ユーザのパーソナルアクセストークンをすべて取得します。
+func (repos *PersonalTokenRepository) FindByUserId(userId *users.UserId) (tokens []*security.PersonalToken, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("select "+personalTokenColumns+" from personal_tokens where personal_tokens.user_id = ?", userId.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token, err := mapPersonalToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// Touch は、キーに紐づけられたパーソナルアクセストークンの最終利用時刻を更新します。
+func (repos *PersonalTokenRepository) Touch(key string, lastUsedAt time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update personal_tokens set last_used_at = ? where token_hash = ?", lastUsedAt.UTC(), key)
+	return err
+}
+
+// Delete は、IDを指定してユーザのパーソナルアクセストークンを削除します。存在しない場合は、security.ErrPersonalTokenNotFoundを返却します。
+func (repos *PersonalTokenRepository) Delete(userId *users.UserId, id string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	result, err := db.Exec("delete from personal_tokens where personal_tokens.user_id = ? and personal_tokens.id = ?", userId.GetValue(), id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return security.ErrPersonalTokenNotFound
+	}
+	return nil
+}
+
+// DeleteByUserId は、ユーザのパーソナルアクセストークンをすべて削除します。
+func (repos *PersonalTokenRepository) DeleteByUserId(userId *users.UserId) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from personal_tokens where personal_tokens.user_id = ?", userId.GetValue())
+	return err
+}
+
+// DeleteExpired は、有効期限がnow以前のパーソナルアクセストークンをすべて削除します。
+func (repos *PersonalTokenRepository) DeleteExpired(now time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from personal_tokens where expires_at <= ?", now.UTC())
+	return err
+}
+
+// rowScanner は、*sql.Rowと*sql.Rowsに共通する、1行を読み取るためのインターフェースです。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// mapPersonalToken は、rowからパーソナルアクセストークンを読み取ります。
+func mapPersonalToken(row rowScanner) (token *security.PersonalToken, err error) {
+	var userIdValue int
+	var scopes string
+	var createdAtStr string
+	var expiresAtStr sql.NullString
+	var lastUsedAtStr sql.NullString
+	token = &security.PersonalToken{}
+	err = row.Scan(&token.Id, &userIdValue, &token.Name, &scopes, &createdAtStr, &expiresAtStr, &lastUsedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	token.UserId = *users.NewUserId(userIdValue)
+	for _, scope := range strings.Fields(scopes) {
+		token.Scopes = append(token.Scopes, security.Scope(scope))
+	}
+	token.CreatedAt, err = db.ParseDateTime(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	token.ExpiresAt, err = parseNullDateTime(expiresAtStr)
+	if err != nil {
+		return nil, err
+	}
+	token.LastUsedAt, err = parseNullDateTime(lastUsedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// toNullTime は、ゼロ値の時刻をnullとして保存するために変換します。
+func toNullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value.UTC(), Valid: !value.IsZero()}
+}
+
+// parseNullDateTime は、nullの場合はゼロ値の時刻を返却します。
+func parseNullDateTime(value sql.NullString) (time.Time, error) {
+	if !value.Valid {
+		return time.Time{}, nil
+	}
+	return db.ParseDateTime(value.String)
+}
+
+// NewPersonalTokenRepository は、PersonalTokenRepository構造体を初期化し、返却します。
+func NewPersonalTokenRepository(connector db.IDBConnector) (repos *PersonalTokenRepository) {
+	return &PersonalTokenRepository{connector: connector}
+}
@@ -3,13 +3,22 @@ package db_test
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	dom_audits "FrogNote_database/domain/audits"
 	dom_backups "FrogNote_database/domain/backups"
 	dom_users "FrogNote_database/domain/users"
+	inf_attempts "FrogNote_database/infrastructure/db/attempts"
+	inf_audits "FrogNote_database/infrastructure/db/audits"
 	inf_backups "FrogNote_database/infrastructure/db/backups"
+	inf_clientcertificates "FrogNote_database/infrastructure/db/clientcertificates"
+	inf_personaltokens "FrogNote_database/infrastructure/db/personaltokens"
+	inf_sessions "FrogNote_database/infrastructure/db/sessions"
+	inf_userkeys "FrogNote_database/infrastructure/db/userkeys"
 	inf_users "FrogNote_database/infrastructure/db/users"
 	"FrogNote_database/infrastructure/security"
 
@@ -51,6 +60,7 @@ var (
 		SignInId:   *getDummyUser1SignInId(),
 		ScreenName: "dummy_1",
 		Password:   "255",
+		Role:       dom_users.RoleUser,
 	}
 
 	// dummyBackup1 は、あらかじめbackupsテーブルに追加したダミーバックアップです。
@@ -61,8 +71,17 @@ var (
 		SavedAt:  "2023-04-09 13:51:13",
 	}
 
-	backupRepos = inf_backups.NewBackupRepository(NewTestDBConnector())
-	userRepos   = inf_users.NewUserRepository(NewTestDBConnector())
+	// testMasterKey1 と testMasterKey2 は、テスト用のマスター鍵です。テストを繰り返しても復号できるよう、固定の値にしています。
+	testMasterKey1, _ = security.NewMasterKey("test-1", []byte("frognote-test-master-key-1-32byt"))
+	testMasterKey2, _ = security.NewMasterKey("test-2", []byte("frognote-test-master-key-2-32byt"))
+	testKeyRing, _    = security.NewKeyRing("test-1", []*security.MasterKey{testMasterKey1, testMasterKey2})
+
+	backupRepos        = inf_backups.NewBackupRepository(NewTestDBConnector(), testKeyRing)
+	userRepos          = inf_users.NewUserRepository(NewTestDBConnector())
+	sessionRepos       = inf_sessions.NewSessionRepository(NewTestDBConnector())
+	attemptRepos       = inf_attempts.NewAttemptRepository(NewTestDBConnector())
+	personalTokenRepos = inf_personaltokens.NewPersonalTokenRepository(NewTestDBConnector())
+	certificateRepos   = inf_clientcertificates.NewClientCertificateRepository(NewTestDBConnector())
 )
 
 func getDummyUser1SignInId() *dom_users.SignInId {
@@ -81,20 +100,299 @@ func TestStart(t *testing.T) {
 	 * テストを行う順番には制約があります。
 	 * 1．ユーザを探し出せるかを証明します
 	 * 2. バックアップを探し出せるかを証明します
-	 * 3. ユーザのライフサイクルをもとにテストします
-	 * 3.1 ユーザが作成できるかをテストします
-	 * 3.2 ユーザが更新されるかをテストします
-	 * 3.3 バックアップを保存できるかをテストします
-	 * 3.4 バックアップを削除できるかをテストします
-	 * 3.5 ユーザを削除できるかをテストします
+	 * 2.1 使用容量を取得できるかを証明します
+	 * 2.2 バックアップデータを暗号化して保存し、マスター鍵を切り替えられるかをテストします
+	 * 3. セッションのライフサイクルをもとにテストします
+	 * 3.1 認証の失敗を記録できるかをテストします
+	 * 3.2 パーソナルアクセストークンのライフサイクルをもとにテストします
+	 * 3.3 クライアント証明書の登録のライフサイクルをもとにテストします
+	 * 3.4 監査ログを記録・検索できるかをテストします
+	 * 4. ユーザのライフサイクルをもとにテストします
+	 * 4.1 ユーザが作成できるかをテストします
+	 * 4.2 ユーザが更新されるかをテストします
+	 * 4.3 二要素認証の状態を更新できるかをテストします
+	 * 4.3.1 アカウントを停止できるかをテストします
+	 * 4.3.2 リカバリーコードを保存・使用できるかをテストします
+	 * 4.4 バックアップを保存できるかをテストします
+	 * 4.5 バックアップを削除できるかをテストします
+	 * 4.6 ユーザを削除できるかをテストします
 	 */
 	t.Run("サインインIDをもとにユーザを探す", testFindUserBySignInId)
 	t.Run("ユーザIDをもとにユーザを探す", testFindUserByUserId)
 	t.Run("ユーザIDをもとにバックアップを探す", testFindBackupByUserId)
 	t.Run("バックアップIDをもとにバックアップを探す。", testFindBackupByBackupId)
+	t.Run("使用容量を取得する", testFindStorageUsage)
+	t.Run("バックアップを暗号化して保存する", testBackupEncryption)
+	t.Run("セッションを保存・取得・削除できるか", testSessionLifecycle)
+	t.Run("認証の失敗を記録・リセットできるか", testAttemptLifecycle)
+	t.Run("パーソナルアクセストークンを保存・取得・削除できるか", testPersonalTokenLifecycle)
+	t.Run("クライアント証明書の登録を保存・取得・削除できるか", testClientCertificateLifecycle)
+	t.Run("監査ログを記録・検索できるか", testAuditEvents)
 	t.Run("ユーザが作成できるか", testCreateUser)
 }
 
+// testSessionLifecycle は、セッションを保存・取得・更新・削除できるかをテストします。
+func testSessionLifecycle(t *testing.T) {
+	key := "0000000000000000000000000000000000000000000000000000000000000001"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	session := &security.Session{UserId: *dummyUser1Id, Kind: security.TokenKindRefresh, FamilyId: "family", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour),
+		Client: security.ClientInfo{RemoteAddr: "192.0.2.1", UserAgent: "test"}}
+	err := sessionRepos.Create(key, session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer sessionRepos.Delete(key)
+
+	if err = sessionRepos.Create(key, session); err != security.ErrSessionExists {
+		t.Errorf("duplicated session was created. %v", err)
+	}
+
+	later := now.Add(time.Minute)
+	if err = sessionRepos.Touch(key, later); err != nil {
+		t.Error(err)
+	}
+	fromRepos, err := sessionRepos.Find(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !fromRepos.UserId.Equals(dummyUser1Id) || fromRepos.Kind != session.Kind || fromRepos.FamilyId != session.FamilyId ||
+		!fromRepos.CreatedAt.Equal(now) || !fromRepos.LastUsedAt.Equal(later) || !fromRepos.ExpiresAt.Equal(session.ExpiresAt) || fromRepos.Used ||
+		fromRepos.Client != session.Client {
+		t.Error("invalid session data.")
+	}
+	byUser, err := sessionRepos.FindByUserId(dummyUser1Id)
+	if err != nil || len(byUser) != 1 || byUser[0].FamilyId != session.FamilyId {
+		t.Errorf("could not find sessions by user. %v", err)
+	}
+
+	otherKey := "0000000000000000000000000000000000000000000000000000000000000002"
+	other := *session
+	other.FamilyId = "other"
+	if err = sessionRepos.Create(otherKey, &other); err != nil {
+		t.Error(err)
+	}
+	if err = sessionRepos.DeleteByUserId(dummyUser1Id, session.FamilyId); err != nil {
+		t.Error(err)
+	}
+	if _, err = sessionRepos.Find(otherKey); err != security.ErrSessionNotFound {
+		t.Errorf("other session was not deleted. %v", err)
+	}
+	if _, err = sessionRepos.Find(key); err != nil {
+		t.Errorf("kept session was deleted. %v", err)
+	}
+
+	if err = sessionRepos.MarkUsed(key); err != nil {
+		t.Error(err)
+	}
+	if err = sessionRepos.MarkUsed(key); err != security.ErrSessionAlreadyUsed {
+		t.Errorf("session was marked as used twice. %v", err)
+	}
+
+	if err = sessionRepos.DeleteExpired(now, now, now); err != nil {
+		t.Error(err)
+	}
+	if _, err = sessionRepos.Find(key); err != security.ErrSessionNotFound {
+		t.Errorf("expired session was not deleted. %v", err)
+		return
+	}
+	t.Log("pass")
+}
+
+// testAttemptLifecycle は、認証の失敗を記録・リセット・削除できるかをテストします。
+func testAttemptLifecycle(t *testing.T) {
+	key := "0000000000000000000000000000000000000000000000000000000000000001"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	defer attemptRepos.Delete(key)
+
+	for i := 1; i <= 2; i++ {
+		attempt, err := attemptRepos.AddFailure(key, now, now.Add(-time.Hour))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if attempt.Failures != i || !attempt.LastFailureAt.Equal(now) {
+			t.Errorf("invalid attempt data. %v", attempt)
+		}
+	}
+
+	// 最後の失敗がresetBefore以前の場合は、1からやりなおす。
+	later := now.Add(2 * time.Hour)
+	attempt, err := attemptRepos.AddFailure(key, later, later.Add(-time.Hour))
+	if err != nil || attempt.Failures != 1 {
+		t.Errorf("failures were not reset. %v %v", attempt, err)
+	}
+
+	if err = attemptRepos.DeleteBefore(later); err != nil {
+		t.Error(err)
+	}
+	if attempt, _ := attemptRepos.Find(key); attempt.Failures != 0 {
+		t.Errorf("attempt was not deleted. %v", attempt)
+		return
+	}
+	t.Log("pass")
+}
+
+// testPersonalTokenLifecycle は、パーソナルアクセストークンを保存・取得・更新・削除できるかをテストします。
+func testPersonalTokenLifecycle(t *testing.T) {
+	key := "0000000000000000000000000000000000000000000000000000000000000001"
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	token := &security.PersonalToken{Id: "00000000-0000-0000-0000-000000000001", UserId: *dummyUser1Id, Name: "ci",
+		Scopes: []security.Scope{security.ScopeBackupRead, security.ScopeBackupWrite}, CreatedAt: now}
+	err := personalTokenRepos.Create(key, token)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer personalTokenRepos.DeleteByUserId(dummyUser1Id)
+
+	if err = personalTokenRepos.Create(key, token); err != security.ErrPersonalTokenExists {
+		t.Errorf("duplicated personal token was created. %v", err)
+	}
+
+	later := now.Add(time.Minute)
+	if err = personalTokenRepos.Touch(key, later); err != nil {
+		t.Error(err)
+	}
+	fromRepos, err := personalTokenRepos.Find(key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// 有効期限がない場合は、ゼロ値として復元される。
+	if fromRepos.Id != token.Id || !fromRepos.UserId.Equals(dummyUser1Id) || fromRepos.Name != token.Name ||
+		!fromRepos.HasScopes(token.Scopes...) || len(fromRepos.Scopes) != 2 || !fromRepos.CreatedAt.Equal(now) ||
+		!fromRepos.ExpiresAt.IsZero() || !fromRepos.LastUsedAt.Equal(later) {
+		t.Error("invalid personal token data.")
+	}
+
+	expiringKey := "0000000000000000000000000000000000000000000000000000000000000002"
+	expiring := *token
+	expiring.Id = "00000000-0000-0000-0000-000000000002"
+	expiring.ExpiresAt = now.Add(time.Hour)
+	if err = personalTokenRepos.Create(expiringKey, &expiring); err != nil {
+		t.Error(err)
+	}
+	byUser, err := personalTokenRepos.FindByUserId(dummyUser1Id)
+	if err != nil || len(byUser) != 2 {
+		t.Errorf("could not find personal tokens by user. %v", err)
+	}
+	if err = personalTokenRepos.DeleteExpired(now.Add(time.Hour)); err != nil {
+		t.Error(err)
+	}
+	if _, err = personalTokenRepos.Find(expiringKey); err != security.ErrPersonalTokenNotFound {
+		t.Errorf("expired personal token was not deleted. %v", err)
+	}
+
+	if err = personalTokenRepos.Delete(dom_users.NewUserId(2), token.Id); err != security.ErrPersonalTokenNotFound {
+		t.Errorf("other user's personal token was deleted. %v", err)
+	}
+	if err = personalTokenRepos.Delete(dummyUser1Id, token.Id); err != nil {
+		t.Error(err)
+	}
+	if _, err = personalTokenRepos.Find(key); err != security.ErrPersonalTokenNotFound {
+		t.Errorf("personal token was not deleted. %v", err)
+		return
+	}
+	t.Log("pass")
+}
+
+// testClientCertificateLifecycle は、クライアント証明書の登録を保存・取得・更新・削除できるかをテストします。
+func testClientCertificateLifecycle(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	certificate := &security.ClientCertificate{Id: "00000000-0000-0000-0000-000000000001", UserId: *dummyUser1Id, Name: "backup-host",
+		Match: security.CertificateMatchSubject, Value: "CN=backup-host,O=FrogNote", Scopes: []security.Scope{security.ScopeBackupWrite}, CreatedAt: now}
+	err := certificateRepos.Create(certificate)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer certificateRepos.DeleteByUserId(dummyUser1Id)
+
+	duplicated := *certificate
+	duplicated.Id = "00000000-0000-0000-0000-000000000002"
+	if err = certificateRepos.Create(&duplicated); err != security.ErrClientCertificateExists {
+		t.Errorf("duplicated client certificate was created. %v", err)
+	}
+
+	later := now.Add(time.Minute)
+	if err = certificateRepos.Touch(certificate.Id, later); err != nil {
+		t.Error(err)
+	}
+	fromRepos, err := certificateRepos.Find(security.CertificateMatchSubject, certificate.Value)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if fromRepos.Id != certificate.Id || !fromRepos.UserId.Equals(dummyUser1Id) || fromRepos.Name != certificate.Name ||
+		fromRepos.Match != certificate.Match || !fromRepos.HasScopes(certificate.Scopes...) || len(fromRepos.Scopes) != 1 ||
+		!fromRepos.CreatedAt.Equal(now) || !fromRepos.LastUsedAt.Equal(later) {
+		t.Error("invalid client certificate data.")
+	}
+	// 同じ値でも、対応付ける方法が異なる場合は見つからない。
+	if _, err = certificateRepos.Find(security.CertificateMatchFingerprint, certificate.Value); err != security.ErrClientCertificateNotFound {
+		t.Errorf("client certificate was found by another match. %v", err)
+	}
+	byUser, err := certificateRepos.FindByUserId(dummyUser1Id)
+	if err != nil || len(byUser) != 1 || !byUser[0].LastUsedAt.Equal(later) {
+		t.Errorf("could not find client certificates by user. %v", err)
+	}
+
+	if err = certificateRepos.Delete(dom_users.NewUserId(2), certificate.Id); err != security.ErrClientCertificateNotFound {
+		t.Errorf("other user's client certificate was deleted. %v", err)
+	}
+	if err = certificateRepos.Delete(dummyUser1Id, certificate.Id); err != nil {
+		t.Error(err)
+	}
+	if _, err = certificateRepos.Find(security.CertificateMatchSubject, certificate.Value); err != security.ErrClientCertificateNotFound {
+		t.Errorf("client certificate was not deleted. %v", err)
+		return
+	}
+	t.Log("pass")
+}
+
+// testAuditEvents は、監査ログを記録し、条件を指定して検索できるかをテストします。
+func testAuditEvents(t *testing.T) {
+	repos := inf_audits.NewAuditEventRepository(NewTestDBConnector())
+	dummy1, _ := userRepos.FindBySignInId(getDummyUser1SignInId())
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	events := []*dom_audits.AuditEvent{
+		{Type: dom_audits.EventSignInFailed, SignInId: "unknown", RemoteAddr: "192.0.2.1", CreatedAt: base},
+		{Type: dom_audits.EventSignInFailed, UserId: &dummy1.Id, SignInId: dummy1.SignInId.GetValue(), Detail: "incorrect_password", CreatedAt: base.Add(time.Second)},
+		{Type: dom_audits.EventSignInSucceeded, UserId: &dummy1.Id, SignInId: dummy1.SignInId.GetValue(), CreatedAt: base.Add(2 * time.Second)},
+	}
+	for _, event := range events {
+		if err := repos.Create(event); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	until := base.Add(3 * time.Second)
+
+	found, err := repos.Find(&dom_audits.Query{UserId: &dummy1.Id, Since: base, Until: until, Limit: 10})
+	if err != nil || len(found) != 2 {
+		t.Error("could not find by user.", err)
+		return
+	}
+	// 新しい順に取得できる。
+	if found[0].Type != dom_audits.EventSignInSucceeded || !found[0].CreatedAt.Equal(events[2].CreatedAt) || !found[0].UserId.Equals(&dummy1.Id) {
+		t.Error(found[0])
+		return
+	}
+	found, err = repos.Find(&dom_audits.Query{Type: dom_audits.EventSignInFailed, Since: base, Until: until, Limit: 10})
+	if err != nil || len(found) != 2 || found[1].UserId != nil || found[1].ActorId != nil || found[1].RemoteAddr != "192.0.2.1" {
+		t.Error("could not find by type.", err)
+		return
+	}
+	found, err = repos.Find(&dom_audits.Query{Since: base, Until: until, Limit: 1})
+	if err != nil || len(found) != 1 {
+		t.Error("could not limit.", err)
+		return
+	}
+	t.Log("pass")
+}
+
 // testFindUserByUserId は、ユーザIDをもとにユーザを取得できるかをテストします。
 func testFindUserByUserId(t *testing.T) {
 	dummyUser1FromRepos, err := userRepos.FindByUserId(&dummyBackup1.UserId)
@@ -122,6 +420,95 @@ func testFindBackupByBackupId(t *testing.T) {
 	}
 }
 
+// testBackupEncryption は、バックアップデータが暗号化して保存され、マスター鍵を切り替えた後も復号できるかをテストします。
+func testBackupEncryption(t *testing.T) {
+	plaintext := []byte("encrypted backup")
+	before, err := backupRepos.FindStorageUsage(dummyUser1Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if err := backupRepos.Create(dummyUser1Id, plaintext); err != nil {
+		t.Error(err)
+		return
+	}
+	// 使用容量は、暗号化する前の大きさで計算する。
+	after, err := backupRepos.FindStorageUsage(dummyUser1Id)
+	if err != nil || after.TotalBytes-before.TotalBytes != int64(len(plaintext)) {
+		t.Error("storage usage is not the plaintext size.", err)
+		return
+	}
+	database, err := NewTestDBConnector().Connect()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer database.Close()
+	var idValue int
+	var stored []byte
+	var dataKey []byte
+	var masterKeyId sql.NullString
+	err = database.QueryRow("select id, backup, data_key, master_key_id from backups where user_id = ? order by id desc limit 1", dummyUser1Id.GetValue()).Scan(&idValue, &stored, &dataKey, &masterKeyId)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	backupId := dom_backups.NewBackupId(idValue)
+	defer backupRepos.DeleteByBackupId(backupId)
+	// データ鍵は、マスター鍵ではなくユーザの鍵でラップされる。
+	if string(stored) == string(plaintext) || dataKey == nil || masterKeyId.Valid {
+		t.Error("backup is not encrypted.")
+		return
+	}
+
+	// マスター鍵を切り替え、ユーザの鍵をラップし直す。
+	rotatedKeyRing, _ := security.NewKeyRing("test-2", []*security.MasterKey{testMasterKey1, testMasterKey2})
+	if _, err := inf_backups.NewBackupRepository(NewTestDBConnector(), rotatedKeyRing).RotateMasterKey(); err != nil {
+		t.Error(err)
+		return
+	}
+	var userKeyMasterKeyId string
+	err = database.QueryRow("select master_key_id from user_keys where user_id = ?", dummyUser1Id.GetValue()).Scan(&userKeyMasterKeyId)
+	if err != nil || userKeyMasterKeyId != "test-2" {
+		t.Error("could not rewrap user key.", err)
+		return
+	}
+	// 古いマスター鍵がなくても復号できる。
+	newKeyRing, _ := security.NewKeyRing("test-2", []*security.MasterKey{testMasterKey2})
+	backup, err := inf_backups.NewBackupRepository(NewTestDBConnector(), newKeyRing).FindByBackupId(backupId)
+	if err != nil || string(backup.Backup) != string(plaintext) {
+		t.Error("could not decrypt after rotation.", err)
+		return
+	}
+	// マスター鍵を設定していない場合は、復号できない。
+	if _, err := inf_backups.NewBackupRepository(NewTestDBConnector(), nil).FindByBackupId(backupId); !errors.Is(err, inf_backups.ErrEncryptionDisabled) {
+		t.Error(err)
+		return
+	}
+	t.Log("pass")
+}
+
+// testFindStorageUsage は、ユーザごとの使用容量を取得できるかをテストします。
+func testFindStorageUsage(t *testing.T) {
+	usage, err := backupRepos.FindStorageUsage(dummyUser1Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	usages, err := backupRepos.FindStorageUsages()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, fromAll := range usages {
+		if fromAll.UserId.Equals(dummyUser1Id) && *fromAll == *usage && usage.BackupCount >= 1 && usage.TotalBytes >= int64(len(dummyBackup1.Backup)) {
+			t.Log("pass")
+			return
+		}
+	}
+	t.Error("invalid storage usage.")
+}
+
 // testFindUserBySignInId は、サインインIDをもとにユーザを取得できるかをテストします。
 func testFindUserBySignInId(t *testing.T) {
 	dummyUser1FromRepos, err := userRepos.FindBySignInId(&dummyUser1.SignInId)
@@ -161,11 +548,17 @@ func testCreateUser(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	// パスワードはハッシュ化される
-	hash := security.Hash{}
-	password = hash.GetHash(password)
+	// パスワードはソルト付きでハッシュ化される
+	passwords := security.Passwords{}
+	ok, _, _ := passwords.Verify(password, user.Password)
 
-	if user.Password == password && user.ScreenName == screenName && user.SignInId.Equals(getDummyUser2SignInId()) {
+	// 作成時刻が記録される
+	if time.Since(user.CreatedAt) > time.Minute {
+		t.Error("作成時刻が記録されていません。", user.CreatedAt)
+		return
+	}
+
+	if ok && user.ScreenName == screenName && user.SignInId.Equals(getDummyUser2SignInId()) {
 		t.Log("pass")
 		t.Run("ユーザが更新されるか", testUpdateUser)
 	}
@@ -187,9 +580,127 @@ func testUpdateUser(t *testing.T) {
 		return
 	}
 	t.Log("pass")
+	t.Run("二要素認証の状態を更新できるか", testUpdateTwoFactor)
+	t.Run("アカウントを停止できるか", testUpdateSuspended)
+	t.Run("アカウントの状態を取得できるか", testFindAccountStatus)
+	t.Run("リカバリーコードを保存・使用できるか", testRecoveryCodes)
 	t.Run("バックアップできるか", testCreateBackup)
 }
 
+// testUpdateTwoFactor は、二要素認証の状態を更新でき、同じ時間ステップのコードを二度使用できないかをテストします。
+func testUpdateTwoFactor(t *testing.T) {
+	dummy2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
+	twoFactor := &dom_users.TwoFactor{Secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", Enabled: true, LastUsedStep: 1}
+	if err := userRepos.UpdateTwoFactor(&dummy2.Id, twoFactor); err != nil {
+		t.Error(err)
+		return
+	}
+	defer userRepos.UpdateTwoFactor(&dummy2.Id, &dom_users.TwoFactor{})
+
+	newDummy2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
+	if newDummy2.TwoFactor != *twoFactor {
+		t.Error("could not update.")
+		return
+	}
+	if ok, err := userRepos.UseTotpStep(&dummy2.Id, 2); !ok || err != nil {
+		t.Error(err)
+	}
+	if ok, _ := userRepos.UseTotpStep(&dummy2.Id, 2); ok {
+		t.Error("totp step was used twice.")
+	}
+	t.Log("pass")
+}
+
+// testUpdateSuspended は、アカウントを停止・停止を解除でき、すべてのユーザの取得結果に反映されるかをテストします。
+func testUpdateSuspended(t *testing.T) {
+	dummy2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
+	if err := userRepos.UpdateSuspended(&dummy2.Id, true); err != nil {
+		t.Error(err)
+		return
+	}
+	defer userRepos.UpdateSuspended(&dummy2.Id, false)
+
+	all, err := userRepos.FindAll()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	found := false
+	for _, user := range all {
+		if user.Id.Equals(&dummy2.Id) {
+			found = user.Suspended && user.Role == dom_users.RoleUser
+		}
+	}
+	if !found {
+		t.Error("could not update.")
+		return
+	}
+	t.Log("pass")
+}
+
+// testFindAccountStatus は、停止中かと、トークンを無効化した時刻を取得できるかをテストします。
+func testFindAccountStatus(t *testing.T) {
+	dummy2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
+	status, err := userRepos.FindAccountStatus(&dummy2.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if status.Suspended || !status.TokensRevokedAt.IsZero() {
+		t.Error("unexpected status.", status)
+		return
+	}
+
+	revokedAt := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	if err = userRepos.RevokeTokens(&dummy2.Id, revokedAt); err != nil {
+		t.Error(err)
+		return
+	}
+	userRepos.UpdateSuspended(&dummy2.Id, true)
+	defer userRepos.UpdateSuspended(&dummy2.Id, false)
+	status, err = userRepos.FindAccountStatus(&dummy2.Id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !status.Suspended || !status.TokensRevokedAt.Equal(revokedAt) {
+		t.Error("could not update.", status)
+		return
+	}
+	t.Log("pass")
+}
+
+// testRecoveryCodes は、リカバリーコードを保存し、一度だけ使用できるかをテストします。
+func testRecoveryCodes(t *testing.T) {
+	dummy2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
+	if err := userRepos.ReplaceRecoveryCodes(&dummy2.Id, []string{"hash1", "hash2"}); err != nil {
+		t.Error(err)
+		return
+	}
+	// 再発行した場合は、それまでのコードが使用できなくなる。
+	if err := userRepos.ReplaceRecoveryCodes(&dummy2.Id, []string{"hash3", "hash4"}); err != nil {
+		t.Error(err)
+		return
+	}
+	if ok, err := userRepos.UseRecoveryCode(&dummy2.Id, "hash1"); ok || err != nil {
+		t.Error("old code could be used.", err)
+		return
+	}
+	if ok, err := userRepos.UseRecoveryCode(&dummy2.Id, "hash3"); !ok || err != nil {
+		t.Error("could not use.", err)
+		return
+	}
+	if ok, _ := userRepos.UseRecoveryCode(&dummy2.Id, "hash3"); ok {
+		t.Error("used code could be used again.")
+		return
+	}
+	if count, err := userRepos.CountRecoveryCodes(&dummy2.Id); count != 1 || err != nil {
+		t.Error(count, err)
+		return
+	}
+	t.Log("pass")
+}
+
 // testCreateBackup は、バックアップを作成できるかをテストします。
 func testCreateBackup(t *testing.T) {
 	dummyUser2, _ := userRepos.FindBySignInId(getDummyUser2SignInId())
@@ -253,6 +764,11 @@ func testDeleteUser(t *testing.T) {
 	if len := len(backupSlice); len != 0 {
 		t.Errorf("could not delete backups of dummyUser2. dummyUser2.Id = %d, %s", dummyUser2.Id.GetValue(), err.Error())
 	}
+	// ユーザの鍵が破棄され、バックアップデータの行が残っていても復号できない。
+	_, err = inf_userkeys.NewUserKeyRepository(NewTestDBConnector(), testKeyRing).Find(&dummyUser2.Id)
+	if !errors.Is(err, inf_userkeys.ErrUserKeyNotFound) {
+		t.Errorf("user key of dummyUser2 was not destroyed. %v", err)
+	}
 	t.Log("pass")
 }
 
@@ -269,5 +785,7 @@ func equalsUser(x *dom_users.User, y *dom_users.User) bool {
 	return x.Id.Equals(&y.Id) &&
 		x.SignInId.Equals(&y.SignInId) &&
 		x.Password == y.Password &&
-		x.ScreenName == y.ScreenName
+		x.ScreenName == y.ScreenName &&
+		x.Role == y.Role &&
+		x.Suspended == y.Suspended
 }
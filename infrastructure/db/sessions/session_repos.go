@@ -0,0 +1,195 @@
+package sessions
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/security"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrDuplicateEntry は、一意制約に違反した際のMySQLのエラー番号です。
+const mysqlErrDuplicateEntry = 1062
+
+// sessionColumns は、セッションを復元する際に取得する列です。mapSessionで読み取る順番と一致させる必要があります。
+const sessionColumns = "user_id, kind, family_id, created_at, last_used_at, expires_at, used, remote_addr, user_agent"
+
+// SessionRepository は、セッションをデータベースに永続化する構造体です。security.ISessionStoreを実装しています。
+// サーバを再起動してもセッションが維持されます。
+type SessionRepository struct {
+	connector db.IDBConnector
+}
+
+// Create は、セッションを新規保存します。同じキーのセッションがすでに存在する場合は、security.ErrSessionExistsを返却します。
+func (repos *SessionRepository) Create(key string, session *security.Session) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("insert into sessions (token_hash, user_id, kind, family_id, created_at, last_used_at, expires_at, used, remote_addr, user_agent) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		key, session.UserId.GetValue(), string(session.Kind), session.FamilyId, session.CreatedAt.UTC(), session.LastUsedAt.UTC(), session.ExpiresAt.UTC(), session.Used,
+		session.Client.RemoteAddr, session.Client.UserAgent)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry {
+		return security.ErrSessionExists
+	}
+	return err
+}
+
+// Find は、キーに紐づけられたセッションを取得します。存在しない場合は、security.ErrSessionNotFoundを返却します。
+func (repos *SessionRepository) Find(key string) (session *security.Session, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row := db.QueryRow("select "+sessionColumns+" from sessions where sessions.token_hash = ?", key)
+	session, err = mapSession(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, security.ErrSessionNotFound
+	}
+	return session, err
+}
+
+// FindByUserId は、ユーザのセッションをすべて取得します。
+func (repos *SessionRepository) FindByUserId(userId *users.UserId) (sessions []*security.Session, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("select "+sessionColumns+" from sessions where sessions.user_id = ?", userId.GetValue())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		session, err := mapSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Touch は、キーに紐づけられたセッションの最終利用時刻を更新します。
+func (repos *SessionRepository) Touch(key string, lastUsedAt time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update sessions set last_used_at = ? where token_hash = ?", lastUsedAt.UTC(), key)
+	return err
+}
+
+// MarkUsed は、キーに紐づけられたセッションを使用済みにします。すでに使用済みの場合は、security.ErrSessionAlreadyUsedを返却します。
+func (repos *SessionRepository) MarkUsed(key string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	// 未使用の場合のみ更新することで、同時に呼び出されても成功するのは一度だけになる。
+	result, err := db.Exec("update sessions set used = true where token_hash = ? and used = false", key)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return security.ErrSessionAlreadyUsed
+	}
+	return nil
+}
+
+// Delete は、キーに紐づけられたセッションを削除します。
+func (repos *SessionRepository) Delete(key string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from sessions where sessions.token_hash = ?", key)
+	return err
+}
+
+// DeleteFamily は、familyIdが同じセッションをすべて削除します。
+func (repos *SessionRepository) DeleteFamily(familyId string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from sessions where sessions.family_id = ?", familyId)
+	return err
+}
+
+// DeleteByUserId は、familyIdがexceptFamilyId以外のユーザのセッションをすべて削除します。一つの文で削除するため、呼び出した時点のセッションはすべて削除されます。
+func (repos *SessionRepository) DeleteByUserId(userId *users.UserId, exceptFamilyId string) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from sessions where sessions.user_id = ? and sessions.family_id <> ?", userId.GetValue(), exceptFamilyId)
+	return err
+}
+
+// DeleteExpired は、有効期限がnow以前のセッション、createdBefore以前に作成されたセッション、lastUsedBefore以前に最後に利用されたセッションをすべて削除します。
+func (repos *SessionRepository) DeleteExpired(now time.Time, createdBefore time.Time, lastUsedBefore time.Time) error {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("delete from sessions where expires_at <= ? or created_at <= ? or last_used_at <= ?", now.UTC(), createdBefore.UTC(), lastUsedBefore.UTC())
+	return err
+}
+
+// rowScanner は、*sql.Rowと*sql.Rowsに共通する、1行を読み取るためのインターフェースです。
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// mapSession は、rowからセッションを読み取ります。
+func mapSession(row rowScanner) (session *security.Session, err error) {
+	var userIdValue int
+	var kind string
+	var createdAtStr string
+	var lastUsedAtStr string
+	var expiresAtStr string
+	session = &security.Session{}
+	err = row.Scan(&userIdValue, &kind, &session.FamilyId, &createdAtStr, &lastUsedAtStr, &expiresAtStr, &session.Used, &session.Client.RemoteAddr, &session.Client.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	session.UserId = *users.NewUserId(userIdValue)
+	session.Kind = security.TokenKind(kind)
+	session.CreatedAt, err = db.ParseDateTime(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	session.LastUsedAt, err = db.ParseDateTime(lastUsedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt, err = db.ParseDateTime(expiresAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// NewSessionRepository は、SessionRepository構造体を初期化し、返却します。
+func NewSessionRepository(connector db.IDBConnector) (repos *SessionRepository) {
+	return &SessionRepository{connector: connector}
+}
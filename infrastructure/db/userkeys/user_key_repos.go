@@ -0,0 +1,126 @@
+package userkeys
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/security"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// ErrUserKeyNotFound は、ユーザの鍵が存在しないことを表すエラーです。アカウントの削除によって鍵が破棄された場合も含みます。
+var ErrUserKeyNotFound = errors.New("user key not found")
+
+// UserKeyRepository は、バックアップデータのデータ鍵をラップするための、ユーザごとの鍵を永続化する構造体です。
+// ユーザの鍵は、マスター鍵でラップして保存します。
+type UserKeyRepository struct {
+	connector db.IDBConnector
+	keyRing   *security.KeyRing
+}
+
+// Find は、ユーザの鍵を取得します。存在しない場合は、ErrUserKeyNotFoundを返却します。
+func (repos *UserKeyRepository) Find(userId *users.UserId) (key []byte, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	var wrappedKey []byte
+	var masterKeyId string
+	err = db.QueryRow("select wrapped_key, master_key_id from user_keys where user_id = ?", userId.GetValue()).Scan(&wrappedKey, &masterKeyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return repos.keyRing.Unwrap(wrappedKey, masterKeyId, userKeyAdditionalData(userId.GetValue()))
+}
+
+// FindOrCreate は、ユーザの鍵を取得します。存在しない場合は、生成して保存します。
+// 同時に生成された場合でも、保存されるのは一つだけで、どちらの呼び出しにも同じ鍵を返却します。
+func (repos *UserKeyRepository) FindOrCreate(userId *users.UserId) (key []byte, err error) {
+	key, err = repos.Find(userId)
+	if !errors.Is(err, ErrUserKeyNotFound) {
+		return key, err
+	}
+
+	key, err = security.NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, masterKeyId, err := repos.keyRing.Wrap(key, userKeyAdditionalData(userId.GetValue()))
+	if err != nil {
+		return nil, err
+	}
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	_, err = db.Exec("insert ignore into user_keys (user_id, wrapped_key, master_key_id, created_at) values (?, ?, ?, ?)", userId.GetValue(), wrappedKey, masterKeyId, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return repos.Find(userId)
+}
+
+// RotateMasterKey は、有効なマスター鍵以外でラップされたユーザの鍵を、すべて有効なマスター鍵でラップし直します。
+func (repos *UserKeyRepository) RotateMasterKey() (rewrapped int, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	// 更新しながら読み取らないよう、対象の鍵を先にすべて取得する。
+	type target struct {
+		userId      int
+		wrappedKey  []byte
+		masterKeyId string
+	}
+	rows, err := db.Query("select user_id, wrapped_key, master_key_id from user_keys where master_key_id <> ?", repos.keyRing.ActiveKeyId())
+	if err != nil {
+		return 0, err
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err = rows.Scan(&t.userId, &t.wrappedKey, &t.masterKeyId); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, t := range targets {
+		wrappedKey, masterKeyId, err := repos.keyRing.Rewrap(t.wrappedKey, t.masterKeyId, userKeyAdditionalData(t.userId))
+		if err != nil {
+			return rewrapped, err
+		}
+		_, err = db.Exec("update user_keys set wrapped_key = ?, master_key_id = ? where user_id = ? and master_key_id = ?", wrappedKey, masterKeyId, t.userId, t.masterKeyId)
+		if err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+	return rewrapped, nil
+}
+
+// userKeyAdditionalData は、ユーザの鍵をラップする際の付加データを返却します。他のユーザの行に移された鍵は、取り出せなくなります。
+func userKeyAdditionalData(userIdValue int) []byte {
+	return []byte("user-key:" + strconv.Itoa(userIdValue))
+}
+
+// NewUserKeyRepository は、UserKeyRepository構造体を初期化し、返却します。
+func NewUserKeyRepository(connector db.IDBConnector, keyRing *security.KeyRing) (repos *UserKeyRepository) {
+	return &UserKeyRepository{connector: connector, keyRing: keyRing}
+}
@@ -6,10 +6,14 @@ import (
 	"FrogNote_database/infrastructure/security"
 	"database/sql"
 	"errors"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// userColumns は、ユーザを復元する際に取得する列です。mapUserで読み取る順番と一致させる必要があります。
+const userColumns = "id, sign_in_id, password, screen_name, totp_secret, totp_enabled, totp_last_step, role, suspended, created_at"
+
 // UserRepository は、ユーザを永続化・復元する構造体です。
 type UserRepository struct {
 	connector db.IDBConnector
@@ -23,9 +27,12 @@ func (repos *UserRepository) Create(signInId *users.SignInId, password string, s
 	}
 	defer db.Close()
 	// パスワードをハッシュ化する。
-	hash := security.Hash{}
-	password = hash.GetHash(password)
-	_, err = db.Exec("insert into users values (default, ?, ?, ?)", signInId.GetValue(), password, screenName)
+	passwords := security.Passwords{}
+	password, err = passwords.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Exec("insert into users (sign_in_id, password, screen_name, created_at) values (?, ?, ?, ?)", signInId.GetValue(), password, screenName, time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -48,14 +55,171 @@ func (repos *UserRepository) Update(user *users.User) (err error) {
 	password := user.Password
 	// パスワードが変更された場合、ハッシュ値にする。
 	if old.Password != password {
-		hash := security.Hash{}
-		password = hash.GetHash(password)
+		passwords := security.Passwords{}
+		password, err = passwords.Hash(password)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = db.Exec("update users set sign_in_id = ?, password = ?, screen_name = ? where id = ?", user.SignInId.GetValue(), password, user.ScreenName, user.Id.GetValue())
 	return
 }
 
+// UpdatePassword は、パスワードをハッシュ化して更新します。旧形式のハッシュ値を現在の形式に移行する際にも使用します。
+func (repos *UserRepository) UpdatePassword(userId *users.UserId, password string) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	passwords := security.Passwords{}
+	hash, err := passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("update users set password = ? where id = ?", hash, userId.GetValue())
+	return
+}
+
+// UpdateTwoFactor は、二要素認証の状態を更新します。
+func (repos *UserRepository) UpdateTwoFactor(userId *users.UserId, twoFactor *users.TwoFactor) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update users set totp_secret = ?, totp_enabled = ?, totp_last_step = ? where id = ?", twoFactor.Secret, twoFactor.Enabled, twoFactor.LastUsedStep, userId.GetValue())
+	return
+}
+
+// UseTotpStep は、二要素認証のコードの時間ステップを使用済みにします。すでにstep以降の時間ステップが使用済みの場合は、falseを返却します。
+// 同時に同じコードで認証された場合でも、成功するのは一度だけです。
+func (repos *UserRepository) UseTotpStep(userId *users.UserId, step int64) (ok bool, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	result, err := db.Exec("update users set totp_last_step = ? where id = ? and totp_last_step < ?", step, userId.GetValue(), step)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// ReplaceRecoveryCodes は、ユーザのリカバリーコードをすべて削除し、新しいコードのハッシュ値を保存します。
+func (repos *UserRepository) ReplaceRecoveryCodes(userId *users.UserId, codeHashes []string) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.Exec("delete from recovery_codes where user_id = ?", userId.GetValue()); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if _, err = tx.Exec("insert into recovery_codes (user_id, code_hash) values (?, ?)", userId.GetValue(), codeHash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// UseRecoveryCode は、リカバリーコードを使用済みにします。コードが存在しない場合は、falseを返却します。
+// 同時に同じコードが使用された場合でも、成功するのは一度だけです。
+func (repos *UserRepository) UseRecoveryCode(userId *users.UserId, codeHash string) (ok bool, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+	result, err := db.Exec("delete from recovery_codes where user_id = ? and code_hash = ?", userId.GetValue(), codeHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CountRecoveryCodes は、ユーザの未使用のリカバリーコードの数を返却します。
+func (repos *UserRepository) CountRecoveryCodes(userId *users.UserId) (count int, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	err = db.QueryRow("select count(*) from recovery_codes where user_id = ?", userId.GetValue()).Scan(&count)
+	return
+}
+
+// UpdateSuspended は、アカウントを停止、または停止を解除します。
+func (repos *UserRepository) UpdateSuspended(userId *users.UserId, suspended bool) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update users set suspended = ? where id = ?", suspended, userId.GetValue())
+	return
+}
+
+// RevokeTokens は、ユーザのトークンをすべて無効化した時刻を記録します。この時刻より前に発行された署名付きのアクセストークンは認証されなくなります。
+func (repos *UserRepository) RevokeTokens(userId *users.UserId, revokedAt time.Time) (err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("update users set tokens_revoked_at = ? where id = ?", revokedAt.UTC(), userId.GetValue())
+	return
+}
+
+// FindAccountStatus は、リクエストを認証する際に確認する、アカウントの状態を取得します。
+func (repos *UserRepository) FindAccountStatus(userId *users.UserId) (status *security.AccountStatus, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	row := db.QueryRow("select suspended, tokens_revoked_at from users where id = ?", userId.GetValue())
+	return mapAccountStatus(row)
+}
+
+// FindAll は、すべてのユーザをユーザIDの順に取得します。
+func (repos *UserRepository) FindAll() (userSlice []*users.User, err error) {
+	db, err := repos.connector.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	rows, err := db.Query("select " + userColumns + " from users order by users.id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := mapUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		userSlice = append(userSlice, user)
+	}
+	return userSlice, rows.Err()
+}
+
 // FindBySignInId は、サインインIDをもとにユーザを取得します。
 func (repos *UserRepository) FindBySignInId(signInId *users.SignInId) (user *users.User, err error) {
 	db, err := repos.connector.Connect()
@@ -63,7 +227,7 @@ func (repos *UserRepository) FindBySignInId(signInId *users.SignInId) (user *use
 		return nil, err
 	}
 	defer db.Close()
-	row := db.QueryRow("select * from users where users.sign_in_id = ?", signInId.GetValue())
+	row := db.QueryRow("select "+userColumns+" from users where users.sign_in_id = ?", signInId.GetValue())
 	user, err = mapUser(row)
 	if err != nil {
 		return nil, err
@@ -79,7 +243,7 @@ func (repos *UserRepository) FindByUserId(userId *users.UserId) (user *users.Use
 	}
 
 	defer db.Close()
-	row := db.QueryRow("select * from users where users.id = ?", userId.GetValue())
+	row := db.QueryRow("select "+userColumns+" from users where users.id = ?", userId.GetValue())
 	user, err = mapUser(row)
 	if err != nil {
 		return nil, err
@@ -87,27 +251,45 @@ func (repos *UserRepository) FindByUserId(userId *users.UserId) (user *users.Use
 	return
 }
 
-// Delete は、指定したサインインIDのユーザを削除します。1
+// Delete は、指定したサインインIDのユーザを削除します。ユーザの鍵も破棄するため、暗号化して保存したバックアップデータは復号できなくなります。
 func (repos *UserRepository) Delete(signInId *users.SignInId) (err error) {
 	db, err := repos.connector.Connect()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
-	_, err = db.Exec("delete from users where users.sign_in_id = ?", signInId.GetValue())
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// バックアップデータを復号できなくするため、外部キーによる削除に任せず、ユーザの鍵を明示的に破棄する。
+	_, err = tx.Exec("delete user_keys from user_keys inner join users on users.id = user_keys.user_id where users.sign_in_id = ?", signInId.GetValue())
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("delete from users where users.sign_in_id = ?", signInId.GetValue())
 	if err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
+}
+
+// rowScanner は、*sql.Rowと*sql.Rowsに共通する、1行を読み取るためのインターフェースです。
+type rowScanner interface {
+	Scan(dest ...any) error
 }
 
 // mapUser は、rowから読み取ります。
-func mapUser(row *sql.Row) (user *users.User, err error) {
+func mapUser(row rowScanner) (user *users.User, err error) {
 	var userIdValue int
 	var signInIdStr string
+	var roleStr string
+	var createdAtStr sql.NullString
 	user = &users.User{}
 
-	err = row.Scan(&userIdValue, &signInIdStr, &user.Password, &user.ScreenName)
+	err = row.Scan(&userIdValue, &signInIdStr, &user.Password, &user.ScreenName, &user.TwoFactor.Secret, &user.TwoFactor.Enabled, &user.TwoFactor.LastUsedStep,
+		&roleStr, &user.Suspended, &createdAtStr)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -120,9 +302,35 @@ func mapUser(row *sql.Row) (user *users.User, err error) {
 	readSignInId, _ := users.NewSignInId(signInIdStr)
 	user.Id = *readUserId
 	user.SignInId = *readSignInId
+	user.Role = users.Role(roleStr)
+	// 作成時刻を記録する前に作成されたユーザは、nullになっている。
+	if createdAtStr.Valid {
+		user.CreatedAt, err = db.ParseDateTime(createdAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return user, nil
 }
 
+// mapAccountStatus は、rowからアカウントの状態を読み取ります。
+func mapAccountStatus(row rowScanner) (status *security.AccountStatus, err error) {
+	var revokedAtStr sql.NullString
+	status = &security.AccountStatus{}
+	err = row.Scan(&status.Suspended, &revokedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	// トークンを無効化したことがないユーザは、nullになっている。
+	if revokedAtStr.Valid {
+		status.TokensRevokedAt, err = db.ParseDateTime(revokedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
 // NewUserRepository は、UserRepositoryを初期化します。
 func NewUserRepository(connector db.IDBConnector) (repos *UserRepository) {
 	return &UserRepository{connector: connector}
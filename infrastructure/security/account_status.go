@@ -0,0 +1,21 @@
+package security
+
+import "time"
+
+// AccountStatus は、リクエストを認証する際に確認する、アカウントの状態を表現する構造体です。
+type AccountStatus struct {
+	// Suspended は、管理者によってアカウントが停止されているかを表します。
+	Suspended bool
+	// TokensRevokedAt は、パスワードの変更などで、ユーザのトークンをすべて無効化した時刻です。ゼロ値の場合は、無効化していません。
+	TokensRevokedAt time.Time
+}
+
+// IsActive は、アカウントが停止されておらず、issuedAtに発行されたアクセストークンが無効化されていない場合にtrueを返却します。
+// ストアに保存するアクセストークンなど、発行時刻を確認する必要がない場合は、issuedAtにゼロ値を指定します。
+func (status *AccountStatus) IsActive(issuedAt time.Time) bool {
+	if status.Suspended {
+		return false
+	}
+	// 署名付きのアクセストークンの発行時刻は秒単位のため、無効化した時刻も秒単位に切り捨てて比較する。
+	return issuedAt.IsZero() || !issuedAt.Before(status.TokensRevokedAt.Truncate(time.Second))
+}
@@ -0,0 +1,35 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"testing"
+	"time"
+)
+
+func TestAccountStatus(t *testing.T) {
+	revokedAt := time.Date(2023, 4, 1, 0, 0, 0, 500, time.UTC)
+
+	t.Run("停止中のアカウントは有効でない", func(t *testing.T) {
+		status := security.AccountStatus{Suspended: true}
+		if status.IsActive(time.Time{}) {
+			t.Error()
+		}
+	})
+
+	t.Run("無効化する前に発行されたアクセストークンは有効でない", func(t *testing.T) {
+		status := security.AccountStatus{TokensRevokedAt: revokedAt}
+		if status.IsActive(revokedAt.Add(-time.Second)) {
+			t.Error()
+		}
+		if !status.IsActive(revokedAt.Add(time.Second)) {
+			t.Error()
+		}
+	})
+
+	t.Run("発行時刻を確認しない場合は、無効化した時刻にかかわらず有効", func(t *testing.T) {
+		status := security.AccountStatus{TokensRevokedAt: revokedAt}
+		if !status.IsActive(time.Time{}) {
+			t.Error()
+		}
+	})
+}
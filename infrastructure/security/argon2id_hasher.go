@@ -0,0 +1,124 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2idPrefix は、Argon2idでエンコードされたハッシュ値の接頭辞です。
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idParams は、Argon2idのハッシュ化パラメータを表現する構造体です。
+type Argon2idParams struct {
+	// Memory は、使用するメモリ量(KiB)です。
+	Memory uint32
+	// Iterations は、反復回数です。
+	Iterations uint32
+	// Parallelism は、並列度です。
+	Parallelism uint8
+	// SaltLength は、ソルトのバイト長です。
+	SaltLength uint32
+	// KeyLength は、導出する鍵のバイト長です。
+	KeyLength uint32
+}
+
+// DefaultArgon2idParams は、Argon2idの既定のパラメータを返却します。
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+}
+
+// Argon2idHasher は、Argon2idでパスワードをハッシュ化する構造体です。
+// ハッシュ値は「$argon2id$v=19$m=65536,t=3,p=2$<ソルト>$<ハッシュ>」の形式でエンコードされます。
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// Hash は、パスワードをランダムなソルト付きでハッシュ化し、エンコード済みの文字列を返却します。
+func (h *Argon2idHasher) Hash(password string) (encoded string, err error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err = rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	return encodeArgon2id(h.params, salt, key), nil
+}
+
+// Verify は、パスワードとエンコード済みのハッシュ値を照合します。パラメータはハッシュ値から読み取ります。
+func (h *Argon2idHasher) Verify(password string, encoded string) (ok bool, err error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+// IsSupported は、エンコード済みのハッシュ値がArgon2idの形式であればtrueを返却します。
+func (h *Argon2idHasher) IsSupported(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+// NeedsRehash は、ハッシュ値がArgon2idでない場合や、パラメータが現在の設定と異なる場合にtrueを返却します。
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != h.params
+}
+
+// encodeArgon2id は、パラメータ・ソルト・鍵をエンコードした文字列を返却します。
+func encodeArgon2id(params Argon2idParams, salt []byte, key []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// decodeArgon2id は、エンコード済みの文字列からパラメータ・ソルト・鍵を読み取ります。
+func decodeArgon2id(encoded string) (params Argon2idParams, salt []byte, key []byte, err error) {
+	// 先頭が空文字列になるため、6要素に分割される。
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// NewArgon2idHasher は、Argon2idHasher構造体を初期化し、返却します。
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
@@ -0,0 +1,11 @@
+package security
+
+import "time"
+
+// Attempt は、認証に失敗した記録を表現する構造体です。
+type Attempt struct {
+	// Failures は、連続して認証に失敗した回数です。
+	Failures int
+	// LastFailureAt は、最後に認証に失敗した時刻です。
+	LastFailureAt time.Time
+}
@@ -0,0 +1,57 @@
+package security
+
+import (
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptHasher は、bcryptでパスワードをハッシュ化する構造体です。
+// bcryptのハッシュ値は、それ自体にバージョン・コスト・ソルトを含んでいます。
+type BcryptHasher struct {
+	cost int
+}
+
+// Hash は、パスワードをbcryptでハッシュ化し、エンコード済みの文字列を返却します。
+func (h *BcryptHasher) Hash(password string) (encoded string, err error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify は、パスワードとエンコード済みのハッシュ値を照合します。
+func (h *BcryptHasher) Verify(password string, encoded string) (ok bool, err error) {
+	err = bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsSupported は、エンコード済みのハッシュ値がbcryptの形式であればtrueを返却します。
+func (h *BcryptHasher) IsSupported(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+// NeedsRehash は、ハッシュ値がbcryptでない場合や、コストが現在の設定と異なる場合にtrueを返却します。
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost != h.cost
+}
+
+// NewBcryptHasher は、BcryptHasher構造体を初期化し、返却します。costは4以上31以下です。
+func NewBcryptHasher(cost int) (hasher *BcryptHasher, err error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, errors.New("'cost' must be between 4 to 31")
+	}
+	return &BcryptHasher{cost: cost}, nil
+}
@@ -0,0 +1,29 @@
+package security
+
+import (
+	"regexp"
+	"strings"
+)
+
+// bearerTokenPattern は、RFC 6750のb64tokenの書式です。
+var bearerTokenPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~+/]+=*$`)
+
+// ParseAuthorization は、Authorizationヘッダの値からトークンを取り出します。
+// RFC 6750のBearer形式("Bearer <token>"、スキーム名の大文字と小文字は区別しない)に加えて、以前の形式であるトークンのみの値も受け付けます。
+// それ以外のスキームや、トークンの書式が不正な場合は、okがfalseになります。
+func ParseAuthorization(value string) (token string, ok bool) {
+	scheme, rest, found := strings.Cut(value, " ")
+	if !found {
+		// スキームを含まない、以前の形式
+		token = value
+	} else {
+		if !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimLeft(rest, " ")
+	}
+	if !bearerTokenPattern.MatchString(token) {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,66 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"testing"
+)
+
+func TestParseAuthorization(t *testing.T) {
+	t.Run("正常", func(t *testing.T) {
+		type testCase struct {
+			testName string
+			value    string
+			token    string
+		}
+		testCases := []testCase{
+			{testName: "Bearer形式", value: "Bearer 0a1b2c3d-4e5f", token: "0a1b2c3d-4e5f"},
+			{testName: "スキーム名が小文字", value: "bearer fnp_abc", token: "fnp_abc"},
+			{testName: "空白が複数", value: "Bearer   abc.def.ghi", token: "abc.def.ghi"},
+			{testName: "末尾のパディング", value: "Bearer YWJj+/==", token: "YWJj+/=="},
+			{testName: "スキームを含まない以前の形式", value: "0a1b2c3d-4e5f", token: "0a1b2c3d-4e5f"},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.testName, func(t *testing.T) {
+				token, ok := security.ParseAuthorization(testCase.value)
+				if !ok || token != testCase.token {
+					t.Error(token)
+				}
+			})
+		}
+	})
+	t.Run("異常", func(t *testing.T) {
+		type testCase struct {
+			testName string
+			value    string
+		}
+		testCases := []testCase{
+			{testName: "空文字列", value: ""},
+			{testName: "トークンがない", value: "Bearer "},
+			{testName: "別のスキーム", value: "Basic dXNlcjpwYXNz"},
+			{testName: "トークンに空白を含む", value: "Bearer abc def"},
+			{testName: "使用できない文字", value: "Bearer abc,def"},
+			{testName: "途中のパディング", value: "Bearer ab=c"},
+		}
+		for _, testCase := range testCases {
+			t.Run(testCase.testName, func(t *testing.T) {
+				if _, ok := security.ParseAuthorization(testCase.value); ok {
+					t.Error()
+				}
+			})
+		}
+	})
+}
+
+func TestVerifyCsrfToken(t *testing.T) {
+	token, err := security.NewCsrfToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := security.NewCsrfToken()
+	if !security.VerifyCsrfToken(token, token) {
+		t.Error()
+	}
+	if security.VerifyCsrfToken(token, other) || security.VerifyCsrfToken("", "") || security.VerifyCsrfToken(token, "") {
+		t.Error()
+	}
+}
@@ -0,0 +1,76 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidChallenge は、チャレンジが存在しない、使用済み、有効期限切れ、または別のサインインID向けであることを表すエラーです。
+var ErrInvalidChallenge = errors.New("invalid challenge")
+
+// nonceLength は、チャレンジのナンスのバイト長です。
+const nonceLength = 32
+
+// challenge は、発行したチャレンジを表現する構造体です。
+type challenge struct {
+	signInId  string
+	expiresAt time.Time
+}
+
+// Challenges は、チャレンジ&レスポンス認証のためのナンスを発行・消費する構造体です。ナンスは一度しか使用できず、有効期限があります。
+type Challenges struct {
+	mutex      sync.Mutex
+	challenges map[string]challenge
+	timeout    time.Duration
+	clock      IClock
+}
+
+// Issue は、サインインID向けのナンスを発行します。
+func (c *Challenges) Issue(signInId string) (nonce string, expiresAt time.Time, err error) {
+	bytes := make([]byte, nonceLength)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", time.Time{}, err
+	}
+	nonce = base64.RawURLEncoding.EncodeToString(bytes)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := c.clock.Now()
+	c.deleteExpired(now)
+	expiresAt = now.Add(c.timeout)
+	c.challenges[nonce] = challenge{signInId: signInId, expiresAt: expiresAt}
+	return nonce, expiresAt, nil
+}
+
+// Consume は、ナンスを消費します。ナンスが発行されていない、使用済み、有効期限切れ、または別のサインインID向けの場合は、ErrInvalidChallengeを返却します。
+// 検証に失敗した場合も、ナンスは消費されます。
+func (c *Challenges) Consume(nonce string, signInId string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	found, ok := c.challenges[nonce]
+	if !ok {
+		return ErrInvalidChallenge
+	}
+	delete(c.challenges, nonce)
+	if !c.clock.Now().Before(found.expiresAt) || found.signInId != signInId {
+		return ErrInvalidChallenge
+	}
+	return nil
+}
+
+// deleteExpired は、有効期限が切れたナンスを削除します。
+func (c *Challenges) deleteExpired(now time.Time) {
+	for nonce, challenge := range c.challenges {
+		if !now.Before(challenge.expiresAt) {
+			delete(c.challenges, nonce)
+		}
+	}
+}
+
+// NewChallenges は、Challenges構造体を初期化し、返却します。timeoutは、ナンスの発行から無効になるまでの時間です。
+func NewChallenges(timeout time.Duration, clock IClock) *Challenges {
+	return &Challenges{challenges: make(map[string]challenge), timeout: timeout, clock: clock}
+}
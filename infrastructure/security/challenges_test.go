@@ -0,0 +1,121 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestChallenges(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	challenges := security.NewChallenges(time.Minute, clock)
+
+	t.Run("ナンスは一度しか使用できない", func(t *testing.T) {
+		nonce, _, err := challenges.Issue("frog")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := challenges.Consume(nonce, "frog"); err != nil {
+			t.Error(err)
+		}
+		if err := challenges.Consume(nonce, "frog"); !errors.Is(err, security.ErrInvalidChallenge) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("有効期限が切れたナンスは使用できない", func(t *testing.T) {
+		nonce, _, _ := challenges.Issue("frog")
+		clock.Advance(time.Minute)
+		if err := challenges.Consume(nonce, "frog"); !errors.Is(err, security.ErrInvalidChallenge) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("別のサインインID向けのナンスは使用できない", func(t *testing.T) {
+		nonce, _, _ := challenges.Issue("frog")
+		if err := challenges.Consume(nonce, "toad"); !errors.Is(err, security.ErrInvalidChallenge) {
+			t.Error(err)
+		}
+		// 失敗してもナンスは消費される。
+		if err := challenges.Consume(nonce, "frog"); !errors.Is(err, security.ErrInvalidChallenge) {
+			t.Error(err)
+		}
+	})
+}
+
+func TestVerifyProof(t *testing.T) {
+	security.UsePasswordHasher(security.NewScramArgon2idHasher(testArgon2idParams))
+	passwords := security.Passwords{}
+	stored, err := passwords.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := passwords.GetChallengeParams(stored)
+	if err != nil || params.Algorithm != security.ChallengeAlgorithmScramArgon2id {
+		t.Fatal(err)
+	}
+
+	// クライアントと同様に、パラメータとソルトを用いてパスワードから鍵を導出する。
+	deriveKey := func(password string) []byte {
+		return argon2.IDKey([]byte(password), params.Salt, params.Argon2id.Iterations, params.Argon2id.Memory, params.Argon2id.Parallelism, params.Argon2id.KeyLength)
+	}
+
+	t.Run("正しいパスワードから導出した鍵で照合できる", func(t *testing.T) {
+		proof := security.ComputeProof(deriveKey("password"), "nonce")
+		if ok, err := passwords.VerifyProof(stored, "nonce", proof); !ok || err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("誤ったパスワードや別のナンスでは照合できない", func(t *testing.T) {
+		if ok, _ := passwords.VerifyProof(stored, "nonce", security.ComputeProof(deriveKey("passwork"), "nonce")); ok {
+			t.Error()
+		}
+		if ok, _ := passwords.VerifyProof(stored, "nonce", security.ComputeProof(deriveKey("password"), "other")); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("保存済みのハッシュ値だけでは証明を作れない", func(t *testing.T) {
+		parts := strings.Split(stored, "$")
+		for _, part := range parts[5:] {
+			key, _ := base64.RawStdEncoding.DecodeString(part)
+			if ok, _ := passwords.VerifyProof(stored, "nonce", security.ComputeProof(key, "nonce")); ok {
+				t.Error()
+			}
+			if ok, _ := passwords.VerifyProof(stored, "nonce", key); ok {
+				t.Error()
+			}
+		}
+	})
+
+	t.Run("SCRAM形式以外のハッシュ値は対応しない", func(t *testing.T) {
+		hash := security.Hash{}
+		legacy := hash.GetHash("password")
+		argon2idHash, _ := security.NewArgon2idHasher(testArgon2idParams).Hash("password")
+		for _, other := range []string{legacy, argon2idHash} {
+			if _, err := passwords.GetChallengeParams(other); !errors.Is(err, security.ErrChallengeNotSupported) {
+				t.Error(err)
+			}
+			key, _ := hex.DecodeString(legacy)
+			if _, err := passwords.VerifyProof(other, "nonce", security.ComputeProof(key, "nonce")); !errors.Is(err, security.ErrChallengeNotSupported) {
+				t.Error(err)
+			}
+		}
+	})
+
+	t.Run("ダミーのパラメータは同じサインインIDなら同じになる", func(t *testing.T) {
+		x := passwords.GetDummyChallengeParams("frog")
+		y := passwords.GetDummyChallengeParams("frog")
+		z := passwords.GetDummyChallengeParams("toad")
+		if string(x.Salt) != string(y.Salt) || string(x.Salt) == string(z.Salt) {
+			t.Error()
+		}
+	})
+}
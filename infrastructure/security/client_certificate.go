@@ -0,0 +1,90 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrClientCertificateNotFound は、クライアント証明書の登録が見つからなかったことを表すエラーです。
+	ErrClientCertificateNotFound = errors.New("client certificate not found")
+	// ErrClientCertificateExists は、同じサブジェクトやフィンガープリントのクライアント証明書がすでに登録されていることを表すエラーです。
+	ErrClientCertificateExists = errors.New("client certificate already exists")
+	// ErrInvalidClientCertificate は、登録しようとしたサブジェクトやフィンガープリントの形式が正しくないことを表すエラーです。
+	ErrInvalidClientCertificate = errors.New("subject must be between 1 to 255 characters and fingerprint must be a SHA-256 hex digest")
+)
+
+// CertificateMatch は、クライアント証明書をユーザに対応付ける方法を表現する型です。
+type CertificateMatch string
+
+const (
+	// CertificateMatchSubject は、証明書のサブジェクトの識別名で対応付けます。証明書を更新しても登録し直す必要はありません。
+	CertificateMatchSubject CertificateMatch = "subject"
+	// CertificateMatchFingerprint は、証明書のSHA-256のフィンガープリントで対応付けます。証明書を更新した場合は登録し直す必要があります。
+	CertificateMatchFingerprint CertificateMatch = "fingerprint"
+)
+
+// NewCertificateMatch は、文字列からCertificateMatchを初期化し、返却します。未知の方法の場合は、エラーを返却します。
+func NewCertificateMatch(value string) (match CertificateMatch, err error) {
+	switch CertificateMatch(value) {
+	case CertificateMatchSubject, CertificateMatchFingerprint:
+		return CertificateMatch(value), nil
+	}
+	return "", fmt.Errorf("unknown certificate match: %s", value)
+}
+
+// ClientCertificate は、ユーザが登録した、相互TLS認証で使用するクライアント証明書を表現する構造体です。
+// 証明書そのものは保存せず、サブジェクトかフィンガープリントのどちらかで対応付けます。
+type ClientCertificate struct {
+	Id     string
+	UserId users.UserId
+	// Name は、用途を見分けるためにユーザがつけた名前です。
+	Name string
+	// Match は、証明書を対応付ける方法です。
+	Match CertificateMatch
+	// Value は、Matchに応じた、証明書のサブジェクトの識別名か、フィンガープリントです。
+	Value  string
+	Scopes []Scope
+	// CreatedAt は、登録した時刻です。
+	CreatedAt time.Time
+	// LastUsedAt は、最後に利用された時刻です。ゼロ値の場合は、まだ利用されていません。
+	LastUsedAt time.Time
+}
+
+// HasScopes は、scopesをすべてもつ場合にtrueを返却します。
+func (certificate *ClientCertificate) HasScopes(scopes ...Scope) bool {
+	return hasScopes(certificate.Scopes, scopes)
+}
+
+// CertificateSubject は、証明書のサブジェクトの識別名を、RFC 2253の形式で返却します。
+// "openssl x509 -noout -subject -nameopt RFC2253"の出力と一致するよう、証明書に含まれる順番を逆にして並べます。
+func CertificateSubject(certificate *x509.Certificate) string {
+	var rdns pkix.RDNSequence
+	if rest, err := asn1.Unmarshal(certificate.RawSubject, &rdns); err != nil || len(rest) != 0 {
+		return certificate.Subject.String()
+	}
+	return rdns.String()
+}
+
+// CertificateFingerprint は、証明書のSHA-256のフィンガープリントを、小文字の16進数で返却します。
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeFingerprint は、"AB:CD:..."のように区切られたフィンガープリントを、小文字の16進数に変換します。SHA-256のフィンガープリントでない場合は、エラーを返却します。
+func NormalizeFingerprint(value string) (fingerprint string, err error) {
+	fingerprint = strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(value))
+	if decoded, err := hex.DecodeString(fingerprint); err != nil || len(decoded) != sha256.Size {
+		return "", errors.New("fingerprint must be a SHA-256 hex digest")
+	}
+	return fingerprint, nil
+}
@@ -0,0 +1,102 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"crypto/x509"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxCertificateSubjectLength は、登録できるサブジェクトの識別名の最大の長さです。
+const maxCertificateSubjectLength = 255
+
+// ClientCertificates は、相互TLS認証で使用するクライアント証明書の登録、検証、削除を行う構造体です。登録はIClientCertificateStoreに保存します。
+// 証明書の署名の検証はTLSのハンドシェイクで行われるため、この構造体は検証済みの証明書をユーザに対応付けるだけです。
+type ClientCertificates struct {
+	store IClientCertificateStore
+	clock IClock
+}
+
+// Register は、ユーザのクライアント証明書を登録します。フィンガープリントは、小文字の16進数に変換して保存します。
+// サブジェクトやフィンガープリントの形式が正しくない場合は、ErrInvalidClientCertificateを返却します。
+// 同じサブジェクトやフィンガープリントがすでに登録されている場合は、ErrClientCertificateExistsを返却します。
+func (certificates *ClientCertificates) Register(userId *users.UserId, name string, match CertificateMatch, value string, scopes []Scope) (certificate *ClientCertificate, err error) {
+	switch match {
+	case CertificateMatchFingerprint:
+		if value, err = NormalizeFingerprint(value); err != nil {
+			return nil, ErrInvalidClientCertificate
+		}
+	case CertificateMatchSubject:
+		value = strings.TrimSpace(value)
+		if value == "" || len(value) > maxCertificateSubjectLength {
+			return nil, ErrInvalidClientCertificate
+		}
+	default:
+		return nil, ErrInvalidClientCertificate
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	certificate = &ClientCertificate{
+		Id:        id.String(),
+		UserId:    *userId,
+		Name:      name,
+		Match:     match,
+		Value:     value,
+		Scopes:    scopes,
+		CreatedAt: certificates.clock.Now(),
+	}
+	if err = certificates.store.Create(certificate); err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// Authenticate は、TLSのハンドシェイクで検証されたクライアント証明書に対応する登録を探し、見つかった場合は最終利用時刻を更新して返却します。
+// フィンガープリントでの登録を、サブジェクトでの登録より優先します。
+func (certificates *ClientCertificates) Authenticate(verified *x509.Certificate) (certificate *ClientCertificate, ok bool) {
+	certificate, err := certificates.store.Find(CertificateMatchFingerprint, CertificateFingerprint(verified))
+	if errors.Is(err, ErrClientCertificateNotFound) {
+		certificate, err = certificates.store.Find(CertificateMatchSubject, CertificateSubject(verified))
+	}
+	if err != nil {
+		return nil, false
+	}
+	now := certificates.clock.Now()
+	if err = certificates.store.Touch(certificate.Id, now); err != nil {
+		return nil, false
+	}
+	certificate.LastUsedAt = now
+	return certificate, true
+}
+
+// List は、ユーザのクライアント証明書の登録を、登録された順に返却します。
+func (certificates *ClientCertificates) List(userId *users.UserId) (found []*ClientCertificate, err error) {
+	found, err = certificates.store.FindByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].CreatedAt.Before(found[j].CreatedAt)
+	})
+	return found, nil
+}
+
+// Revoke は、IDを指定してユーザのクライアント証明書の登録を削除します。ユーザの登録でない場合は、ErrClientCertificateNotFoundを返却します。
+func (certificates *ClientCertificates) Revoke(userId *users.UserId, id string) error {
+	return certificates.store.Delete(userId, id)
+}
+
+// RevokeAll は、ユーザのクライアント証明書の登録をすべて削除します。パスワードの変更や退会の際に使用します。
+func (certificates *ClientCertificates) RevokeAll(userId *users.UserId) error {
+	return certificates.store.DeleteByUserId(userId)
+}
+
+// NewClientCertificates は、ClientCertificates構造体を初期化し、返却します。
+func NewClientCertificates(store IClientCertificateStore, clock IClock) *ClientCertificates {
+	return &ClientCertificates{store: store, clock: clock}
+}
@@ -0,0 +1,141 @@
+package security_test
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClientCertificates は、メモリ上に保存するClientCertificatesを返却します。
+func newTestClientCertificates() (*security.ClientCertificates, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	return security.NewClientCertificates(security.NewMemoryClientCertificateStore(), clock), clock
+}
+
+// newTestCertificate は、サブジェクトのCNがcommonNameの自己署名証明書を生成します。
+func newTestCertificate(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"FrogNote"}},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestClientCertificates(t *testing.T) {
+	userId := users.NewUserId(1)
+	scopes := []security.Scope{security.ScopeBackupWrite}
+	t.Run("フィンガープリントで登録した証明書で認証できる", func(t *testing.T) {
+		certificates, clock := newTestClientCertificates()
+		certificate := newTestCertificate(t, "backup-host")
+		// OpenSSLが出力する、コロンで区切られた大文字の形式でも登録できる
+		fingerprint := strings.ToUpper(security.CertificateFingerprint(certificate))
+		var separated []string
+		for i := 0; i < len(fingerprint); i += 2 {
+			separated = append(separated, fingerprint[i:i+2])
+		}
+		registered, err := certificates.Register(userId, "backup-host", security.CertificateMatchFingerprint, strings.Join(separated, ":"), scopes)
+		if err != nil || registered.Value != security.CertificateFingerprint(certificate) {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Hour)
+		authenticated, ok := certificates.Authenticate(certificate)
+		if !ok || authenticated.Id != registered.Id || !authenticated.UserId.Equals(userId) || !authenticated.LastUsedAt.Equal(clock.Now()) {
+			t.Error()
+		}
+		if !authenticated.HasScopes(security.ScopeBackupWrite) || authenticated.HasScopes(security.ScopeBackupRead) {
+			t.Error()
+		}
+		// 同じサブジェクトでも、別の証明書では認証できない
+		if _, ok = certificates.Authenticate(newTestCertificate(t, "backup-host")); ok {
+			t.Error()
+		}
+	})
+	t.Run("サブジェクトで登録した場合は、更新した証明書でも認証できる", func(t *testing.T) {
+		certificates, _ := newTestClientCertificates()
+		certificate := newTestCertificate(t, "backup-host")
+		if _, err := certificates.Register(userId, "backup-host", security.CertificateMatchSubject, security.CertificateSubject(certificate), scopes); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := certificates.Authenticate(newTestCertificate(t, "backup-host")); !ok {
+			t.Error()
+		}
+		if _, ok := certificates.Authenticate(newTestCertificate(t, "other-host")); ok {
+			t.Error()
+		}
+	})
+	t.Run("フィンガープリントでの登録をサブジェクトでの登録より優先する", func(t *testing.T) {
+		certificates, _ := newTestClientCertificates()
+		certificate := newTestCertificate(t, "backup-host")
+		certificates.Register(users.NewUserId(2), "subject", security.CertificateMatchSubject, security.CertificateSubject(certificate), scopes)
+		certificates.Register(userId, "fingerprint", security.CertificateMatchFingerprint, security.CertificateFingerprint(certificate), scopes)
+		if authenticated, ok := certificates.Authenticate(certificate); !ok || authenticated.Name != "fingerprint" {
+			t.Error()
+		}
+	})
+	t.Run("同じ証明書は重複して登録できない", func(t *testing.T) {
+		certificates, _ := newTestClientCertificates()
+		subject := security.CertificateSubject(newTestCertificate(t, "backup-host"))
+		certificates.Register(userId, "backup-host", security.CertificateMatchSubject, subject, scopes)
+		if _, err := certificates.Register(users.NewUserId(2), "backup-host", security.CertificateMatchSubject, subject, scopes); !errors.Is(err, security.ErrClientCertificateExists) {
+			t.Error(err)
+		}
+	})
+	t.Run("不正なフィンガープリントやサブジェクトは登録できない", func(t *testing.T) {
+		certificates, _ := newTestClientCertificates()
+		if _, err := certificates.Register(userId, "ci", security.CertificateMatchFingerprint, "abcd", scopes); !errors.Is(err, security.ErrInvalidClientCertificate) {
+			t.Error(err)
+		}
+		if _, err := certificates.Register(userId, "ci", security.CertificateMatchSubject, " ", scopes); !errors.Is(err, security.ErrInvalidClientCertificate) {
+			t.Error(err)
+		}
+		if _, err := security.NewCertificateMatch("serial"); err == nil {
+			t.Error()
+		}
+	})
+	t.Run("削除した登録では認証できない", func(t *testing.T) {
+		certificates, _ := newTestClientCertificates()
+		certificate := newTestCertificate(t, "backup-host")
+		other := newTestCertificate(t, "other-host")
+		registered, _ := certificates.Register(userId, "backup-host", security.CertificateMatchFingerprint, security.CertificateFingerprint(certificate), scopes)
+		certificates.Register(userId, "other-host", security.CertificateMatchFingerprint, security.CertificateFingerprint(other), scopes)
+		// 別のユーザの登録は削除できない
+		if err := certificates.Revoke(users.NewUserId(2), registered.Id); !errors.Is(err, security.ErrClientCertificateNotFound) {
+			t.Error(err)
+		}
+		if err := certificates.Revoke(userId, registered.Id); err != nil {
+			t.Error(err)
+		}
+		if _, ok := certificates.Authenticate(certificate); ok {
+			t.Error()
+		}
+		if err := certificates.RevokeAll(userId); err != nil {
+			t.Error(err)
+		}
+		if found, err := certificates.List(userId); err != nil || len(found) != 0 {
+			t.Error(err)
+		}
+	})
+}
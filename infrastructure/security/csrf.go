@@ -0,0 +1,27 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// csrfTokenBytes は、CSRFトークンに含めるランダムなバイト数です。
+const csrfTokenBytes = 32
+
+// NewCsrfToken は、ダブルサブミットクッキー方式で使用する、新しいCSRFトークンを生成します。
+func NewCsrfToken() (token string, err error) {
+	bytes := make([]byte, csrfTokenBytes)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// VerifyCsrfToken は、クッキーのCSRFトークンと、リクエストヘッダのCSRFトークンが一致する場合にtrueを返却します。どちらかが空の場合は、falseを返却します。
+func VerifyCsrfToken(cookieToken string, headerToken string) bool {
+	if cookieToken == "" || headerToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(headerToken)) == 1
+}
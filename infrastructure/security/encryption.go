@@ -0,0 +1,66 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// DataKeySize は、データの暗号化に使用する鍵(AES-256)のバイト数です。
+const DataKeySize = 32
+
+// ErrDecryptionFailed は、鍵や付加データが異なる場合や、暗号文が改ざんされている場合に、復号できなかったことを表すエラーです。
+var ErrDecryptionFailed = errors.New("could not decrypt")
+
+// NewDataKey は、データを暗号化するためのランダムな鍵を生成します。
+func NewDataKey() (key []byte, err error) {
+	key = make([]byte, DataKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// SealWithKey は、AES-256-GCMでplaintextを暗号化し、ランダムなノンスを先頭に付けて返却します。
+// additionalDataは暗号化されませんが、復号する際に同じ値が必要になります。
+func SealWithKey(key []byte, plaintext []byte, additionalData []byte) (sealed []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// OpenWithKey は、SealWithKeyで暗号化したデータを復号します。復号できない場合は、ErrDecryptionFailedを返却します。
+func OpenWithKey(key []byte, sealed []byte, additionalData []byte) (plaintext []byte, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecryptionFailed
+	}
+	nonceSize := aead.NonceSize()
+	plaintext, err = aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// newGCM は、AES-256-GCMのAEADを初期化します。
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != DataKeySize {
+		return nil, errors.New("key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -9,6 +9,7 @@ import (
 type Hash struct{}
 
 // GetHash は、与えられた文字列をSHA-256で、10,000回ハッシュ化します。
+// ソルトを用いないため、パスワードのハッシュ化にはPasswordsを使用してください。旧形式のハッシュ値の照合にのみ使用しています。
 func (h *Hash) GetHash(str string) string {
 	sha := sha256.New()
 	result := str
@@ -0,0 +1,10 @@
+package security
+
+import "FrogNote_database/domain/users"
+
+// IAccountStatusStore は、リクエストを認証する際に確認する、アカウントの状態を取得するストアのインターフェースです。
+// 実装は、複数のゴルーチンから同時に呼び出されても安全である必要があります。
+type IAccountStatusStore interface {
+	// FindAccountStatus は、ユーザのアカウントの状態を取得します。ユーザが存在しない場合は、エラーを返却します。
+	FindAccountStatus(userId *users.UserId) (status *AccountStatus, err error)
+}
@@ -0,0 +1,17 @@
+package security
+
+import "time"
+
+// IAttemptStore は、認証に失敗した記録を保存するストアのインターフェースです。キーにはサインインIDやリモートアドレスそのものではなく、ハッシュ値を用います。
+// 実装は、複数のゴルーチンから同時に呼び出されても安全である必要があります。
+type IAttemptStore interface {
+	// Find は、キーの記録を取得します。存在しない場合は、失敗回数が0の記録を返却します。
+	Find(key string) (attempt *Attempt, err error)
+	// AddFailure は、キーの失敗回数を1増やし、最終失敗時刻をnowにして、更新後の記録を返却します。最後の失敗がresetBefore以前の場合は、失敗回数を1からやりなおします。
+	// 同時に呼び出された場合でも、失敗回数が失われてはいけません。
+	AddFailure(key string, now time.Time, resetBefore time.Time) (attempt *Attempt, err error)
+	// Delete は、キーの記録を削除します。
+	Delete(key string) error
+	// DeleteBefore は、最後の失敗がlastFailureBefore以前の記録をすべて削除します。
+	DeleteBefore(lastFailureBefore time.Time) error
+}
@@ -0,0 +1,23 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"time"
+)
+
+// IClientCertificateStore は、ユーザが登録したクライアント証明書を保存するストアのインターフェースです。
+// 実装は、複数のゴルーチンから同時に呼び出されても安全である必要があります。
+type IClientCertificateStore interface {
+	// Create は、クライアント証明書を新規登録します。同じ方法と値の証明書がすでに登録されている場合は、ErrClientCertificateExistsを返却します。
+	Create(certificate *ClientCertificate) error
+	// Find は、対応付ける方法と値をもとにクライアント証明書を取得します。存在しない場合は、ErrClientCertificateNotFoundを返却します。
+	Find(match CertificateMatch, value string) (certificate *ClientCertificate, err error)
+	// FindByUserId は、ユーザのクライアント証明書をすべて取得します。
+	FindByUserId(userId *users.UserId) (certificates []*ClientCertificate, err error)
+	// Touch は、クライアント証明書の最終利用時刻を更新します。
+	Touch(id string, lastUsedAt time.Time) error
+	// Delete は、IDを指定してユーザのクライアント証明書を削除します。存在しない場合は、ErrClientCertificateNotFoundを返却します。
+	Delete(userId *users.UserId, id string) error
+	// DeleteByUserId は、ユーザのクライアント証明書をすべて削除します。
+	DeleteByUserId(userId *users.UserId) error
+}
@@ -0,0 +1,9 @@
+package security
+
+import "time"
+
+// IClock は、現在時刻を取得するためのインターフェースです。テスト時に時刻を差し替えるために使用します。
+type IClock interface {
+	// Now は、現在時刻を返却します。
+	Now() time.Time
+}
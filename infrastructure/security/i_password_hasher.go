@@ -0,0 +1,13 @@
+package security
+
+// IPasswordHasher は、パスワードをハッシュ化・照合するハッシャのインターフェースです。
+type IPasswordHasher interface {
+	// Hash は、パスワードをソルト付きでハッシュ化し、アルゴリズム・パラメータ・ソルトを含むエンコード済みの文字列を返却します。
+	Hash(password string) (encoded string, err error)
+	// Verify は、パスワードとエンコード済みのハッシュ値を照合します。一致すればtrueを返却します。
+	Verify(password string, encoded string) (ok bool, err error)
+	// IsSupported は、エンコード済みのハッシュ値がこのハッシャの形式であればtrueを返却します。
+	IsSupported(encoded string) bool
+	// NeedsRehash は、エンコード済みのハッシュ値が現在の設定と異なり、再ハッシュ化が必要な場合にtrueを返却します。
+	NeedsRehash(encoded string) bool
+}
@@ -0,0 +1,25 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"time"
+)
+
+// IPersonalTokenStore は、パーソナルアクセストークンを保存するストアのインターフェースです。キーにはトークンそのものではなく、トークンのハッシュ値を用います。
+// 実装は、複数のゴルーチンから同時に呼び出されても安全である必要があります。
+type IPersonalTokenStore interface {
+	// Create は、パーソナルアクセストークンを新規保存します。同じキーのトークンがすでに存在する場合は、ErrPersonalTokenExistsを返却します。
+	Create(key string, token *PersonalToken) error
+	// Find は、キーに紐づけられたパーソナルアクセストークンを取得します。存在しない場合は、ErrPersonalTokenNotFoundを返却します。
+	Find(key string) (token *PersonalToken, err error)
+	// FindByUserId は、ユーザのパーソナルアクセストークンをすべて取得します。
+	FindByUserId(userId *users.UserId) (tokens []*PersonalToken, err error)
+	// Touch は、キーに紐づけられたパーソナルアクセストークンの最終利用時刻を更新します。
+	Touch(key string, lastUsedAt time.Time) error
+	// Delete は、IDを指定してユーザのパーソナルアクセストークンを削除します。存在しない場合は、ErrPersonalTokenNotFoundを返却します。
+	Delete(userId *users.UserId, id string) error
+	// DeleteByUserId は、ユーザのパーソナルアクセストークンをすべて削除します。
+	DeleteByUserId(userId *users.UserId) error
+	// DeleteExpired は、有効期限がnow以前のパーソナルアクセストークンをすべて削除します。
+	DeleteExpired(now time.Time) error
+}
@@ -0,0 +1,31 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"time"
+)
+
+// ISessionStore は、セッションを保存するストアのインターフェースです。キーにはトークンそのものではなく、トークンのハッシュ値を用います。
+// 実装は、複数のゴルーチンから同時に呼び出されても安全である必要があります。
+type ISessionStore interface {
+	// Create は、セッションを新規保存します。同じキーのセッションがすでに存在する場合は、ErrSessionExistsを返却します。
+	Create(key string, session *Session) error
+	// Find は、キーに紐づけられたセッションを取得します。存在しない場合は、ErrSessionNotFoundを返却します。
+	Find(key string) (session *Session, err error)
+	// FindByUserId は、ユーザのセッションをすべて取得します。
+	FindByUserId(userId *users.UserId) (sessions []*Session, err error)
+	// Touch は、キーに紐づけられたセッションの最終利用時刻を更新します。
+	Touch(key string, lastUsedAt time.Time) error
+	// MarkUsed は、キーに紐づけられたセッションを使用済みにします。すでに使用済みの場合は、ErrSessionAlreadyUsedを返却します。
+	// 同時に呼び出された場合でも、成功するのは一度だけである必要があります。
+	MarkUsed(key string) error
+	// Delete は、キーに紐づけられたセッションを削除します。
+	Delete(key string) error
+	// DeleteFamily は、familyIdが同じセッションをすべて削除します。
+	DeleteFamily(familyId string) error
+	// DeleteByUserId は、familyIdがexceptFamilyId以外のユーザのセッションをすべて削除します。exceptFamilyIdが空文字列の場合は、すべて削除します。
+	// 呼び出した時点で保存されているユーザのセッションは、すべて削除される必要があります。
+	DeleteByUserId(userId *users.UserId, exceptFamilyId string) error
+	// DeleteExpired は、有効期限がnow以前のセッション、createdBefore以前に作成されたセッション、lastUsedBefore以前に最後に利用されたセッションをすべて削除します。
+	DeleteExpired(now time.Time, createdBefore time.Time, lastUsedBefore time.Time) error
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrMasterKeyNotFound は、暗号化に使用したマスター鍵が、キーリングに存在しないことを表すエラーです。
+var ErrMasterKeyNotFound = errors.New("master key not found")
+
+// MasterKey は、データの暗号化に使用した鍵を暗号化(ラップ)するための鍵を表現する構造体です。
+type MasterKey struct {
+	// Id は、鍵のIDです。ラップした鍵とともに保存し、復号時に鍵を選択するために使用します。
+	Id  string
+	key []byte
+}
+
+// NewMasterKey は、MasterKey構造体を初期化し、返却します。keyは32バイトである必要があります。
+func NewMasterKey(id string, key []byte) (masterKey *MasterKey, err error) {
+	if id == "" {
+		return nil, errors.New("master key ID must not be empty")
+	}
+	if len(key) != DataKeySize {
+		return nil, fmt.Errorf("master key %s must be 32 bytes", id)
+	}
+	return &MasterKey{Id: id, key: key}, nil
+}
+
+// LoadMasterKey は、32バイトの鍵をそのまま書き込んだファイルから、マスター鍵を読み込みます。
+func LoadMasterKey(id string, path string) (masterKey *MasterKey, err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewMasterKey(id, bytes)
+}
+
+// KeyRing は、マスター鍵の一覧を表現する構造体です。新しい鍵のラップには有効な鍵を使用し、復号には鍵のIDに対応する鍵を使用します。
+// 有効な鍵を切り替えた後も、古い鍵でラップした鍵をすべてラップし直すまでは、古い鍵をキーリングに残しておく必要があります。
+type KeyRing struct {
+	active *MasterKey
+	keys   map[string]*MasterKey
+}
+
+// Wrap は、有効なマスター鍵で鍵をラップし、使用したマスター鍵のIDとともに返却します。
+func (keyRing *KeyRing) Wrap(key []byte, additionalData []byte) (wrapped []byte, masterKeyId string, err error) {
+	wrapped, err = SealWithKey(keyRing.active.key, key, additionalData)
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, keyRing.active.Id, nil
+}
+
+// Unwrap は、IDで指定したマスター鍵で、ラップされた鍵を復号します。
+func (keyRing *KeyRing) Unwrap(wrapped []byte, masterKeyId string, additionalData []byte) (key []byte, err error) {
+	masterKey, ok := keyRing.keys[masterKeyId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMasterKeyNotFound, masterKeyId)
+	}
+	return OpenWithKey(masterKey.key, wrapped, additionalData)
+}
+
+// Rewrap は、ラップされた鍵を復号し、有効なマスター鍵でラップし直します。鍵そのものは変わらないため、その鍵で暗号化したデータを暗号化し直す必要はありません。
+func (keyRing *KeyRing) Rewrap(wrapped []byte, masterKeyId string, additionalData []byte) (rewrapped []byte, newMasterKeyId string, err error) {
+	key, err := keyRing.Unwrap(wrapped, masterKeyId, additionalData)
+	if err != nil {
+		return nil, "", err
+	}
+	return keyRing.Wrap(key, additionalData)
+}
+
+// ActiveKeyId は、有効なマスター鍵のIDを返却します。
+func (keyRing *KeyRing) ActiveKeyId() string {
+	return keyRing.active.Id
+}
+
+// NewKeyRing は、KeyRing構造体を初期化し、返却します。activeKeyIdの鍵が、鍵のラップに使用されます。
+func NewKeyRing(activeKeyId string, masterKeys []*MasterKey) (keyRing *KeyRing, err error) {
+	keyRing = &KeyRing{keys: make(map[string]*MasterKey, len(masterKeys))}
+	for _, masterKey := range masterKeys {
+		if _, exists := keyRing.keys[masterKey.Id]; exists {
+			return nil, fmt.Errorf("duplicated master key id: %s", masterKey.Id)
+		}
+		keyRing.keys[masterKey.Id] = masterKey
+	}
+	active, ok := keyRing.keys[activeKeyId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMasterKeyNotFound, activeKeyId)
+	}
+	keyRing.active = active
+	return keyRing, nil
+}
@@ -0,0 +1,107 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func newMasterKey(t *testing.T, id string) *security.MasterKey {
+	key, err := security.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	masterKey, err := security.NewMasterKey(id, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return masterKey
+}
+
+func TestSealWithKey(t *testing.T) {
+	key, _ := security.NewDataKey()
+	plaintext := []byte("かえるのうた")
+	sealed, err := security.SealWithKey(key, plaintext, []byte("user:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("同じ鍵と付加データで復号できる", func(t *testing.T) {
+		opened, err := security.OpenWithKey(key, sealed, []byte("user:1"))
+		if err != nil || !bytes.Equal(opened, plaintext) {
+			t.Error(err)
+		}
+	})
+	t.Run("付加データが異なる場合は復号できない", func(t *testing.T) {
+		if _, err := security.OpenWithKey(key, sealed, []byte("user:2")); !errors.Is(err, security.ErrDecryptionFailed) {
+			t.Error(err)
+		}
+	})
+	t.Run("鍵が異なる場合は復号できない", func(t *testing.T) {
+		other, _ := security.NewDataKey()
+		if _, err := security.OpenWithKey(other, sealed, []byte("user:1")); !errors.Is(err, security.ErrDecryptionFailed) {
+			t.Error(err)
+		}
+	})
+	t.Run("改ざんされた場合は復号できない", func(t *testing.T) {
+		tampered := append([]byte{}, sealed...)
+		tampered[len(tampered)-1] ^= 1
+		if _, err := security.OpenWithKey(key, tampered, []byte("user:1")); !errors.Is(err, security.ErrDecryptionFailed) {
+			t.Error(err)
+		}
+		if _, err := security.OpenWithKey(key, sealed[:10], []byte("user:1")); !errors.Is(err, security.ErrDecryptionFailed) {
+			t.Error(err)
+		}
+	})
+	t.Run("同じデータでも暗号文は毎回異なる", func(t *testing.T) {
+		again, _ := security.SealWithKey(key, plaintext, []byte("user:1"))
+		if bytes.Equal(sealed, again) {
+			t.Error()
+		}
+	})
+}
+
+func TestKeyRing(t *testing.T) {
+	oldKey := newMasterKey(t, "2023-04")
+	newKey := newMasterKey(t, "2023-10")
+	dataKey, _ := security.NewDataKey()
+
+	oldRing, _ := security.NewKeyRing("2023-04", []*security.MasterKey{oldKey})
+	wrapped, masterKeyId, err := oldRing.Wrap(dataKey, []byte("user:1"))
+	if err != nil || masterKeyId != "2023-04" {
+		t.Fatal(err)
+	}
+
+	t.Run("鍵をラップし直しても同じ鍵を取り出せる", func(t *testing.T) {
+		ring, _ := security.NewKeyRing("2023-10", []*security.MasterKey{oldKey, newKey})
+		rewrapped, newId, err := ring.Rewrap(wrapped, masterKeyId, []byte("user:1"))
+		if err != nil || newId != "2023-10" {
+			t.Error(err)
+			return
+		}
+		// 古い鍵をキーリングから除いても、ラップし直した鍵は復号できる。
+		newRing, _ := security.NewKeyRing("2023-10", []*security.MasterKey{newKey})
+		unwrapped, err := newRing.Unwrap(rewrapped, newId, []byte("user:1"))
+		if err != nil || !bytes.Equal(unwrapped, dataKey) {
+			t.Error(err)
+		}
+		if _, err := newRing.Unwrap(wrapped, masterKeyId, []byte("user:1")); !errors.Is(err, security.ErrMasterKeyNotFound) {
+			t.Error(err)
+		}
+	})
+	t.Run("有効な鍵が存在しない場合はエラーになる", func(t *testing.T) {
+		if _, err := security.NewKeyRing("2024-01", []*security.MasterKey{oldKey}); !errors.Is(err, security.ErrMasterKeyNotFound) {
+			t.Error(err)
+		}
+	})
+	t.Run("鍵のIDが重複している場合はエラーになる", func(t *testing.T) {
+		if _, err := security.NewKeyRing("2023-04", []*security.MasterKey{oldKey, oldKey}); err == nil {
+			t.Error()
+		}
+	})
+	t.Run("32バイトではない鍵はエラーになる", func(t *testing.T) {
+		if _, err := security.NewMasterKey("short", []byte("0123456789")); err == nil {
+			t.Error()
+		}
+	})
+}
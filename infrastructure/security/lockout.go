@@ -0,0 +1,108 @@
+package security
+
+import (
+	"net"
+	"time"
+)
+
+// Lockout は、サインインIDとリモートアドレスごとに認証の失敗を記録し、失敗が続いた場合に次の試行までの待ち時間を課す構造体です。
+// 待ち時間は、失敗できる回数を超えると、失敗するたびに指数的に長くなります。
+type Lockout struct {
+	store  IAttemptStore
+	config LockoutConfig
+	clock  IClock
+}
+
+// Check は、サインインIDとリモートアドレスのどちらかがロックアウトされている場合、再試行できるまでの時間を返却します。ロックアウトされていない場合は0を返却します。
+func (lockout *Lockout) Check(signInId string, remoteAddr string) (retryAfter time.Duration, err error) {
+	now := lockout.clock.Now()
+	for _, target := range lockout.targets(signInId, remoteAddr) {
+		attempt, err := lockout.store.Find(target.key)
+		if err != nil {
+			return 0, err
+		}
+		if !attempt.LastFailureAt.After(now.Add(-lockout.config.ResetAfter)) {
+			continue
+		}
+		lockedUntil := attempt.LastFailureAt.Add(lockout.delay(attempt.Failures, target.maxFailures))
+		if wait := lockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return retryAfter, nil
+}
+
+// Fail は、サインインIDとリモートアドレスの認証の失敗を記録します。
+func (lockout *Lockout) Fail(signInId string, remoteAddr string) error {
+	now := lockout.clock.Now()
+	for _, target := range lockout.targets(signInId, remoteAddr) {
+		if _, err := lockout.store.AddFailure(target.key, now, now.Add(-lockout.config.ResetAfter)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Succeed は、サインインIDの失敗の記録を削除します。
+// リモートアドレスの記録は削除しません。攻撃者が自身のアカウントでサインインして、記録をリセットできないようにするためです。
+func (lockout *Lockout) Succeed(signInId string) error {
+	return lockout.store.Delete(lockoutKey("sign_in_id", signInId))
+}
+
+// Reap は、リセットされた記録をすべて破棄します。
+func (lockout *Lockout) Reap() error {
+	return lockout.store.DeleteBefore(lockout.clock.Now().Add(-lockout.config.ResetAfter))
+}
+
+// StartReaper は、設定された間隔でリセットされた記録を破棄するゴルーチンを開始します。返却された関数を呼び出すと停止します。
+func (lockout *Lockout) StartReaper() (stop func()) {
+	return StartTicker(lockout.config.ReapInterval, func() { lockout.Reap() })
+}
+
+// lockoutTarget は、失敗を記録する対象を表現する構造体です。
+type lockoutTarget struct {
+	key         string
+	maxFailures int
+}
+
+// targets は、サインインIDとリモートアドレスの、失敗を記録する対象を返却します。
+func (lockout *Lockout) targets(signInId string, remoteAddr string) []lockoutTarget {
+	return []lockoutTarget{
+		{key: lockoutKey("sign_in_id", signInId), maxFailures: lockout.config.MaxFailuresPerSignInId},
+		{key: lockoutKey("remote_addr", RemoteHost(remoteAddr)), maxFailures: lockout.config.MaxFailuresPerAddress},
+	}
+}
+
+// delay は、失敗回数に応じた、最後の失敗からの待ち時間を返却します。
+func (lockout *Lockout) delay(failures int, maxFailures int) time.Duration {
+	if failures < maxFailures {
+		return 0
+	}
+	delay := lockout.config.BaseDelay
+	for i := maxFailures; i < failures; i++ {
+		delay *= 2
+		if delay >= lockout.config.MaxDelay {
+			return lockout.config.MaxDelay
+		}
+	}
+	return delay
+}
+
+// lockoutKey は、ストアのキーとして用いるハッシュ値を返却します。長さを固定し、サインインIDやアドレスをそのまま保存しないためです。
+func lockoutKey(kind string, value string) string {
+	return hashToken(kind + ":" + value)
+}
+
+// RemoteHost は、http.Request.RemoteAddrの形式のアドレスから、ポート番号を除いたホストを返却します。
+func RemoteHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// NewLockout は、Lockout構造体を初期化し、返却します。
+func NewLockout(store IAttemptStore, config LockoutConfig, clock IClock) *Lockout {
+	return &Lockout{store: store, config: config, clock: clock}
+}
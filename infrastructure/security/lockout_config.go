@@ -0,0 +1,31 @@
+package security
+
+import "time"
+
+// LockoutConfig は、認証の失敗によるロックアウトの設定を表現する構造体です。
+type LockoutConfig struct {
+	// MaxFailuresPerSignInId は、サインインIDごとに、待ち時間なしで認証に失敗できる回数です。
+	MaxFailuresPerSignInId int
+	// MaxFailuresPerAddress は、リモートアドレスごとに、待ち時間なしで認証に失敗できる回数です。複数の利用者が同じアドレスを共有する場合を考慮して、サインインIDより多くします。
+	MaxFailuresPerAddress int
+	// BaseDelay は、失敗できる回数を超えた際の最初の待ち時間です。以降は失敗するたびに2倍になります。
+	BaseDelay time.Duration
+	// MaxDelay は、待ち時間の上限です。
+	MaxDelay time.Duration
+	// ResetAfter は、最後の失敗から失敗回数がリセットされるまでの時間です。
+	ResetAfter time.Duration
+	// ReapInterval は、リセットされた記録を破棄する間隔です。
+	ReapInterval time.Duration
+}
+
+// DefaultLockoutConfig は、既定のロックアウトの設定を返却します。
+func DefaultLockoutConfig() LockoutConfig {
+	return LockoutConfig{
+		MaxFailuresPerSignInId: 5,
+		MaxFailuresPerAddress:  20,
+		BaseDelay:              time.Second,
+		MaxDelay:               15 * time.Minute,
+		ResetAfter:             time.Hour,
+		ReapInterval:           time.Minute,
+	}
+}
@@ -0,0 +1,125 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLockout は、テスト用の時計で動作するLockoutを返却します。
+func newTestLockout() (*security.Lockout, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	config := security.LockoutConfig{
+		MaxFailuresPerSignInId: 3,
+		MaxFailuresPerAddress:  10,
+		BaseDelay:              time.Second,
+		MaxDelay:               time.Minute,
+		ResetAfter:             time.Hour,
+		ReapInterval:           time.Minute,
+	}
+	return security.NewLockout(security.NewMemoryAttemptStore(), config, clock), clock
+}
+
+func TestLockout(t *testing.T) {
+	t.Run("失敗できる回数を超えると待ち時間が指数的に長くなる", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second}
+		for i, delay := range expected {
+			lockout.Fail("frog", "192.0.2.1:1234")
+			if retryAfter, _ := lockout.Check("frog", "192.0.2.1:5678"); retryAfter != delay {
+				t.Error(i, retryAfter)
+			}
+		}
+	})
+
+	t.Run("待ち時間には上限がある", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		for i := 0; i < 30; i++ {
+			lockout.Fail("frog", "192.0.2.1:1234")
+		}
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.1:1234"); retryAfter != time.Minute {
+			t.Error(retryAfter)
+		}
+	})
+
+	t.Run("待ち時間が経過すると再試行できる", func(t *testing.T) {
+		lockout, clock := newTestLockout()
+		for i := 0; i < 3; i++ {
+			lockout.Fail("frog", "192.0.2.1:1234")
+		}
+		clock.Advance(time.Second)
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.1:1234"); retryAfter != 0 {
+			t.Error(retryAfter)
+		}
+	})
+
+	t.Run("サインインIDを変えても同じアドレスからの失敗はロックアウトされる", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		for i := 0; i < 10; i++ {
+			lockout.Fail(string(rune('a'+i)), "192.0.2.1:1234")
+		}
+		if retryAfter, _ := lockout.Check("z", "192.0.2.1:1234"); retryAfter == 0 {
+			t.Error()
+		}
+		if retryAfter, _ := lockout.Check("z", "192.0.2.2:1234"); retryAfter != 0 {
+			t.Error(retryAfter)
+		}
+	})
+
+	t.Run("成功するとサインインIDの失敗はリセットされるが、アドレスの失敗はリセットされない", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		for i := 0; i < 10; i++ {
+			lockout.Fail("frog", "192.0.2.1:1234")
+		}
+		lockout.Succeed("frog")
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.2:1234"); retryAfter != 0 {
+			t.Error(retryAfter)
+		}
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.1:1234"); retryAfter == 0 {
+			t.Error()
+		}
+	})
+
+	t.Run("最後の失敗から一定時間が経過すると失敗回数がリセットされる", func(t *testing.T) {
+		lockout, clock := newTestLockout()
+		for i := 0; i < 3; i++ {
+			lockout.Fail("frog", "192.0.2.1:1234")
+		}
+		clock.Advance(time.Hour)
+		lockout.Fail("frog", "192.0.2.1:1234")
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.1:1234"); retryAfter != 0 {
+			t.Error(retryAfter)
+		}
+	})
+
+	t.Run("同時に失敗しても回数が失われない", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		var wg sync.WaitGroup
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				lockout.Fail("frog", "192.0.2.1:1234")
+			}()
+		}
+		wg.Wait()
+		if retryAfter, _ := lockout.Check("frog", "192.0.2.1:1234"); retryAfter != time.Minute {
+			t.Error(retryAfter)
+		}
+	})
+}
+
+func TestMemoryAttemptStoreDeleteBefore(t *testing.T) {
+	store := security.NewMemoryAttemptStore()
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	store.AddFailure("old", now.Add(-2*time.Hour), time.Time{})
+	store.AddFailure("new", now, time.Time{})
+	store.DeleteBefore(now.Add(-time.Hour))
+	if attempt, _ := store.Find("old"); attempt.Failures != 0 {
+		t.Error(attempt)
+	}
+	if attempt, _ := store.Find("new"); attempt.Failures != 1 {
+		t.Error(attempt)
+	}
+}
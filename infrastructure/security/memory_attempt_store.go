@@ -0,0 +1,59 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryAttemptStore は、認証に失敗した記録をメモリ上に保存する構造体です。サーバを再起動すると記録は破棄されます。
+type MemoryAttemptStore struct {
+	mutex    sync.Mutex
+	attempts map[string]Attempt
+}
+
+// Find は、キーの記録のコピーを取得します。存在しない場合は、失敗回数が0の記録を返却します。
+func (store *MemoryAttemptStore) Find(key string) (attempt *Attempt, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	found := store.attempts[key]
+	return &found, nil
+}
+
+// AddFailure は、キーの失敗回数を1増やし、更新後の記録を返却します。最後の失敗がresetBefore以前の場合は、失敗回数を1からやりなおします。
+func (store *MemoryAttemptStore) AddFailure(key string, now time.Time, resetBefore time.Time) (attempt *Attempt, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	found := store.attempts[key]
+	if !found.LastFailureAt.After(resetBefore) {
+		found.Failures = 0
+	}
+	found.Failures++
+	found.LastFailureAt = now
+	store.attempts[key] = found
+	return &found, nil
+}
+
+// Delete は、キーの記録を削除します。
+func (store *MemoryAttemptStore) Delete(key string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.attempts, key)
+	return nil
+}
+
+// DeleteBefore は、最後の失敗がlastFailureBefore以前の記録をすべて削除します。
+func (store *MemoryAttemptStore) DeleteBefore(lastFailureBefore time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for key, attempt := range store.attempts {
+		if !attempt.LastFailureAt.After(lastFailureBefore) {
+			delete(store.attempts, key)
+		}
+	}
+	return nil
+}
+
+// NewMemoryAttemptStore は、MemoryAttemptStore構造体を初期化し、返却します。
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]Attempt)}
+}
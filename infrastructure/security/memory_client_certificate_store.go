@@ -0,0 +1,101 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"sync"
+	"time"
+)
+
+// MemoryClientCertificateStore は、クライアント証明書の登録をメモリ上に保存する構造体です。サーバを再起動すると登録は破棄されます。
+type MemoryClientCertificateStore struct {
+	mutex        sync.Mutex
+	certificates map[string]ClientCertificate
+}
+
+// Create は、クライアント証明書の登録のコピーを新規保存します。同じ方法と値の登録がすでに存在する場合は、ErrClientCertificateExistsを返却します。
+func (store *MemoryClientCertificateStore) Create(certificate *ClientCertificate) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, registered := range store.certificates {
+		if registered.Match == certificate.Match && registered.Value == certificate.Value {
+			return ErrClientCertificateExists
+		}
+	}
+	store.certificates[certificate.Id] = copyClientCertificate(certificate)
+	return nil
+}
+
+// Find は、対応付ける方法と値をもとにクライアント証明書の登録のコピーを取得します。存在しない場合は、ErrClientCertificateNotFoundを返却します。
+func (store *MemoryClientCertificateStore) Find(match CertificateMatch, value string) (certificate *ClientCertificate, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, registered := range store.certificates {
+		if registered.Match == match && registered.Value == value {
+			found := copyClientCertificate(&registered)
+			return &found, nil
+		}
+	}
+	return nil, ErrClientCertificateNotFound
+}
+
+// FindByUserId は、ユーザのクライアント証明書の登録のコピーをすべて取得します。
+func (store *MemoryClientCertificateStore) FindByUserId(userId *users.UserId) (certificates []*ClientCertificate, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, registered := range store.certificates {
+		if registered.UserId.Equals(userId) {
+			found := copyClientCertificate(&registered)
+			certificates = append(certificates, &found)
+		}
+	}
+	return certificates, nil
+}
+
+// Touch は、クライアント証明書の登録の最終利用時刻を更新します。
+func (store *MemoryClientCertificateStore) Touch(id string, lastUsedAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	certificate, exists := store.certificates[id]
+	if !exists {
+		return ErrClientCertificateNotFound
+	}
+	certificate.LastUsedAt = lastUsedAt
+	store.certificates[id] = certificate
+	return nil
+}
+
+// Delete は、IDを指定してユーザのクライアント証明書の登録を削除します。存在しない場合は、ErrClientCertificateNotFoundを返却します。
+func (store *MemoryClientCertificateStore) Delete(userId *users.UserId, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	certificate, exists := store.certificates[id]
+	if !exists || !certificate.UserId.Equals(userId) {
+		return ErrClientCertificateNotFound
+	}
+	delete(store.certificates, id)
+	return nil
+}
+
+// DeleteByUserId は、ユーザのクライアント証明書の登録をすべて削除します。
+func (store *MemoryClientCertificateStore) DeleteByUserId(userId *users.UserId) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for id, certificate := range store.certificates {
+		if certificate.UserId.Equals(userId) {
+			delete(store.certificates, id)
+		}
+	}
+	return nil
+}
+
+// copyClientCertificate は、スコープのスライスを共有しないように、クライアント証明書の登録をコピーします。
+func copyClientCertificate(certificate *ClientCertificate) ClientCertificate {
+	copied := *certificate
+	copied.Scopes = append([]Scope(nil), certificate.Scopes...)
+	return copied
+}
+
+// NewMemoryClientCertificateStore は、MemoryClientCertificateStore構造体を初期化し、返却します。
+func NewMemoryClientCertificateStore() *MemoryClientCertificateStore {
+	return &MemoryClientCertificateStore{certificates: make(map[string]ClientCertificate)}
+}
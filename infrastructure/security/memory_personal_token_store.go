@@ -0,0 +1,111 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"sync"
+	"time"
+)
+
+// MemoryPersonalTokenStore は、パーソナルアクセストークンをメモリ上に保存する構造体です。サーバを再起動するとトークンは破棄されます。
+type MemoryPersonalTokenStore struct {
+	mutex  sync.Mutex
+	tokens map[string]PersonalToken
+}
+
+// Create は、パーソナルアクセストークンのコピーを新規保存します。同じキーのトークンがすでに存在する場合は、ErrPersonalTokenExistsを返却します。
+func (store *MemoryPersonalTokenStore) Create(key string, token *PersonalToken) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, exists := store.tokens[key]; exists {
+		return ErrPersonalTokenExists
+	}
+	store.tokens[key] = copyPersonalToken(token)
+	return nil
+}
+
+// Find は、キーに紐づけられたパーソナルアクセストークンのコピーを取得します。存在しない場合は、ErrPersonalTokenNotFoundを返却します。
+func (store *MemoryPersonalTokenStore) Find(key string) (token *PersonalToken, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	found, exists := store.tokens[key]
+	if !exists {
+		return nil, ErrPersonalTokenNotFound
+	}
+	found = copyPersonalToken(&found)
+	return &found, nil
+}
+
+// FindByUserId は、ユーザのパーソナルアクセストークンのコピーをすべて取得します。
+func (store *MemoryPersonalTokenStore) FindByUserId(userId *users.UserId) (tokens []*PersonalToken, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for _, token := range store.tokens {
+		if token.UserId.Equals(userId) {
+			found := copyPersonalToken(&token)
+			tokens = append(tokens, &found)
+		}
+	}
+	return tokens, nil
+}
+
+// Touch は、キーに紐づけられたパーソナルアクセストークンの最終利用時刻を更新します。
+func (store *MemoryPersonalTokenStore) Touch(key string, lastUsedAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	token, exists := store.tokens[key]
+	if !exists {
+		return ErrPersonalTokenNotFound
+	}
+	token.LastUsedAt = lastUsedAt
+	store.tokens[key] = token
+	return nil
+}
+
+// Delete は、IDを指定してユーザのパーソナルアクセストークンを削除します。存在しない場合は、ErrPersonalTokenNotFoundを返却します。
+func (store *MemoryPersonalTokenStore) Delete(userId *users.UserId, id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for key, token := range store.tokens {
+		if token.Id == id && token.UserId.Equals(userId) {
+			delete(store.tokens, key)
+			return nil
+		}
+	}
+	return ErrPersonalTokenNotFound
+}
+
+// DeleteByUserId は、ユーザのパーソナルアクセストークンをすべて削除します。
+func (store *MemoryPersonalTokenStore) DeleteByUserId(userId *users.UserId) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for key, token := range store.tokens {
+		if token.UserId.Equals(userId) {
+			delete(store.tokens, key)
+		}
+	}
+	return nil
+}
+
+// DeleteExpired は、有効期限がnow以前のパーソナルアクセストークンをすべて削除します。
+func (store *MemoryPersonalTokenStore) DeleteExpired(now time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	for key, token := range store.tokens {
+		if token.IsExpired(now) {
+			delete(store.tokens, key)
+		}
+	}
+	return nil
+}
+
+// copyPersonalToken は、スコープのスライスを共有しないように、パーソナルアクセストークンをコピーします。
+func copyPersonalToken(token *PersonalToken) PersonalToken {
+	copied := *token
+	copied.Scopes = append([]Scope(nil), token.Scopes...)
+	return copied
+}
+
+// NewMemoryPersonalTokenStore は、MemoryPersonalTokenStore構造体を初期化し、返却します。
+func NewMemoryPersonalTokenStore() *MemoryPersonalTokenStore {
+	return &MemoryPersonalTokenStore{tokens: make(map[string]PersonalToken)}
+}
@@ -0,0 +1,221 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// memorySessionStoreShardCount は、MemorySessionStoreのシャード数です。
+const memorySessionStoreShardCount = 32
+
+// memorySessionShard は、MemorySessionStoreのシャードを表現する構造体です。
+type memorySessionShard struct {
+	mutex    sync.RWMutex
+	sessions map[string]Session
+}
+
+// memoryUserIndexShard は、ユーザごとのセッションのキーの索引のシャードを表現する構造体です。
+type memoryUserIndexShard struct {
+	mutex sync.Mutex
+	keys  map[int]map[string]struct{}
+}
+
+// MemorySessionStore は、セッションをメモリ上に保存する構造体です。
+// ロックの競合を減らすため、キーごとにシャードに分割し、シャード単位で排他制御しています。
+// ユーザのセッションをまとめて取得・削除できるよう、ユーザごとにシャードに分割した索引ももちます。
+// 索引とセッションの両方をロックする場合は、デッドロックを防ぐため、必ず索引のシャードを先にロックします。
+type MemorySessionStore struct {
+	shards     [memorySessionStoreShardCount]*memorySessionShard
+	userShards [memorySessionStoreShardCount]*memoryUserIndexShard
+}
+
+// Create は、セッションを新規保存します。同じキーのセッションがすでに存在する場合は、ErrSessionExistsを返却します。
+func (store *MemorySessionStore) Create(key string, session *Session) error {
+	index := store.getUserShard(&session.UserId)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	shard := store.getShard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if _, exists := shard.sessions[key]; exists {
+		return ErrSessionExists
+	}
+	shard.sessions[key] = *session
+
+	userKeys, exists := index.keys[session.UserId.GetValue()]
+	if !exists {
+		userKeys = make(map[string]struct{})
+		index.keys[session.UserId.GetValue()] = userKeys
+	}
+	userKeys[key] = struct{}{}
+	return nil
+}
+
+// Find は、キーに紐づけられたセッションのコピーを取得します。存在しない場合は、ErrSessionNotFoundを返却します。
+func (store *MemorySessionStore) Find(key string) (session *Session, err error) {
+	shard := store.getShard(key)
+	shard.mutex.RLock()
+	defer shard.mutex.RUnlock()
+	found, exists := shard.sessions[key]
+	if !exists {
+		return nil, ErrSessionNotFound
+	}
+	return &found, nil
+}
+
+// FindByUserId は、ユーザのセッションのコピーをすべて取得します。
+func (store *MemorySessionStore) FindByUserId(userId *users.UserId) (sessions []*Session, err error) {
+	index := store.getUserShard(userId)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	for key := range index.keys[userId.GetValue()] {
+		shard := store.getShard(key)
+		shard.mutex.RLock()
+		found, exists := shard.sessions[key]
+		shard.mutex.RUnlock()
+		if exists {
+			sessions = append(sessions, &found)
+		}
+	}
+	return sessions, nil
+}
+
+// Touch は、キーに紐づけられたセッションの最終利用時刻を更新します。
+func (store *MemorySessionStore) Touch(key string, lastUsedAt time.Time) error {
+	shard := store.getShard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	session, exists := shard.sessions[key]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	session.LastUsedAt = lastUsedAt
+	shard.sessions[key] = session
+	return nil
+}
+
+// MarkUsed は、キーに紐づけられたセッションを使用済みにします。すでに使用済みの場合は、ErrSessionAlreadyUsedを返却します。
+func (store *MemorySessionStore) MarkUsed(key string) error {
+	shard := store.getShard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	session, exists := shard.sessions[key]
+	if !exists {
+		return ErrSessionNotFound
+	}
+	if session.Used {
+		return ErrSessionAlreadyUsed
+	}
+	session.Used = true
+	shard.sessions[key] = session
+	return nil
+}
+
+// Delete は、キーに紐づけられたセッションを削除します。
+func (store *MemorySessionStore) Delete(key string) error {
+	shard := store.getShard(key)
+	shard.mutex.Lock()
+	session, exists := shard.sessions[key]
+	delete(shard.sessions, key)
+	shard.mutex.Unlock()
+	if exists {
+		store.unindex(&session.UserId, key)
+	}
+	return nil
+}
+
+// DeleteFamily は、familyIdが同じセッションをすべて削除します。
+func (store *MemorySessionStore) DeleteFamily(familyId string) error {
+	store.deleteIf(func(session *Session) bool {
+		return session.FamilyId == familyId
+	})
+	return nil
+}
+
+// DeleteByUserId は、familyIdがexceptFamilyId以外のユーザのセッションをすべて削除します。
+// 削除している間はユーザのセッションを新規保存できないため、呼び出した時点で保存されているセッションはすべて削除されます。
+func (store *MemorySessionStore) DeleteByUserId(userId *users.UserId, exceptFamilyId string) error {
+	index := store.getUserShard(userId)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	userKeys := index.keys[userId.GetValue()]
+	for key := range userKeys {
+		shard := store.getShard(key)
+		shard.mutex.Lock()
+		session, exists := shard.sessions[key]
+		if exists && session.FamilyId == exceptFamilyId {
+			shard.mutex.Unlock()
+			continue
+		}
+		delete(shard.sessions, key)
+		shard.mutex.Unlock()
+		delete(userKeys, key)
+	}
+	if len(userKeys) == 0 {
+		delete(index.keys, userId.GetValue())
+	}
+	return nil
+}
+
+// DeleteExpired は、有効期限がnow以前のセッション、createdBefore以前に作成されたセッション、lastUsedBefore以前に最後に利用されたセッションをすべて削除します。
+func (store *MemorySessionStore) DeleteExpired(now time.Time, createdBefore time.Time, lastUsedBefore time.Time) error {
+	store.deleteIf(func(session *Session) bool {
+		return !session.ExpiresAt.After(now) || !session.CreatedAt.After(createdBefore) || !session.LastUsedAt.After(lastUsedBefore)
+	})
+	return nil
+}
+
+// deleteIf は、条件を満たすセッションをすべてのシャードから削除します。
+func (store *MemorySessionStore) deleteIf(predicate func(session *Session) bool) {
+	for _, shard := range store.shards {
+		deleted := make(map[string]users.UserId)
+		shard.mutex.Lock()
+		for key, session := range shard.sessions {
+			if predicate(&session) {
+				delete(shard.sessions, key)
+				deleted[key] = session.UserId
+			}
+		}
+		shard.mutex.Unlock()
+		// 索引のシャードを先にロックする順序を守るため、セッションのシャードのロックを解放してから索引から削除する。
+		for key, userId := range deleted {
+			store.unindex(&userId, key)
+		}
+	}
+}
+
+// unindex は、ユーザの索引からキーを削除します。
+func (store *MemorySessionStore) unindex(userId *users.UserId, key string) {
+	index := store.getUserShard(userId)
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+	userKeys := index.keys[userId.GetValue()]
+	delete(userKeys, key)
+	if len(userKeys) == 0 {
+		delete(index.keys, userId.GetValue())
+	}
+}
+
+// getShard は、キーが属するシャードを返却します。
+func (store *MemorySessionStore) getShard(key string) *memorySessionShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return store.shards[hash.Sum32()%memorySessionStoreShardCount]
+}
+
+// getUserShard は、ユーザの索引が属するシャードを返却します。
+func (store *MemorySessionStore) getUserShard(userId *users.UserId) *memoryUserIndexShard {
+	return store.userShards[uint(userId.GetValue())%memorySessionStoreShardCount]
+}
+
+// NewMemorySessionStore は、MemorySessionStore構造体を初期化し、返却します。
+func NewMemorySessionStore() *MemorySessionStore {
+	store := &MemorySessionStore{}
+	for i := range store.shards {
+		store.shards[i] = &memorySessionShard{sessions: make(map[string]Session)}
+		store.userShards[i] = &memoryUserIndexShard{keys: make(map[int]map[string]struct{})}
+	}
+	return store
+}
@@ -0,0 +1,189 @@
+package security_test
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemorySessionStore(t *testing.T) {
+	store := security.NewMemorySessionStore()
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	session := &security.Session{UserId: *users.NewUserId(1), Kind: security.TokenKindRefresh, FamilyId: "family", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	if err := store.Create("key", session); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("同じキーは保存できない", func(t *testing.T) {
+		if err := store.Create("key", session); !errors.Is(err, security.ErrSessionExists) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("最終利用時刻を更新できる", func(t *testing.T) {
+		later := now.Add(time.Minute)
+		if err := store.Touch("key", later); err != nil {
+			t.Fatal(err)
+		}
+		found, err := store.Find("key")
+		if err != nil || !found.LastUsedAt.Equal(later) || !found.CreatedAt.Equal(now) {
+			t.Error(found, err)
+		}
+	})
+
+	t.Run("ユーザのセッションをすべて取得できる", func(t *testing.T) {
+		store.Create("other", &security.Session{UserId: *users.NewUserId(2), FamilyId: "other", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		defer store.Delete("other")
+		found, err := store.FindByUserId(users.NewUserId(1))
+		if err != nil || len(found) != 1 || found[0].FamilyId != "family" {
+			t.Error(found, err)
+		}
+	})
+
+	t.Run("期限切れのセッションを削除できる", func(t *testing.T) {
+		store.Create("old", &security.Session{UserId: *users.NewUserId(2), CreatedAt: now.Add(-time.Hour), LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		store.Create("expired", &security.Session{UserId: *users.NewUserId(2), CreatedAt: now, LastUsedAt: now, ExpiresAt: now})
+		if err := store.DeleteExpired(now, now.Add(-30*time.Minute), now.Add(-30*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Find("old"); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+		if _, err := store.Find("expired"); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+		if _, err := store.Find("key"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("使用済みにできるのは一度だけ", func(t *testing.T) {
+		if err := store.MarkUsed("key"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.MarkUsed("key"); !errors.Is(err, security.ErrSessionAlreadyUsed) {
+			t.Error(err)
+		}
+		if found, _ := store.Find("key"); !found.Used {
+			t.Error()
+		}
+	})
+
+	t.Run("同じファミリーのセッションを削除できる", func(t *testing.T) {
+		store.Create("sibling", &security.Session{UserId: *users.NewUserId(1), FamilyId: "family", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		store.Create("other", &security.Session{UserId: *users.NewUserId(1), FamilyId: "other", CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+		if err := store.DeleteFamily("family"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Find("sibling"); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+		if _, err := store.Find("other"); err != nil {
+			t.Error(err)
+		}
+		store.Create("key", session)
+	})
+
+	t.Run("削除したセッションは取得できない", func(t *testing.T) {
+		store.Delete("key")
+		if _, err := store.Find("key"); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+		if err := store.Touch("key", now); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+	})
+}
+
+func TestMemorySessionStoreDeleteByUserId(t *testing.T) {
+	store := security.NewMemorySessionStore()
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	create := func(key string, userId int, familyId string) {
+		store.Create(key, &security.Session{UserId: *users.NewUserId(userId), FamilyId: familyId, CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+	}
+	create("a1", 1, "a")
+	create("a2", 1, "a")
+	create("b1", 1, "b")
+	create("c1", 2, "c")
+
+	t.Run("指定したファミリー以外のユーザのセッションを削除できる", func(t *testing.T) {
+		if err := store.DeleteByUserId(users.NewUserId(1), "a"); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := store.FindByUserId(users.NewUserId(1)); len(found) != 2 {
+			t.Error(found)
+		}
+		if _, err := store.Find("b1"); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+	})
+
+	t.Run("ユーザのセッションをすべて削除できる", func(t *testing.T) {
+		if err := store.DeleteByUserId(users.NewUserId(1), ""); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := store.FindByUserId(users.NewUserId(1)); len(found) != 0 {
+			t.Error(found)
+		}
+		if _, err := store.Find("c1"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("保存と削除が同時に行われても索引が壊れない", func(t *testing.T) {
+		userId := users.NewUserId(3)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					create(fmt.Sprintf("%d-%d", i, j), 3, "family")
+				}
+			}(i)
+		}
+		for i := 0; i < 10; i++ {
+			store.DeleteByUserId(userId, "")
+		}
+		wg.Wait()
+		store.DeleteByUserId(userId, "")
+		if found, _ := store.FindByUserId(userId); len(found) != 0 {
+			t.Error(len(found))
+		}
+	})
+}
+
+func TestMemorySessionStoreParallel(t *testing.T) {
+	store := security.NewMemorySessionStore()
+	now := time.Now()
+	for i := 0; i < 8; i++ {
+		i := i
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			var wg sync.WaitGroup
+			for j := 0; j < 8; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					for k := 0; k < 200; k++ {
+						key := fmt.Sprintf("%d-%d-%d", i, j, k)
+						store.Create(key, &security.Session{UserId: *users.NewUserId(i), CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(time.Hour)})
+						store.Touch(key, now.Add(time.Second))
+						if _, err := store.Find(key); err != nil {
+							t.Error(err)
+							return
+						}
+						store.Delete(key)
+					}
+				}(j)
+			}
+			store.DeleteExpired(now, now.Add(-time.Hour), now.Add(-time.Hour))
+			wg.Wait()
+		})
+	}
+}
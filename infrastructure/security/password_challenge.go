@@ -0,0 +1,94 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+)
+
+// ErrChallengeNotSupported は、保存済みのハッシュ値の形式がチャレンジ&レスポンス認証に対応していないことを表すエラーです。
+var ErrChallengeNotSupported = errors.New("challenge-response is not supported for this password hash")
+
+// チャレンジ&レスポンス認証で、クライアントが鍵を導出するアルゴリズムです。
+const (
+	// ChallengeAlgorithmScramArgon2id は、パラメータとソルトを用いてArgon2idで鍵を導出し、SCRAM(RFC 5802)と同様に証明を計算します。
+	ChallengeAlgorithmScramArgon2id = "scram-argon2id"
+)
+
+var (
+	// dummyChallengeSecret は、存在しないサインインIDに対するダミーのソルトを導出するための秘密値です。
+	dummyChallengeSecret = newDummyChallengeSecret()
+)
+
+// ChallengeParams は、クライアントがパスワードから鍵を導出するためのパラメータを表現する構造体です。
+type ChallengeParams struct {
+	Algorithm string
+	Salt      []byte
+	Argon2id  Argon2idParams
+}
+
+// GetChallengeParams は、保存済みのハッシュ値から、クライアントが同じ鍵を導出するためのパラメータを返却します。
+// SCRAM形式以外のハッシュ値はサーバ側の値だけで証明を作れてしまうため、対応しません。
+func (p *Passwords) GetChallengeParams(stored string) (params *ChallengeParams, err error) {
+	argon2idParams, salt, _, _, err := decodeScramArgon2id(stored)
+	if err != nil {
+		return nil, ErrChallengeNotSupported
+	}
+	return &ChallengeParams{Algorithm: ChallengeAlgorithmScramArgon2id, Salt: salt, Argon2id: argon2idParams}, nil
+}
+
+// GetDummyChallengeParams は、存在しないサインインIDに対するダミーのパラメータを返却します。
+// ユーザが存在するかを推測されないよう、同じサインインIDには同じソルトを返却します。
+func (p *Passwords) GetDummyChallengeParams(signInId string) *ChallengeParams {
+	params := DefaultArgon2idParams()
+	mac := hmac.New(sha256.New, dummyChallengeSecret)
+	mac.Write([]byte(signInId))
+	return &ChallengeParams{Algorithm: ChallengeAlgorithmScramArgon2id, Salt: mac.Sum(nil)[:params.SaltLength], Argon2id: params}
+}
+
+// VerifyProof は、クライアントが送信した証明(ClientProof)を、保存済みのStoredKeyで照合します。
+// 証明とHMAC(StoredKey, ナンス)の排他的論理和からClientKeyを復元し、そのSHA-256がStoredKeyと一致するかを確認します。
+func (p *Passwords) VerifyProof(stored string, nonce string, proof []byte) (ok bool, err error) {
+	_, _, storedKey, _, err := decodeScramArgon2id(stored)
+	if err != nil {
+		return false, ErrChallengeNotSupported
+	}
+	if len(proof) != sha256.Size {
+		return false, nil
+	}
+	clientKey := xorBytes(proof, computeClientSignature(storedKey, nonce))
+	otherStoredKey := sha256.Sum256(clientKey)
+	return hmac.Equal(otherStoredKey[:], storedKey), nil
+}
+
+// ComputeProof は、パスワードから導出した鍵(SaltedPassword)から、ナンスに対する証明(ClientProof)を計算します。
+// クライアントと同じ計算で、ClientKeyとHMAC(SHA-256(ClientKey), ナンス)の排他的論理和です。
+func ComputeProof(saltedPassword []byte, nonce string) []byte {
+	clientKey := ComputeClientKey(saltedPassword)
+	storedKey := sha256.Sum256(clientKey)
+	return xorBytes(clientKey, computeClientSignature(storedKey[:], nonce))
+}
+
+// computeClientSignature は、StoredKeyで、ナンスに対するHMAC-SHA256(ClientSignature)を計算します。
+func computeClientSignature(storedKey []byte, nonce string) []byte {
+	mac := hmac.New(sha256.New, storedKey)
+	mac.Write([]byte(nonce))
+	return mac.Sum(nil)
+}
+
+// xorBytes は、同じ長さのバイト列の排他的論理和を返却します。
+func xorBytes(x []byte, y []byte) []byte {
+	result := make([]byte, len(x))
+	for i := range x {
+		result[i] = x[i] ^ y[i]
+	}
+	return result
+}
+
+// newDummyChallengeSecret は、ランダムな秘密値を生成します。
+func newDummyChallengeSecret() []byte {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
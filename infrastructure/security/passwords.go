@@ -0,0 +1,87 @@
+package security
+
+import (
+	"crypto/subtle"
+	"regexp"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// passwordHasher は、新たにパスワードをハッシュ化する際に使用するハッシャです。
+	passwordHasher IPasswordHasher = NewScramArgon2idHasher(DefaultArgon2idParams())
+	// legacyHashPattern は、旧形式(Hash.GetHash)のハッシュ値にマッチする正規表現です。
+	legacyHashPattern = regexp.MustCompile("^[0-9a-f]{64}$")
+	// dummyHashMutex は、dummyHashの生成を排他制御します。
+	dummyHashMutex sync.Mutex
+	// dummyHash は、存在しないユーザの照合に使用する、dummyHashHasherでハッシュ化したダミーのハッシュ値です。
+	dummyHash       string
+	dummyHashHasher IPasswordHasher
+)
+
+// UsePasswordHasher は、新たにパスワードをハッシュ化する際に使用するハッシャを差し替えます。
+func UsePasswordHasher(hasher IPasswordHasher) {
+	passwordHasher = hasher
+}
+
+// Passwords は、パスワードのハッシュ化と照合を行う構造体です。旧形式(Hash.GetHash)のハッシュ値も照合できます。
+type Passwords struct{}
+
+// Hash は、現在のハッシャでパスワードをハッシュ化し、エンコード済みの文字列を返却します。
+func (p *Passwords) Hash(password string) (encoded string, err error) {
+	return passwordHasher.Hash(password)
+}
+
+// Verify は、パスワードと保存済みのハッシュ値を照合します。
+// 一致した場合、保存済みのハッシュ値が旧形式であったり現在のハッシャの設定と異なっていれば、needsRehashにtrueを返却します。
+func (p *Passwords) Verify(password string, stored string) (ok bool, needsRehash bool, err error) {
+	if IsLegacyHash(stored) {
+		hash := Hash{}
+		ok = subtle.ConstantTimeCompare([]byte(hash.GetHash(password)), []byte(stored)) == 1
+		return ok, ok, nil
+	}
+
+	for _, hasher := range verifiers() {
+		if !hasher.IsSupported(stored) {
+			continue
+		}
+		ok, err = hasher.Verify(password, stored)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, passwordHasher.NeedsRehash(stored), nil
+	}
+	return false, false, nil
+}
+
+// DummyHash は、存在しないユーザの照合に使用する、現在のハッシャでハッシュ化した固定のダミーのハッシュ値を返却します。
+// 存在するユーザと同じ時間をかけて照合し、応答時間からサインインIDが存在するかを推測されないようにするためです。
+func (p *Passwords) DummyHash() (encoded string, err error) {
+	dummyHashMutex.Lock()
+	defer dummyHashMutex.Unlock()
+	if dummyHash == "" || dummyHashHasher != passwordHasher {
+		encoded, err = passwordHasher.Hash("frognote-dummy-password")
+		if err != nil {
+			return "", err
+		}
+		dummyHash, dummyHashHasher = encoded, passwordHasher
+	}
+	return dummyHash, nil
+}
+
+// IsLegacyHash は、保存済みのハッシュ値が旧形式(SHA-256を64桁の16進数で表現したもの)であればtrueを返却します。
+func IsLegacyHash(stored string) bool {
+	return legacyHashPattern.MatchString(stored)
+}
+
+// verifiers は、照合に使用するハッシャのスライスを返却します。照合時のパラメータはハッシュ値から読み取るため、既定の設定で十分です。
+func verifiers() []IPasswordHasher {
+	bcryptHasher, _ := NewBcryptHasher(bcrypt.DefaultCost)
+	return []IPasswordHasher{
+		passwordHasher,
+		NewScramArgon2idHasher(DefaultArgon2idParams()),
+		NewArgon2idHasher(DefaultArgon2idParams()),
+		bcryptHasher,
+	}
+}
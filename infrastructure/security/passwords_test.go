@@ -0,0 +1,177 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"strings"
+	"testing"
+)
+
+// testArgon2idParams は、テストを高速に行うための軽量なパラメータです。
+var testArgon2idParams = security.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestArgon2idHasher(t *testing.T) {
+	hasher := security.NewArgon2idHasher(testArgon2idParams)
+	encoded, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("アルゴリズムとパラメータがエンコードされているかのテスト", func(t *testing.T) {
+		if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+			t.Error(encoded)
+		}
+	})
+
+	t.Run("同じパスワードでもソルトにより異なる結果になるかのテスト", func(t *testing.T) {
+		encoded2, _ := hasher.Hash("password")
+		if encoded == encoded2 {
+			t.Error()
+		}
+	})
+
+	t.Run("照合できるかのテスト", func(t *testing.T) {
+		if ok, err := hasher.Verify("password", encoded); !ok || err != nil {
+			t.Error(err)
+		}
+		if ok, _ := hasher.Verify("passwork", encoded); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("パラメータが異なる場合に再ハッシュ化が必要と判定されるかのテスト", func(t *testing.T) {
+		if hasher.NeedsRehash(encoded) {
+			t.Error()
+		}
+		other := security.NewArgon2idHasher(security.Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+		if !other.NeedsRehash(encoded) {
+			t.Error()
+		}
+	})
+}
+
+func TestScramArgon2idHasher(t *testing.T) {
+	hasher := security.NewScramArgon2idHasher(testArgon2idParams)
+	encoded, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("アルゴリズムとパラメータがエンコードされているかのテスト", func(t *testing.T) {
+		if !strings.HasPrefix(encoded, "$scram-argon2id$v=19$m=1024,t=1,p=1,l=32$") || !hasher.IsSupported(encoded) {
+			t.Error(encoded)
+		}
+	})
+
+	t.Run("照合できるかのテスト", func(t *testing.T) {
+		if ok, err := hasher.Verify("password", encoded); !ok || err != nil {
+			t.Error(err)
+		}
+		if ok, _ := hasher.Verify("passwork", encoded); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("Argon2idのハッシュ値は照合でき、再ハッシュ化が必要", func(t *testing.T) {
+		security.UsePasswordHasher(hasher)
+		defer security.UsePasswordHasher(security.NewArgon2idHasher(testArgon2idParams))
+		passwords := security.Passwords{}
+		argon2idHash, _ := security.NewArgon2idHasher(testArgon2idParams).Hash("password")
+		ok, needsRehash, err := passwords.Verify("password", argon2idHash)
+		if !ok || !needsRehash || err != nil {
+			t.Error(ok, needsRehash, err)
+		}
+		ok, needsRehash, err = passwords.Verify("password", encoded)
+		if !ok || needsRehash || err != nil {
+			t.Error(ok, needsRehash, err)
+		}
+	})
+}
+
+func TestBcryptHasher(t *testing.T) {
+	hasher, err := security.NewBcryptHasher(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasher.IsSupported(encoded) {
+		t.Error(encoded)
+	}
+	if ok, err := hasher.Verify("password", encoded); !ok || err != nil {
+		t.Error(err)
+	}
+	if ok, _ := hasher.Verify("passwork", encoded); ok {
+		t.Error()
+	}
+
+	t.Run("コストが範囲外の場合はエラーになるかのテスト", func(t *testing.T) {
+		if _, err := security.NewBcryptHasher(3); err == nil {
+			t.Error()
+		}
+	})
+}
+
+func TestPasswordsVerify(t *testing.T) {
+	security.UsePasswordHasher(security.NewArgon2idHasher(testArgon2idParams))
+	passwords := security.Passwords{}
+
+	t.Run("現在の形式のハッシュ値は再ハッシュ化が不要", func(t *testing.T) {
+		encoded, _ := passwords.Hash("password")
+		ok, needsRehash, err := passwords.Verify("password", encoded)
+		if !ok || needsRehash || err != nil {
+			t.Error(ok, needsRehash, err)
+		}
+	})
+
+	t.Run("旧形式のハッシュ値は照合でき、再ハッシュ化が必要", func(t *testing.T) {
+		hash := security.Hash{}
+		legacy := hash.GetHash("password")
+		if !security.IsLegacyHash(legacy) {
+			t.Error()
+		}
+		ok, needsRehash, err := passwords.Verify("password", legacy)
+		if !ok || !needsRehash || err != nil {
+			t.Error(ok, needsRehash, err)
+		}
+		ok, needsRehash, _ = passwords.Verify("passwork", legacy)
+		if ok || needsRehash {
+			t.Error()
+		}
+	})
+
+	t.Run("別のアルゴリズムのハッシュ値は照合でき、再ハッシュ化が必要", func(t *testing.T) {
+		bcryptHasher, _ := security.NewBcryptHasher(4)
+		encoded, _ := bcryptHasher.Hash("password")
+		ok, needsRehash, err := passwords.Verify("password", encoded)
+		if !ok || !needsRehash || err != nil {
+			t.Error(ok, needsRehash, err)
+		}
+	})
+
+	t.Run("未知の形式のハッシュ値は照合に失敗する", func(t *testing.T) {
+		ok, _, _ := passwords.Verify("password", "password")
+		if ok {
+			t.Error()
+		}
+	})
+
+	t.Run("ダミーのハッシュ値は現在の形式で、ハッシャを差し替えると生成し直す", func(t *testing.T) {
+		dummy, err := passwords.DummyHash()
+		if err != nil || !strings.HasPrefix(dummy, "$argon2id$v=19$m=1024,t=1,p=1$") {
+			t.Fatal(dummy, err)
+		}
+		if again, _ := passwords.DummyHash(); again != dummy {
+			t.Error("ダミーのハッシュ値が固定されていません。")
+		}
+		security.UsePasswordHasher(security.NewScramArgon2idHasher(testArgon2idParams))
+		defer security.UsePasswordHasher(security.NewArgon2idHasher(testArgon2idParams))
+		if dummy, _ = passwords.DummyHash(); !strings.HasPrefix(dummy, "$scram-argon2id$") {
+			t.Error(dummy)
+		}
+		if ok, _, _ := passwords.Verify("password", dummy); ok {
+			t.Error()
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPersonalTokenNotFound は、パーソナルアクセストークンが見つからなかったことを表すエラーです。
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	// ErrPersonalTokenExists は、同じキーのパーソナルアクセストークンがすでに存在することを表すエラーです。
+	ErrPersonalTokenExists = errors.New("personal access token already exists")
+	// ErrPersonalTokenLifetimeExceeded は、パーソナルアクセストークンの有効期限が上限を超えていることを表すエラーです。
+	ErrPersonalTokenLifetimeExceeded = errors.New("personal access token lifetime exceeded")
+)
+
+// PersonalTokenPrefix は、パーソナルアクセストークンの接頭辞です。セッションのアクセストークンと区別するために使用します。
+const PersonalTokenPrefix = "fnp_"
+
+// Scope は、パーソナルアクセストークンで許可する操作の範囲を表現する型です。
+type Scope string
+
+const (
+	// ScopeBackupRead は、バックアップデータのメタデータの取得とダウンロードを許可します。
+	ScopeBackupRead Scope = "backup:read"
+	// ScopeBackupWrite は、バックアップデータの保存と削除を許可します。
+	ScopeBackupWrite Scope = "backup:write"
+	// ScopeUserRead は、ユーザ情報の取得を許可します。
+	ScopeUserRead Scope = "user:read"
+)
+
+// NewScope は、文字列からScopeを初期化し、返却します。未知のスコープの場合は、エラーを返却します。
+func NewScope(value string) (scope Scope, err error) {
+	switch Scope(value) {
+	case ScopeBackupRead, ScopeBackupWrite, ScopeUserRead:
+		return Scope(value), nil
+	}
+	return "", fmt.Errorf("unknown scope: %s", value)
+}
+
+// PersonalToken は、スクリプトなどから使用するために、ユーザが発行したパーソナルアクセストークンを表現する構造体です。
+type PersonalToken struct {
+	Id     string
+	UserId users.UserId
+	// Name は、用途を見分けるためにユーザがつけた名前です。
+	Name   string
+	Scopes []Scope
+	// CreatedAt は、発行した時刻です。
+	CreatedAt time.Time
+	// ExpiresAt は、有効期限です。ゼロ値の場合は、無期限です。
+	ExpiresAt time.Time
+	// LastUsedAt は、最後に利用された時刻です。ゼロ値の場合は、まだ利用されていません。
+	LastUsedAt time.Time
+}
+
+// HasScopes は、scopesをすべてもつ場合にtrueを返却します。
+func (token *PersonalToken) HasScopes(scopes ...Scope) bool {
+	return hasScopes(token.Scopes, scopes)
+}
+
+// hasScopes は、grantedがwantedをすべて含む場合にtrueを返却します。
+func hasScopes(granted []Scope, wanted []Scope) bool {
+	for _, scope := range wanted {
+		found := false
+		for _, grantedScope := range granted {
+			if grantedScope == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// IsExpired は、有効期限を過ぎている場合にtrueを返却します。
+func (token *PersonalToken) IsExpired(now time.Time) bool {
+	return !token.ExpiresAt.IsZero() && !now.Before(token.ExpiresAt)
+}
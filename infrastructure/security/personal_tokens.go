@@ -0,0 +1,131 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// personalTokenBytes は、パーソナルアクセストークンに含めるランダムなバイト数です。
+const personalTokenBytes = 32
+
+// PersonalTokens は、パーソナルアクセストークンの発行、検証、無効化を行う構造体です。トークンはIPersonalTokenStoreに保存します。
+type PersonalTokens struct {
+	store IPersonalTokenStore
+	// maxLifetime は、発行できるトークンの有効期限の上限です。0の場合は、無期限のトークンも発行できます。
+	maxLifetime time.Duration
+	clock       IClock
+}
+
+// Create は、ユーザのパーソナルアクセストークンを発行します。expiresAtがゼロ値の場合は、無期限のトークンになります。
+// 有効期限が上限を超える場合は、ErrPersonalTokenLifetimeExceededを返却します。
+// トークンそのものはストアに保存しないため、返却されたtokenを再び取得することはできません。
+func (tokens *PersonalTokens) Create(userId *users.UserId, name string, scopes []Scope, expiresAt time.Time) (token string, info *PersonalToken, err error) {
+	now := tokens.clock.Now()
+	if tokens.maxLifetime > 0 && (expiresAt.IsZero() || expiresAt.After(now.Add(tokens.maxLifetime))) {
+		return "", nil, ErrPersonalTokenLifetimeExceeded
+	}
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+	info = &PersonalToken{
+		Id:        id.String(),
+		UserId:    *userId,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: expiresAt,
+	}
+	for {
+		bytes := make([]byte, personalTokenBytes)
+		if _, err = rand.Read(bytes); err != nil {
+			return "", nil, err
+		}
+		token = PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(bytes)
+		err = tokens.store.Create(hashToken(token), info)
+		// 同じトークンが存在していた場合は、やりなおす。
+		if errors.Is(err, ErrPersonalTokenExists) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return token, info, nil
+	}
+}
+
+// Authenticate は、パーソナルアクセストークンを検証し、有効な場合は最終利用時刻を更新して返却します。
+func (tokens *PersonalTokens) Authenticate(token string) (info *PersonalToken, ok bool) {
+	if !IsPersonalToken(token) {
+		return nil, false
+	}
+	key := hashToken(token)
+	info, err := tokens.store.Find(key)
+	if err != nil {
+		return nil, false
+	}
+	now := tokens.clock.Now()
+	if info.IsExpired(now) {
+		return nil, false
+	}
+	if err = tokens.store.Touch(key, now); err != nil {
+		return nil, false
+	}
+	info.LastUsedAt = now
+	return info, true
+}
+
+// List は、ユーザの有効なパーソナルアクセストークンを、発行された順に返却します。
+func (tokens *PersonalTokens) List(userId *users.UserId) (infos []*PersonalToken, err error) {
+	found, err := tokens.store.FindByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	now := tokens.clock.Now()
+	for _, info := range found {
+		if !info.IsExpired(now) {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].CreatedAt.Before(infos[j].CreatedAt)
+	})
+	return infos, nil
+}
+
+// Revoke は、IDを指定してユーザのパーソナルアクセストークンを無効化します。ユーザのトークンでない場合は、ErrPersonalTokenNotFoundを返却します。
+func (tokens *PersonalTokens) Revoke(userId *users.UserId, id string) error {
+	return tokens.store.Delete(userId, id)
+}
+
+// RevokeAll は、ユーザのパーソナルアクセストークンをすべて無効化します。パスワードの変更や退会の際に使用します。
+func (tokens *PersonalTokens) RevokeAll(userId *users.UserId) error {
+	return tokens.store.DeleteByUserId(userId)
+}
+
+// Reap は、有効期限が切れたパーソナルアクセストークンをすべて破棄します。
+func (tokens *PersonalTokens) Reap() error {
+	return tokens.store.DeleteExpired(tokens.clock.Now())
+}
+
+// StartReaper は、intervalごとに期限切れのパーソナルアクセストークンを破棄するゴルーチンを開始します。返却された関数を呼び出すと停止します。
+func (tokens *PersonalTokens) StartReaper(interval time.Duration) (stop func()) {
+	return StartTicker(interval, func() { tokens.Reap() })
+}
+
+// IsPersonalToken は、トークンがパーソナルアクセストークンの形式の場合にtrueを返却します。
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// NewPersonalTokens は、PersonalTokens構造体を初期化し、返却します。maxLifetimeが0の場合は、無期限のトークンも発行できます。
+func NewPersonalTokens(store IPersonalTokenStore, maxLifetime time.Duration, clock IClock) *PersonalTokens {
+	return &PersonalTokens{store: store, maxLifetime: maxLifetime, clock: clock}
+}
@@ -0,0 +1,112 @@
+package security_test
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestPersonalTokens は、メモリ上に保存する、有効期限の上限がないPersonalTokensを返却します。
+func newTestPersonalTokens() (*security.PersonalTokens, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	return security.NewPersonalTokens(security.NewMemoryPersonalTokenStore(), 0, clock), clock
+}
+
+func TestPersonalTokens(t *testing.T) {
+	userId := users.NewUserId(1)
+	t.Run("発行したトークンで認証できる", func(t *testing.T) {
+		tokens, clock := newTestPersonalTokens()
+		token, info, err := tokens.Create(userId, "ci", []security.Scope{security.ScopeBackupWrite}, time.Time{})
+		if err != nil || !strings.HasPrefix(token, security.PersonalTokenPrefix) {
+			t.Error(err)
+			return
+		}
+		clock.Advance(24 * time.Hour)
+		authenticated, ok := tokens.Authenticate(token)
+		if !ok || authenticated.Id != info.Id || !authenticated.UserId.Equals(userId) || !authenticated.LastUsedAt.Equal(clock.Now()) {
+			t.Error()
+		}
+		if !authenticated.HasScopes(security.ScopeBackupWrite) || authenticated.HasScopes(security.ScopeBackupRead) {
+			t.Error()
+		}
+	})
+	t.Run("存在しないトークンやセッションのトークンでは認証できない", func(t *testing.T) {
+		tokens, _ := newTestPersonalTokens()
+		if _, ok := tokens.Authenticate(security.PersonalTokenPrefix + "unknown"); ok {
+			t.Error()
+		}
+		if _, ok := tokens.Authenticate("00000000-0000-0000-0000-000000000000"); ok {
+			t.Error()
+		}
+	})
+	t.Run("有効期限を過ぎると認証できず、一覧にも含まれない", func(t *testing.T) {
+		tokens, clock := newTestPersonalTokens()
+		token, _, _ := tokens.Create(userId, "cron", []security.Scope{security.ScopeBackupRead}, clock.Now().Add(time.Hour))
+		tokens.Create(userId, "ci", []security.Scope{security.ScopeBackupRead}, time.Time{})
+		clock.Advance(time.Hour)
+		if _, ok := tokens.Authenticate(token); ok {
+			t.Error()
+		}
+		infos, err := tokens.List(userId)
+		if err != nil || len(infos) != 1 || infos[0].Name != "ci" {
+			t.Error(err)
+		}
+	})
+	t.Run("有効期限の上限を超えるトークンは発行できない", func(t *testing.T) {
+		clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+		tokens := security.NewPersonalTokens(security.NewMemoryPersonalTokenStore(), 24*time.Hour, clock)
+		scopes := []security.Scope{security.ScopeBackupRead}
+		if _, _, err := tokens.Create(userId, "ci", scopes, time.Time{}); !errors.Is(err, security.ErrPersonalTokenLifetimeExceeded) {
+			t.Error(err)
+		}
+		if _, _, err := tokens.Create(userId, "ci", scopes, clock.Now().Add(25*time.Hour)); !errors.Is(err, security.ErrPersonalTokenLifetimeExceeded) {
+			t.Error(err)
+		}
+		if _, _, err := tokens.Create(userId, "ci", scopes, clock.Now().Add(24*time.Hour)); err != nil {
+			t.Error(err)
+		}
+	})
+	t.Run("無効化したトークンでは認証できない", func(t *testing.T) {
+		tokens, _ := newTestPersonalTokens()
+		token, info, _ := tokens.Create(userId, "ci", []security.Scope{security.ScopeBackupRead}, time.Time{})
+		// 別のユーザのトークンは無効化できない
+		if err := tokens.Revoke(users.NewUserId(2), info.Id); !errors.Is(err, security.ErrPersonalTokenNotFound) {
+			t.Error(err)
+		}
+		if err := tokens.Revoke(userId, info.Id); err != nil {
+			t.Error(err)
+		}
+		if _, ok := tokens.Authenticate(token); ok {
+			t.Error()
+		}
+	})
+	t.Run("ユーザのトークンをすべて無効化できる", func(t *testing.T) {
+		tokens, _ := newTestPersonalTokens()
+		token1, _, _ := tokens.Create(userId, "ci", []security.Scope{security.ScopeBackupRead}, time.Time{})
+		token2, _, _ := tokens.Create(userId, "cron", []security.Scope{security.ScopeBackupWrite}, time.Time{})
+		otherToken, _, _ := tokens.Create(users.NewUserId(2), "ci", []security.Scope{security.ScopeBackupRead}, time.Time{})
+		if err := tokens.RevokeAll(userId); err != nil {
+			t.Error(err)
+		}
+		_, ok1 := tokens.Authenticate(token1)
+		_, ok2 := tokens.Authenticate(token2)
+		_, okOther := tokens.Authenticate(otherToken)
+		if ok1 || ok2 || !okOther {
+			t.Error()
+		}
+	})
+}
+
+func TestNewScope(t *testing.T) {
+	for _, value := range []string{"backup:read", "backup:write", "user:read"} {
+		if _, err := security.NewScope(value); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := security.NewScope("admin"); err == nil {
+		t.Error()
+	}
+}
@@ -0,0 +1,45 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount は、一度に生成するリカバリーコードの数です。
+	RecoveryCodeCount = 10
+	// recoveryCodeBytes は、リカバリーコードに含めるランダムなバイト数です。80ビットあるため、ハッシュ値が漏洩しても総当たりで復元することはできません。
+	recoveryCodeBytes = 10
+	// recoveryCodeGroupLength は、読みやすくするためにハイフンで区切る文字数です。
+	recoveryCodeGroupLength = 4
+)
+
+// GenerateRecoveryCodes は、利用者に見せるリカバリーコードと、保存するためのハッシュ値を生成します。
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	codes = make([]string, RecoveryCodeCount)
+	hashes = make([]string, RecoveryCodeCount)
+	for i := range codes {
+		bytes := make([]byte, recoveryCodeBytes)
+		if _, err = rand.Read(bytes); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes))
+		var groups []string
+		for start := 0; start < len(encoded); start += recoveryCodeGroupLength {
+			groups = append(groups, encoded[start:start+recoveryCodeGroupLength])
+		}
+		codes[i] = strings.Join(groups, "-")
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode は、リカバリーコードを保存・照合するためのハッシュ値を返却します。大文字と小文字、ハイフンと空白の有無は区別しません。
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,44 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := security.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != security.RecoveryCodeCount || len(hashes) != security.RecoveryCodeCount {
+		t.Error(codes)
+		return
+	}
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if len(code) != 19 || strings.Count(code, "-") != 3 || seen[code] {
+			t.Error(code)
+		}
+		seen[code] = true
+		if hashes[i] != security.HashRecoveryCode(code) || hashes[i] == code {
+			t.Error(hashes[i])
+		}
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	hash := security.HashRecoveryCode("abcd-efgh-ijkl-mnop")
+	t.Run("表記の揺れを区別しない", func(t *testing.T) {
+		for _, code := range []string{"ABCD-EFGH-IJKL-MNOP", "abcdefghijklmnop", "abcd efgh ijkl mnop"} {
+			if security.HashRecoveryCode(code) != hash {
+				t.Error(code)
+			}
+		}
+	})
+	t.Run("異なるコード", func(t *testing.T) {
+		if security.HashRecoveryCode("abcd-efgh-ijkl-mnoq") == hash {
+			t.Error()
+		}
+	})
+}
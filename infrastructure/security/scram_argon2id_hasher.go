@@ -0,0 +1,133 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// scramArgon2idPrefix は、SCRAM形式(RFC 5802)の鍵としてエンコードされたハッシュ値の接頭辞です。
+const scramArgon2idPrefix = "$scram-argon2id$"
+
+// ScramArgon2idHasher は、Argon2idで導出した鍵から、SCRAM形式(RFC 5802)のStoredKeyとServerKeyを保存するハッシャです。
+// ハッシュ値は「$scram-argon2id$v=19$m=65536,t=3,p=2,l=32$<ソルト>$<StoredKey>$<ServerKey>」の形式でエンコードされます。
+// StoredKeyはClientKeyのハッシュ値なので、保存済みのハッシュ値からチャレンジ&レスポンス認証の証明を作ることはできません。
+type ScramArgon2idHasher struct {
+	params Argon2idParams
+}
+
+// Hash は、パスワードをランダムなソルト付きでハッシュ化し、エンコード済みの文字列を返却します。
+func (h *ScramArgon2idHasher) Hash(password string) (encoded string, err error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err = rand.Read(salt); err != nil {
+		return "", err
+	}
+	saltedPassword := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+	storedKey, serverKey := deriveScramKeys(saltedPassword)
+	return encodeScramArgon2id(h.params, salt, storedKey, serverKey), nil
+}
+
+// Verify は、パスワードから導出したStoredKeyと、エンコード済みのハッシュ値のStoredKeyを照合します。パラメータはハッシュ値から読み取ります。
+func (h *ScramArgon2idHasher) Verify(password string, encoded string) (ok bool, err error) {
+	params, salt, storedKey, _, err := decodeScramArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	saltedPassword := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	otherStoredKey, _ := deriveScramKeys(saltedPassword)
+	return subtle.ConstantTimeCompare(storedKey, otherStoredKey) == 1, nil
+}
+
+// IsSupported は、エンコード済みのハッシュ値がSCRAM形式であればtrueを返却します。
+func (h *ScramArgon2idHasher) IsSupported(encoded string) bool {
+	return strings.HasPrefix(encoded, scramArgon2idPrefix)
+}
+
+// NeedsRehash は、ハッシュ値がSCRAM形式でない場合や、パラメータが現在の設定と異なる場合にtrueを返却します。
+func (h *ScramArgon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, _, err := decodeScramArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != h.params
+}
+
+// ComputeClientKey は、パスワードから導出した鍵(SaltedPassword)から、ClientKeyを計算します。
+func ComputeClientKey(saltedPassword []byte) []byte {
+	mac := hmac.New(sha256.New, saltedPassword)
+	mac.Write([]byte("Client Key"))
+	return mac.Sum(nil)
+}
+
+// deriveScramKeys は、パスワードから導出した鍵(SaltedPassword)から、StoredKeyとServerKeyを計算します。
+func deriveScramKeys(saltedPassword []byte) (storedKey []byte, serverKey []byte) {
+	clientKey := sha256.Sum256(ComputeClientKey(saltedPassword))
+	mac := hmac.New(sha256.New, saltedPassword)
+	mac.Write([]byte("Server Key"))
+	return clientKey[:], mac.Sum(nil)
+}
+
+// encodeScramArgon2id は、パラメータ・ソルト・StoredKey・ServerKeyをエンコードした文字列を返却します。
+func encodeScramArgon2id(params Argon2idParams, salt []byte, storedKey []byte, serverKey []byte) string {
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d,l=%d$%s$%s$%s",
+		scramArgon2idPrefix,
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		params.KeyLength,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(storedKey),
+		base64.RawStdEncoding.EncodeToString(serverKey),
+	)
+}
+
+// decodeScramArgon2id は、エンコード済みの文字列からパラメータ・ソルト・StoredKey・ServerKeyを読み取ります。
+func decodeScramArgon2id(encoded string) (params Argon2idParams, salt []byte, storedKey []byte, serverKey []byte, err error) {
+	// 先頭が空文字列になるため、7要素に分割される。
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 7 || parts[1] != "scram-argon2id" {
+		return params, nil, nil, nil, errors.New("invalid scram-argon2id hash format")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d,l=%d", &params.Memory, &params.Iterations, &params.Parallelism, &params.KeyLength); err != nil {
+		return params, nil, nil, nil, err
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, nil, err
+	}
+	storedKey, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, nil, err
+	}
+	serverKey, err = base64.RawStdEncoding.DecodeString(parts[6])
+	if err != nil {
+		return params, nil, nil, nil, err
+	}
+	if len(storedKey) != sha256.Size || len(serverKey) != sha256.Size {
+		return params, nil, nil, nil, errors.New("invalid scram-argon2id key length")
+	}
+	params.SaltLength = uint32(len(salt))
+	return params, salt, storedKey, serverKey, nil
+}
+
+// NewScramArgon2idHasher は、ScramArgon2idHasher構造体を初期化し、返却します。
+func NewScramArgon2idHasher(params Argon2idParams) *ScramArgon2idHasher {
+	return &ScramArgon2idHasher{params: params}
+}
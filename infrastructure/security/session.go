@@ -0,0 +1,73 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound は、セッションが見つからなかったことを表すエラーです。
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExists は、同じキーのセッションがすでに存在することを表すエラーです。
+	ErrSessionExists = errors.New("session already exists")
+	// ErrSessionAlreadyUsed は、一度しか使用できないセッションがすでに使用済みであることを表すエラーです。
+	ErrSessionAlreadyUsed = errors.New("session already used")
+)
+
+// TokenKind は、セッションに紐づくトークンの種類を表現する型です。
+type TokenKind string
+
+const (
+	// TokenKindAccess は、APIへのアクセスに使用する、有効期限の短いアクセストークンです。
+	TokenKindAccess TokenKind = "access"
+	// TokenKindRefresh は、アクセストークンを再発行するために一度だけ使用できる、有効期限の長いリフレッシュトークンです。
+	TokenKindRefresh TokenKind = "refresh"
+)
+
+// Session は、トークンに紐づけられたセッションを表現する構造体です。
+// 一度のサインインで発行されたトークンと、それらをリフレッシュして発行されたトークンは、同じFamilyIdをもちます。
+type Session struct {
+	UserId   users.UserId
+	Kind     TokenKind
+	FamilyId string
+	// CreatedAt は、サインインした時刻です。リフレッシュしても引き継がれます。
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	// ExpiresAt は、トークン自体の有効期限です。
+	ExpiresAt time.Time
+	// Used は、リフレッシュトークンが使用済みかを表します。
+	Used bool
+	// Client は、トークンを要求したクライアントの情報です。
+	Client ClientInfo
+}
+
+// maxUserAgentLength は、保存するUser-Agentの最大バイト数です。
+const maxUserAgentLength = 255
+
+// ClientInfo は、トークンを要求したクライアントの情報を表現する構造体です。
+type ClientInfo struct {
+	RemoteAddr string
+	UserAgent  string
+}
+
+// NewClientInfo は、ClientInfo構造体を初期化し、返却します。remoteAddrはポート番号を除き、userAgentは255バイトまでに切り詰めます。
+func NewClientInfo(remoteAddr string, userAgent string) ClientInfo {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	return ClientInfo{RemoteAddr: RemoteHost(remoteAddr), UserAgent: userAgent}
+}
+
+// SessionInfo は、一度のサインインで発行されたトークンをまとめた、利用者に見せるためのセッションの情報を表現する構造体です。
+type SessionInfo struct {
+	// FamilyId は、セッションのIDです。
+	FamilyId string
+	// CreatedAt は、サインインした時刻です。
+	CreatedAt time.Time
+	// LastUsedAt は、セッションのトークンが最後に利用された時刻です。
+	LastUsedAt time.Time
+	// Client は、最後にトークンを要求したクライアントの情報です。
+	Client ClientInfo
+}
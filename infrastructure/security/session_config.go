@@ -0,0 +1,25 @@
+package security
+
+import "time"
+
+// SessionConfig は、セッションの有効期限に関する設定を表現する構造体です。
+type SessionConfig struct {
+	// AbsoluteTimeout は、サインインからセッションが無効になるまでの時間です。リフレッシュしても延長されません。
+	AbsoluteTimeout time.Duration
+	// IdleTimeout は、トークンが最後に利用されてから無効になるまでの時間です。リフレッシュトークンの有効期限にもなります。
+	IdleTimeout time.Duration
+	// AccessTokenTimeout は、アクセストークンの発行から無効になるまでの時間です。
+	AccessTokenTimeout time.Duration
+	// ReapInterval は、期限切れのセッションを破棄する間隔です。
+	ReapInterval time.Duration
+}
+
+// DefaultSessionConfig は、セッションの既定の設定を返却します。
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		AbsoluteTimeout:    30 * 24 * time.Hour,
+		IdleTimeout:        7 * 24 * time.Hour,
+		AccessTokenTimeout: 15 * time.Minute,
+		ReapInterval:       time.Minute,
+	}
+}
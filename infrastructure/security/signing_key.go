@@ -0,0 +1,131 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// 署名アルゴリズムの種類です。
+const (
+	// SigningAlgorithmHS256 は、共有鍵を用いるHMAC-SHA256です。
+	SigningAlgorithmHS256 = "HS256"
+	// SigningAlgorithmEdDSA は、公開鍵暗号を用いるEd25519です。
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// hmacSecretMinLength は、HMACの共有鍵の最小バイト長です。
+const hmacSecretMinLength = 32
+
+// SigningKey は、アクセストークンの署名・検証に用いる鍵を表現する構造体です。Idはトークンのヘッダに含まれ、検証時に鍵を選択するために使用します。
+type SigningKey struct {
+	Id        string
+	Algorithm string
+
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// CanSign は、鍵が署名に使用できる場合にtrueを返却します。Ed25519の公開鍵のみの場合は、検証にのみ使用できます。
+func (key *SigningKey) CanSign() bool {
+	return key.secret != nil || key.privateKey != nil
+}
+
+// sign は、メッセージに署名します。
+func (key *SigningKey) sign(message []byte) (signature []byte, err error) {
+	switch {
+	case key.secret != nil:
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(message)
+		return mac.Sum(nil), nil
+	case key.privateKey != nil:
+		return ed25519.Sign(key.privateKey, message), nil
+	}
+	return nil, fmt.Errorf("signing key '%s' can only verify", key.Id)
+}
+
+// verify は、メッセージの署名を検証します。正しい署名であればtrueを返却します。
+func (key *SigningKey) verify(message []byte, signature []byte) bool {
+	if key.secret != nil {
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write(message)
+		return hmac.Equal(mac.Sum(nil), signature)
+	}
+	return ed25519.Verify(key.publicKey, message, signature)
+}
+
+// NewHMACSigningKey は、HMAC-SHA256の共有鍵を初期化し、返却します。secretは32バイト以上です。
+func NewHMACSigningKey(id string, secret []byte) (key *SigningKey, err error) {
+	if len(secret) < hmacSecretMinLength {
+		return nil, errors.New("'secret' must be at least 32 bytes")
+	}
+	return &SigningKey{Id: id, Algorithm: SigningAlgorithmHS256, secret: secret}, nil
+}
+
+// NewEd25519SigningKey は、Ed25519の秘密鍵から、署名と検証に使用できる鍵を初期化し、返却します。
+func NewEd25519SigningKey(id string, privateKey ed25519.PrivateKey) *SigningKey {
+	return &SigningKey{
+		Id:         id,
+		Algorithm:  SigningAlgorithmEdDSA,
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+}
+
+// NewEd25519VerifyingKey は、Ed25519の公開鍵から、検証にのみ使用できる鍵を初期化し、返却します。
+func NewEd25519VerifyingKey(id string, publicKey ed25519.PublicKey) *SigningKey {
+	return &SigningKey{Id: id, Algorithm: SigningAlgorithmEdDSA, publicKey: publicKey}
+}
+
+// LoadSigningKey は、ファイルから鍵を読み込みます。
+// HS256の場合はファイルの内容そのものを共有鍵とし、EdDSAの場合はPEM形式の秘密鍵(PKCS #8)または公開鍵(PKIX)を読み込みます。
+func LoadSigningKey(id string, algorithm string, path string) (key *SigningKey, err error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case SigningAlgorithmHS256:
+		return NewHMACSigningKey(id, bytes)
+	case SigningAlgorithmEdDSA:
+		return parseEd25519PEM(id, bytes)
+	}
+	return nil, fmt.Errorf("unknown signing algorithm: %s", algorithm)
+}
+
+// parseEd25519PEM は、PEM形式のEd25519の鍵を読み込みます。
+func parseEd25519PEM(id string, bytes []byte) (key *SigningKey, err error) {
+	block, _ := pem.Decode(bytes)
+	if block == nil {
+		return nil, errors.New("could not decode PEM")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		privateKey, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 private key")
+		}
+		return NewEd25519SigningKey(id, privateKey), nil
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("not an ed25519 public key")
+		}
+		return NewEd25519VerifyingKey(id, publicKey), nil
+	}
+	return nil, fmt.Errorf("unknown PEM type: %s", block.Type)
+}
@@ -0,0 +1,11 @@
+package security
+
+import "time"
+
+// SystemClock は、システムの現在時刻を返却する構造体です。
+type SystemClock struct{}
+
+// Now は、システムの現在時刻を返却します。
+func (c SystemClock) Now() time.Time {
+	return time.Now()
+}
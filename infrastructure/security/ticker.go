@@ -0,0 +1,28 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// StartTicker は、intervalごとにtickを呼び出すゴルーチンを開始します。返却された関数を呼び出すと停止します。複数回呼び出しても問題ありません。
+// 期限切れのセッションの破棄や、証明書の読み込み直しなど、定期的な処理に使用します。
+func StartTicker(interval time.Duration, tick func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				tick()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
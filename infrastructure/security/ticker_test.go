@@ -0,0 +1,35 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartTicker(t *testing.T) {
+	var ticks int32
+	stop := security.StartTicker(time.Millisecond, func() { atomic.AddInt32(&ticks, 1) })
+
+	t.Run("間隔ごとに呼び出される", func(t *testing.T) {
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt32(&ticks) < 2 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if atomic.LoadInt32(&ticks) < 2 {
+			t.Error(atomic.LoadInt32(&ticks))
+		}
+	})
+
+	t.Run("停止すると呼び出されず、何度停止しても問題ない", func(t *testing.T) {
+		stop()
+		stop()
+		// 停止を受け取る前に実行中だった呼び出しを待つ。
+		time.Sleep(10 * time.Millisecond)
+		stopped := atomic.LoadInt32(&ticks)
+		time.Sleep(10 * time.Millisecond)
+		if atomic.LoadInt32(&ticks) != stopped {
+			t.Error("停止した後に呼び出されました。")
+		}
+	})
+}
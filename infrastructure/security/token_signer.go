@@ -0,0 +1,143 @@
+package security
+
+import (
+	"FrogNote_database/domain/users"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAccessToken は、署名付きアクセストークンの形式・署名・有効期限のいずれかが不正であることを表すエラーです。
+var ErrInvalidAccessToken = errors.New("invalid access token")
+
+// AccessTokenClaims は、署名付きアクセストークンに含まれる情報を表現する構造体です。
+type AccessTokenClaims struct {
+	UserId    users.UserId
+	FamilyId  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// tokenHeader は、署名付きアクセストークンのヘッダを表現する構造体です。
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+	KeyId     string `json:"kid"`
+}
+
+// tokenPayload は、署名付きアクセストークンのペイロードを表現する構造体です。
+type tokenPayload struct {
+	Subject   string `json:"sub"`
+	SessionId string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenSigner は、ユーザIDと有効期限を含むアクセストークンを署名・検証する構造体です。トークンはJWTの形式です。
+// 署名には有効な鍵を使用し、検証にはトークンのヘッダの鍵IDに一致する鍵を使用するため、古い鍵を残したまま新しい鍵に切り替えられます。
+type TokenSigner struct {
+	activeKey *SigningKey
+	keys      map[string]*SigningKey
+}
+
+// Sign は、アクセストークンを署名して返却します。
+func (signer *TokenSigner) Sign(claims *AccessTokenClaims) (token string, err error) {
+	header, err := json.Marshal(tokenHeader{Algorithm: signer.activeKey.Algorithm, Type: "JWT", KeyId: signer.activeKey.Id})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(tokenPayload{
+		Subject:   strconv.Itoa(claims.UserId.GetValue()),
+		SessionId: claims.FamilyId,
+		IssuedAt:  claims.IssuedAt.Unix(),
+		ExpiresAt: claims.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	signature, err := signer.activeKey.sign([]byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + encodeSegment(signature), nil
+}
+
+// Verify は、アクセストークンの署名と有効期限を検証し、含まれる情報を返却します。不正な場合は、ErrInvalidAccessTokenを返却します。
+func (signer *TokenSigner) Verify(token string, now time.Time) (claims *AccessTokenClaims, err error) {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return nil, ErrInvalidAccessToken
+	}
+
+	header := tokenHeader{}
+	if err = decodeSegment(segments[0], &header); err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	key, ok := signer.keys[header.KeyId]
+	// 鍵と異なるアルゴリズムを指定したトークンは受け付けない。
+	if !ok || key.Algorithm != header.Algorithm {
+		return nil, ErrInvalidAccessToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(segments[2])
+	if err != nil || !key.verify([]byte(segments[0]+"."+segments[1]), signature) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	payload := tokenPayload{}
+	if err = decodeSegment(segments[1], &payload); err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	userIdValue, err := strconv.Atoi(payload.Subject)
+	if err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+	claims = &AccessTokenClaims{
+		UserId:    *users.NewUserId(userIdValue),
+		FamilyId:  payload.SessionId,
+		IssuedAt:  time.Unix(payload.IssuedAt, 0),
+		ExpiresAt: time.Unix(payload.ExpiresAt, 0),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return nil, ErrInvalidAccessToken
+	}
+	return claims, nil
+}
+
+// encodeSegment は、トークンの各部分をbase64urlでエンコードします。
+func encodeSegment(bytes []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// decodeSegment は、base64urlでエンコードされたトークンの一部分をデコードし、JSONとしてアンマーシャルします。
+func decodeSegment(segment string, obj any) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, obj)
+}
+
+// NewTokenSigner は、TokenSigner構造体を初期化し、返却します。activeKeyIdの鍵で署名し、keysのすべての鍵で検証します。
+func NewTokenSigner(activeKeyId string, keys []*SigningKey) (signer *TokenSigner, err error) {
+	signer = &TokenSigner{keys: make(map[string]*SigningKey)}
+	for _, key := range keys {
+		if _, exists := signer.keys[key.Id]; exists {
+			return nil, fmt.Errorf("duplicated signing key id: %s", key.Id)
+		}
+		signer.keys[key.Id] = key
+	}
+	activeKey, ok := signer.keys[activeKeyId]
+	if !ok {
+		return nil, fmt.Errorf("signing key not found: %s", activeKeyId)
+	}
+	if !activeKey.CanSign() {
+		return nil, fmt.Errorf("signing key '%s' can only verify", activeKeyId)
+	}
+	signer.activeKey = activeKey
+	return signer, nil
+}
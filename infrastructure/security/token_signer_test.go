@@ -0,0 +1,164 @@
+package security_test
+
+import (
+	"FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHMACKey は、テスト用のHMACの鍵を返却します。
+func newTestHMACKey(t *testing.T, id string) *security.SigningKey {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	key, err := security.NewHMACSigningKey(id, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// newTestClaims は、発行時刻から5分間有効な情報を返却します。
+func newTestClaims(now time.Time) *security.AccessTokenClaims {
+	return &security.AccessTokenClaims{UserId: *users.NewUserId(1), FamilyId: "family", IssuedAt: now, ExpiresAt: now.Add(5 * time.Minute)}
+}
+
+func TestTokenSigner(t *testing.T) {
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	keys := map[string]*security.SigningKey{
+		"HS256": newTestHMACKey(t, "hmac"),
+		"EdDSA": security.NewEd25519SigningKey("ed25519", privateKey),
+	}
+	for algorithm, key := range keys {
+		t.Run(algorithm, func(t *testing.T) {
+			signer, err := security.NewTokenSigner(key.Id, []*security.SigningKey{key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			token, err := signer.Sign(newTestClaims(now))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Run("署名したトークンを検証できる", func(t *testing.T) {
+				claims, err := signer.Verify(token, now.Add(time.Minute))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !claims.UserId.Equals(users.NewUserId(1)) || claims.FamilyId != "family" {
+					t.Error(claims)
+				}
+			})
+
+			t.Run("有効期限が切れたトークンは検証に失敗する", func(t *testing.T) {
+				if _, err := signer.Verify(token, now.Add(5*time.Minute)); err != security.ErrInvalidAccessToken {
+					t.Error(err)
+				}
+			})
+
+			t.Run("改ざんされたトークンは検証に失敗する", func(t *testing.T) {
+				segments := strings.Split(token, ".")
+				other, _ := signer.Sign(&security.AccessTokenClaims{UserId: *users.NewUserId(2), FamilyId: "family", IssuedAt: now, ExpiresAt: now.Add(5 * time.Minute)})
+				// 別のトークンのペイロードに差し替える
+				segments[1] = strings.Split(other, ".")[1]
+				if _, err := signer.Verify(strings.Join(segments, "."), now); err != security.ErrInvalidAccessToken {
+					t.Error(err)
+				}
+			})
+		})
+	}
+
+	t.Run("UUID形式のトークンは検証に失敗する", func(t *testing.T) {
+		signer, _ := security.NewTokenSigner("hmac", []*security.SigningKey{keys["HS256"]})
+		if _, err := signer.Verify("3fa2a7a4-8d2e-4c0b-9a4a-4b7e1f3e2c10", now); err != security.ErrInvalidAccessToken {
+			t.Error(err)
+		}
+	})
+}
+
+func TestTokenSignerKeyRotation(t *testing.T) {
+	now := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+	oldKey := newTestHMACKey(t, "old")
+	newKey := newTestHMACKey(t, "new")
+
+	oldSigner, _ := security.NewTokenSigner("old", []*security.SigningKey{oldKey})
+	oldToken, _ := oldSigner.Sign(newTestClaims(now))
+
+	rotatedSigner, err := security.NewTokenSigner("new", []*security.SigningKey{oldKey, newKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, _ := rotatedSigner.Sign(newTestClaims(now))
+
+	t.Run("切り替え前の鍵で署名されたトークンも検証できる", func(t *testing.T) {
+		if _, err := rotatedSigner.Verify(oldToken, now); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("古い鍵を削除すると検証に失敗する", func(t *testing.T) {
+		newOnlySigner, _ := security.NewTokenSigner("new", []*security.SigningKey{newKey})
+		if _, err := newOnlySigner.Verify(oldToken, now); err != security.ErrInvalidAccessToken {
+			t.Error(err)
+		}
+		if _, err := newOnlySigner.Verify(newToken, now); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("存在しない鍵や検証専用の鍵は署名に使用できない", func(t *testing.T) {
+		if _, err := security.NewTokenSigner("unknown", []*security.SigningKey{oldKey}); err == nil {
+			t.Error()
+		}
+		publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+		verifyingKey := security.NewEd25519VerifyingKey("public", publicKey)
+		if _, err := security.NewTokenSigner("public", []*security.SigningKey{verifyingKey}); err == nil {
+			t.Error()
+		}
+	})
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	dir := t.TempDir()
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	privateDer, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDer, _ := x509.MarshalPKIXPublicKey(publicKey)
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}), 0600)
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}), 0600)
+
+	signingKey, err := security.LoadSigningKey("ed25519", security.SigningAlgorithmEdDSA, privatePath)
+	if err != nil || !signingKey.CanSign() {
+		t.Fatal(err)
+	}
+	verifyingKey, err := security.LoadSigningKey("ed25519", security.SigningAlgorithmEdDSA, publicPath)
+	if err != nil || verifyingKey.CanSign() {
+		t.Fatal(err)
+	}
+
+	// 秘密鍵で署名したトークンを、公開鍵のみをもつサーバで検証できる
+	now := time.Now()
+	signer, _ := security.NewTokenSigner("ed25519", []*security.SigningKey{signingKey})
+	token, _ := signer.Sign(newTestClaims(now))
+	verifier, _ := security.NewTokenSigner("hmac", []*security.SigningKey{newTestHMACKey(t, "hmac"), verifyingKey})
+	if _, err := verifier.Verify(token, now); err != nil {
+		t.Error(err)
+	}
+
+	t.Run("短すぎる共有鍵は読み込めない", func(t *testing.T) {
+		path := filepath.Join(dir, "short.key")
+		os.WriteFile(path, []byte("short"), 0600)
+		if _, err := security.LoadSigningKey("short", security.SigningAlgorithmHS256, path); err == nil {
+			t.Error()
+		}
+	})
+}
@@ -2,51 +2,321 @@ package security
 
 import (
 	"FrogNote_database/domain/users"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	// tokenUserPairs は、トークンとユーザIDを紐づけるためのマップです。
-	tokenUserPairs map[string]*users.UserId = make(map[string]*users.UserId)
+	// ErrInvalidRefreshToken は、リフレッシュトークンが存在しない、または有効期限切れであることを表すエラーです。
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused は、使用済みのリフレッシュトークンが再び使用されたことを表すエラーです。
+	ErrRefreshTokenReused = errors.New("refresh token reused")
 )
 
-// Tokens は、トークンの生成、無効化を行う構造体です。
-type Tokens struct{}
+// TokenPair は、アクセストークンとリフレッシュトークンの組を表現する構造体です。
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+// Tokens は、トークンの生成、無効化を行う構造体です。セッションはISessionStoreに保存します。
+// TokenSignerが設定されている場合、アクセストークンは署名付きのトークンとなり、ストアを参照せずに検証します。
+type Tokens struct {
+	store  ISessionStore
+	config SessionConfig
+	clock  IClock
+	signer *TokenSigner
+}
+
+// GenerateToken は、サインインしたユーザのために、新しいアクセストークンとリフレッシュトークンを生成します。
+func (tokens *Tokens) GenereteToken(userId *users.UserId, client ClientInfo) (pair *TokenPair, err error) {
+	familyId, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+	now := tokens.clock.Now()
+	return tokens.issue(userId, familyId.String(), now, now, client)
+}
+
+// Refresh は、リフレッシュトークンを使用済みにし、新しいアクセストークンとリフレッシュトークンを生成します。
+// 使用済みのリフレッシュトークンが再び使用された場合は、トークンが盗まれたとみなして同じサインインで発行されたトークンをすべて無効化し、ErrRefreshTokenReusedを返却します。
+func (tokens *Tokens) Refresh(refreshToken string, client ClientInfo) (pair *TokenPair, err error) {
+	key := hashToken(refreshToken)
+	session, err := tokens.store.Find(key)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.Kind != TokenKindRefresh {
+		return nil, ErrInvalidRefreshToken
+	}
+	now := tokens.clock.Now()
+	if tokens.isExpired(session, now) {
+		tokens.store.Delete(key)
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// 同時にリフレッシュされた場合でも、使用済みにできるのは一度だけなので、再使用を検知できる。
+	if session.Used {
+		return nil, tokens.revokeReusedFamily(session.FamilyId)
+	}
+	err = tokens.store.MarkUsed(key)
+	if errors.Is(err, ErrSessionAlreadyUsed) {
+		return nil, tokens.revokeReusedFamily(session.FamilyId)
+	}
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	pair, err = tokens.issue(&session.UserId, session.FamilyId, session.CreatedAt, now, client)
+	if err != nil {
+		return nil, err
+	}
+	// リフレッシュしている間にセッションが無効化された場合は、使用済みのリフレッシュトークンも削除されているので、発行したトークンも無効化する。
+	_, err = tokens.store.Find(key)
+	if errors.Is(err, ErrSessionNotFound) {
+		if err = tokens.store.DeleteFamily(session.FamilyId); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Invalidate は、トークンと、同じサインインで発行されたトークンをすべて無効化します。
+// 署名付きのアクセストークンはストアに保存されていないため、リフレッシュトークンのみが無効になり、アクセストークン自体は有効期限まで有効です。
+func (tokens *Tokens) Invalidate(token string) error {
+	if tokens.signer != nil {
+		if claims, err := tokens.signer.Verify(token, tokens.clock.Now()); err == nil {
+			return tokens.store.DeleteFamily(claims.FamilyId)
+		}
+	}
+	session, err := tokens.store.Find(hashToken(token))
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tokens.store.DeleteFamily(session.FamilyId)
+}
+
+// GetUserId は、アクセストークンに紐づけられたユーザを取得します。有効期限が切れている場合は無効化し、有効な場合は最終利用時刻を更新します。
+// 署名付きのアクセストークンの場合は、署名と有効期限のみを検証します。
+func (tokens *Tokens) GetUserId(token string) (userId *users.UserId, ok bool) {
+	userId, _, ok = tokens.Authenticate(token)
+	return
+}
+
+// Authenticate は、GetUserIdと同様にアクセストークンを検証し、紐づけられたユーザと、署名付きのアクセストークンが発行された時刻を返却します。
+// ストアに保存するアクセストークンは、ストアから削除することで無効化できるため、発行時刻はゼロ値です。
+func (tokens *Tokens) Authenticate(token string) (userId *users.UserId, issuedAt time.Time, ok bool) {
+	if tokens.signer != nil {
+		claims, err := tokens.signer.Verify(token, tokens.clock.Now())
+		if err != nil {
+			return nil, time.Time{}, false
+		}
+		return &claims.UserId, claims.IssuedAt, true
+	}
+
+	key := hashToken(token)
+	session, err := tokens.store.Find(key)
+	if err != nil || session.Kind != TokenKindAccess {
+		return nil, time.Time{}, false
+	}
+	now := tokens.clock.Now()
+	if tokens.isExpired(session, now) {
+		tokens.store.Delete(key)
+		return nil, time.Time{}, false
+	}
+	if err = tokens.store.Touch(key, now); err != nil {
+		return nil, time.Time{}, false
+	}
+	return &session.UserId, time.Time{}, true
+}
+
+// GetFamilyId は、アクセストークンが属するセッションのIDを取得します。
+func (tokens *Tokens) GetFamilyId(token string) (familyId string, ok bool) {
+	now := tokens.clock.Now()
+	if tokens.signer != nil {
+		claims, err := tokens.signer.Verify(token, now)
+		if err != nil {
+			return "", false
+		}
+		return claims.FamilyId, true
+	}
 
-// GenerateToken は、新しいトークンを生成します。
-func (tokens *Tokens) GenereteToken(userId *users.UserId) (token string) {
-	notGenereted := true
-	for notGenereted {
+	session, err := tokens.store.Find(hashToken(token))
+	if err != nil || session.Kind != TokenKindAccess || tokens.isExpired(session, now) {
+		return "", false
+	}
+	return session.FamilyId, true
+}
+
+// ListSessions は、ユーザの有効なセッションを、サインインごとにまとめて、最後に利用された順に返却します。
+func (tokens *Tokens) ListSessions(userId *users.UserId) (infos []*SessionInfo, err error) {
+	sessions, err := tokens.store.FindByUserId(userId)
+	if err != nil {
+		return nil, err
+	}
+	now := tokens.clock.Now()
+	families := make(map[string]*SessionInfo)
+	for _, session := range sessions {
+		// 使用済みのリフレッシュトークンは、再使用の検知のために残しているだけなので含めない。
+		if session.Used || tokens.isExpired(session, now) {
+			continue
+		}
+		info, exists := families[session.FamilyId]
+		if !exists {
+			info = &SessionInfo{FamilyId: session.FamilyId, CreatedAt: session.CreatedAt}
+			families[session.FamilyId] = info
+			infos = append(infos, info)
+		}
+		if !exists || session.LastUsedAt.After(info.LastUsedAt) {
+			info.LastUsedAt = session.LastUsedAt
+			info.Client = session.Client
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].LastUsedAt.After(infos[j].LastUsedAt)
+	})
+	return infos, nil
+}
+
+// RevokeSession は、ユーザのセッションを無効化します。familyIdがユーザのセッションでない場合は、ErrSessionNotFoundを返却します。
+func (tokens *Tokens) RevokeSession(userId *users.UserId, familyId string) error {
+	sessions, err := tokens.store.FindByUserId(userId)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.FamilyId == familyId {
+			return tokens.store.DeleteFamily(familyId)
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RevokeOtherSessions は、currentFamilyId以外のユーザのセッションをすべて無効化します。
+func (tokens *Tokens) RevokeOtherSessions(userId *users.UserId, currentFamilyId string) error {
+	return tokens.store.DeleteByUserId(userId, currentFamilyId)
+}
+
+// RevokeAllSessions は、ユーザのセッションをすべて無効化します。パスワードの変更や退会の際に使用します。
+func (tokens *Tokens) RevokeAllSessions(userId *users.UserId) error {
+	return tokens.store.DeleteByUserId(userId, "")
+}
+
+// Reap は、有効期限が切れたセッションをすべて破棄します。
+func (tokens *Tokens) Reap() error {
+	now := tokens.clock.Now()
+	return tokens.store.DeleteExpired(now, now.Add(-tokens.config.AbsoluteTimeout), now.Add(-tokens.config.IdleTimeout))
+}
+
+// StartReaper は、設定された間隔で期限切れのセッションを破棄するゴルーチンを開始します。返却された関数を呼び出すと停止します。
+func (tokens *Tokens) StartReaper() (stop func()) {
+	return StartTicker(tokens.config.ReapInterval, func() { tokens.Reap() })
+}
+
+// issue は、familyIdのセッションとして、アクセストークンとリフレッシュトークンを生成します。どちらの有効期限も、サインインからの有効期限を超えません。
+func (tokens *Tokens) issue(userId *users.UserId, familyId string, createdAt time.Time, now time.Time, client ClientInfo) (pair *TokenPair, err error) {
+	limit := createdAt.Add(tokens.config.AbsoluteTimeout)
+	pair = &TokenPair{
+		AccessTokenExpiresAt:  earlier(now.Add(tokens.config.AccessTokenTimeout), limit),
+		RefreshTokenExpiresAt: earlier(now.Add(tokens.config.IdleTimeout), limit),
+	}
+	if tokens.signer != nil {
+		pair.AccessToken, err = tokens.signer.Sign(&AccessTokenClaims{
+			UserId: *userId, FamilyId: familyId, IssuedAt: now, ExpiresAt: pair.AccessTokenExpiresAt,
+		})
+	} else {
+		pair.AccessToken, err = tokens.createToken(&Session{
+			UserId: *userId, Kind: TokenKindAccess, FamilyId: familyId, CreatedAt: createdAt, LastUsedAt: now, ExpiresAt: pair.AccessTokenExpiresAt, Client: client,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	pair.RefreshToken, err = tokens.createToken(&Session{
+		UserId: *userId, Kind: TokenKindRefresh, FamilyId: familyId, CreatedAt: createdAt, LastUsedAt: now, ExpiresAt: pair.RefreshTokenExpiresAt, Client: client,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// createToken は、新しいトークンを生成し、セッションと紐づけて保存します。
+func (tokens *Tokens) createToken(session *Session) (token string, err error) {
+	for {
 		uuid, err := uuid.NewRandom()
 		if err != nil {
 			continue
 		}
 
 		token = uuid.String()
+		err = tokens.store.Create(hashToken(token), session)
 		// すでに同じトークンが存在していた場合、やりなおす。（uuidなので基本的に重複しないが、セキュリティ機能なのでしっかり重複チェックを行っている。）
-		if _, exists := tokens.GetUserId(token); exists {
+		if errors.Is(err, ErrSessionExists) {
 			continue
 		}
-		// 存在していない
-		notGenereted = false
+		if err != nil {
+			return "", err
+		}
+		return token, nil
 	}
-	tokens.set(token, userId)
-	return
 }
 
-// Invalidate は、トークンを無効化します。
-func (tokens *Tokens) Invalidate(token string) {
-	delete(tokenUserPairs, token)
+// revokeReusedFamily は、リフレッシュトークンが再使用されたサインインのトークンをすべて無効化し、ErrRefreshTokenReusedを返却します。
+func (tokens *Tokens) revokeReusedFamily(familyId string) error {
+	if err := tokens.store.DeleteFamily(familyId); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
 }
 
-// GetUserId は、トークンに紐づけられたユーザを取得します。
-func (tokens *Tokens) GetUserId(token string) (userId *users.UserId, ok bool) {
-	userId, ok = tokenUserPairs[token]
-	return
+// isExpired は、トークンの有効期限、サインインからの有効期限、最終利用からの有効期限のいずれかを過ぎている場合にtrueを返却します。
+func (tokens *Tokens) isExpired(session *Session, now time.Time) bool {
+	return !now.Before(session.ExpiresAt) ||
+		!now.Before(session.CreatedAt.Add(tokens.config.AbsoluteTimeout)) ||
+		!now.Before(session.LastUsedAt.Add(tokens.config.IdleTimeout))
+}
+
+// hashToken は、ストアのキーとして用いるトークンのハッシュ値を返却します。ストアが漏洩してもトークンとして使用できないようにするためです。
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// earlier は、二つの時刻のうち早い方を返却します。
+func earlier(x time.Time, y time.Time) time.Time {
+	if x.Before(y) {
+		return x
+	}
+	return y
+}
+
+// NewTokens は、アクセストークンをストアに保存するTokens構造体を初期化し、返却します。
+func NewTokens(store ISessionStore, config SessionConfig, clock IClock) *Tokens {
+	return &Tokens{store: store, config: config, clock: clock}
 }
 
-// set は、トークンとユーザIDを紐づけます。
-func (tokens *Tokens) set(token string, userId *users.UserId) {
-	tokenUserPairs[token] = userId
+// NewSignedTokens は、署名付きのアクセストークンを発行するTokens構造体を初期化し、返却します。
+// アクセストークンはストアを参照せずに検証できるため、ストアを共有していない複数のサーバでも検証できます。リフレッシュトークンはストアに保存します。
+func NewSignedTokens(store ISessionStore, config SessionConfig, clock IClock, signer *TokenSigner) *Tokens {
+	return &Tokens{store: store, config: config, clock: clock, signer: signer}
 }
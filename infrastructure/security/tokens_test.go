@@ -3,13 +3,59 @@ package security_test
 import (
 	"FrogNote_database/domain/users"
 	"FrogNote_database/infrastructure/security"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 )
 
+// testClient は、テスト用のクライアントの情報です。
+var testClient = security.NewClientInfo("192.0.2.1:1234", "test")
+
+// fakeClock は、テスト用に時刻を操作できる時計です。
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance は、時刻をdだけ進めます。
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestTokens は、サインインから60分、最終利用から10分で期限切れになるTokensを返却します。アクセストークン自体の有効期限は60分です。
+func newTestTokens() (*security.Tokens, *fakeClock) {
+	config := security.SessionConfig{AbsoluteTimeout: 60 * time.Minute, IdleTimeout: 10 * time.Minute, AccessTokenTimeout: 60 * time.Minute, ReapInterval: time.Minute}
+	return newTestTokensWithConfig(config)
+}
+
+// newTestTokensWithConfig は、設定を指定してTokensを返却します。
+func newTestTokensWithConfig(config security.SessionConfig) (*security.Tokens, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	return security.NewTokens(security.NewMemorySessionStore(), config, clock), clock
+}
+
+// generateAccessToken は、アクセストークンを生成して返却します。
+func generateAccessToken(t *testing.T, tokens *security.Tokens, userId *users.UserId) string {
+	pair, err := tokens.GenereteToken(userId, testClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair.AccessToken
+}
+
 func TestGetUserId(t *testing.T) {
-	tokens := security.Tokens{}
+	tokens, _ := newTestTokens()
 	userId := users.NewUserId(1)
-	token := tokens.GenereteToken(userId)
+	token := generateAccessToken(t, tokens, userId)
 	userIdFromTokens, ok := tokens.GetUserId(token)
 
 	if !ok || !userId.Equals(userIdFromTokens) {
@@ -18,9 +64,9 @@ func TestGetUserId(t *testing.T) {
 }
 
 func TestInvalidate(t *testing.T) {
-	tokens := security.Tokens{}
+	tokens, _ := newTestTokens()
 	userId := users.NewUserId(1)
-	token := tokens.GenereteToken(userId)
+	token := generateAccessToken(t, tokens, userId)
 
 	// 無効化
 	tokens.Invalidate(token)
@@ -31,3 +77,414 @@ func TestInvalidate(t *testing.T) {
 		t.Error()
 	}
 }
+
+func TestIdleTimeout(t *testing.T) {
+	t.Run("最終利用から一定時間が経過すると無効になる", func(t *testing.T) {
+		tokens, clock := newTestTokens()
+		token := generateAccessToken(t, tokens, users.NewUserId(1))
+		clock.Advance(10 * time.Minute)
+		if _, ok := tokens.GetUserId(token); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("利用されるたびに有効期限が延長される", func(t *testing.T) {
+		tokens, clock := newTestTokens()
+		token := generateAccessToken(t, tokens, users.NewUserId(1))
+		for i := 0; i < 3; i++ {
+			clock.Advance(9 * time.Minute)
+			if _, ok := tokens.GetUserId(token); !ok {
+				t.Error(i)
+			}
+		}
+	})
+}
+
+func TestAbsoluteTimeout(t *testing.T) {
+	tokens, clock := newTestTokens()
+	token := generateAccessToken(t, tokens, users.NewUserId(1))
+	// 利用し続けても、発行から一定時間が経過すると無効になる
+	for i := 0; i < 6; i++ {
+		clock.Advance(9 * time.Minute)
+		if _, ok := tokens.GetUserId(token); !ok {
+			t.Fatal(i)
+		}
+	}
+	clock.Advance(9 * time.Minute)
+	if _, ok := tokens.GetUserId(token); ok {
+		t.Error()
+	}
+}
+
+func TestReap(t *testing.T) {
+	tokens, clock := newTestTokens()
+	expired := generateAccessToken(t, tokens, users.NewUserId(1))
+	clock.Advance(5 * time.Minute)
+	alive := generateAccessToken(t, tokens, users.NewUserId(2))
+	clock.Advance(5 * time.Minute)
+
+	tokens.Reap()
+
+	// 期限切れの時刻より前に戻しても、破棄されていれば取得できない
+	clock.Advance(-time.Minute)
+	if _, ok := tokens.GetUserId(expired); ok {
+		t.Error("expired session was not reaped")
+	}
+	if _, ok := tokens.GetUserId(alive); !ok {
+		t.Error("alive session was reaped")
+	}
+}
+
+func TestTokensConcurrentAccess(t *testing.T) {
+	tokens, clock := newTestTokens()
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userId := users.NewUserId(i)
+			for j := 0; j < 100; j++ {
+				pair, err := tokens.GenereteToken(userId, testClient)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if pair, err = tokens.Refresh(pair.RefreshToken, testClient); err != nil {
+					t.Error(err)
+					return
+				}
+				token := pair.AccessToken
+				if id, ok := tokens.GetUserId(token); !ok || !id.Equals(userId) {
+					t.Error(i, j)
+					return
+				}
+				tokens.Invalidate(token)
+			}
+		}(i)
+	}
+	// 生成・取得・無効化と並行して、時刻の更新と破棄を行う
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			clock.Advance(time.Second)
+			tokens.Reap()
+		}
+	}()
+	wg.Wait()
+}
+
+func TestAccessTokenTimeout(t *testing.T) {
+	config := security.SessionConfig{AbsoluteTimeout: 60 * time.Minute, IdleTimeout: 30 * time.Minute, AccessTokenTimeout: 5 * time.Minute, ReapInterval: time.Minute}
+	tokens, clock := newTestTokensWithConfig(config)
+	pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+	if !pair.AccessTokenExpiresAt.Equal(clock.Now().Add(5*time.Minute)) || !pair.RefreshTokenExpiresAt.Equal(clock.Now().Add(30*time.Minute)) {
+		t.Error(pair)
+	}
+
+	// 利用し続けても、アクセストークンは発行から一定時間が経過すると無効になる
+	clock.Advance(4 * time.Minute)
+	if _, ok := tokens.GetUserId(pair.AccessToken); !ok {
+		t.Error()
+	}
+	clock.Advance(time.Minute)
+	if _, ok := tokens.GetUserId(pair.AccessToken); ok {
+		t.Error()
+	}
+
+	// リフレッシュトークンはアクセスに使用できない
+	if _, ok := tokens.GetUserId(pair.RefreshToken); ok {
+		t.Error()
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	config := security.SessionConfig{AbsoluteTimeout: 60 * time.Minute, IdleTimeout: 30 * time.Minute, AccessTokenTimeout: 5 * time.Minute, ReapInterval: time.Minute}
+
+	t.Run("新しいトークンの組が発行される", func(t *testing.T) {
+		tokens, clock := newTestTokensWithConfig(config)
+		userId := users.NewUserId(1)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		clock.Advance(10 * time.Minute)
+		refreshed, err := tokens.Refresh(pair.RefreshToken, testClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refreshed.AccessToken == pair.AccessToken || refreshed.RefreshToken == pair.RefreshToken {
+			t.Error()
+		}
+		if id, ok := tokens.GetUserId(refreshed.AccessToken); !ok || !id.Equals(userId) {
+			t.Error()
+		}
+	})
+
+	t.Run("アクセストークンではリフレッシュできない", func(t *testing.T) {
+		tokens, _ := newTestTokensWithConfig(config)
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		if _, err := tokens.Refresh(pair.AccessToken, testClient); err != security.ErrInvalidRefreshToken {
+			t.Error(err)
+		}
+	})
+
+	t.Run("使用済みのリフレッシュトークンが再使用されると、同じサインインのトークンがすべて無効になる", func(t *testing.T) {
+		tokens, _ := newTestTokensWithConfig(config)
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		other, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		refreshed, _ := tokens.Refresh(pair.RefreshToken, testClient)
+
+		if _, err := tokens.Refresh(pair.RefreshToken, testClient); err != security.ErrRefreshTokenReused {
+			t.Error(err)
+		}
+		if _, ok := tokens.GetUserId(refreshed.AccessToken); ok {
+			t.Error("access token of the reused family is still valid")
+		}
+		if _, err := tokens.Refresh(refreshed.RefreshToken, testClient); err != security.ErrInvalidRefreshToken {
+			t.Error(err)
+		}
+		// 別のサインインで発行されたトークンは無効にならない
+		if _, ok := tokens.GetUserId(other.AccessToken); !ok {
+			t.Error("access token of another family was revoked")
+		}
+	})
+
+	t.Run("同時にリフレッシュされても、成功するのは一度だけ", func(t *testing.T) {
+		tokens, _ := newTestTokensWithConfig(config)
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		var wg sync.WaitGroup
+		var mutex sync.Mutex
+		succeeded := 0
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := tokens.Refresh(pair.RefreshToken, testClient); err == nil {
+					mutex.Lock()
+					succeeded++
+					mutex.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if succeeded != 1 {
+			t.Error(succeeded)
+		}
+	})
+
+	t.Run("サインインからの有効期限を過ぎるとリフレッシュできない", func(t *testing.T) {
+		tokens, clock := newTestTokensWithConfig(config)
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		var err error
+		for i := 0; i < 3; i++ {
+			clock.Advance(20 * time.Minute)
+			pair, err = tokens.Refresh(pair.RefreshToken, testClient)
+			if i < 2 && err != nil {
+				t.Fatal(i, err)
+			}
+		}
+		if err != security.ErrInvalidRefreshToken {
+			t.Error(err)
+		}
+	})
+
+	t.Run("サインアウトすると、リフレッシュトークンも無効になる", func(t *testing.T) {
+		tokens, _ := newTestTokensWithConfig(config)
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		tokens.Invalidate(pair.AccessToken)
+		if _, err := tokens.Refresh(pair.RefreshToken, testClient); err != security.ErrInvalidRefreshToken {
+			t.Error(err)
+		}
+	})
+}
+
+func TestSignedTokens(t *testing.T) {
+	config := security.SessionConfig{AbsoluteTimeout: 60 * time.Minute, IdleTimeout: 30 * time.Minute, AccessTokenTimeout: 5 * time.Minute, ReapInterval: time.Minute}
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	signer, err := security.NewTokenSigner("hmac", []*security.SigningKey{newTestHMACKey(t, "hmac")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId := users.NewUserId(1)
+
+	t.Run("ストアを共有していなくても、同じ鍵をもつサーバで検証できる", func(t *testing.T) {
+		issuer := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		verifier := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		pair, err := issuer.GenereteToken(userId, testClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, ok := verifier.GetUserId(pair.AccessToken); !ok || !id.Equals(userId) {
+			t.Error()
+		}
+	})
+
+	t.Run("アクセストークンは有効期限が切れると無効になる", func(t *testing.T) {
+		tokens := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		clock.Advance(5 * time.Minute)
+		if _, ok := tokens.GetUserId(pair.AccessToken); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("リフレッシュすると署名付きのアクセストークンが発行される", func(t *testing.T) {
+		tokens := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		refreshed, err := tokens.Refresh(pair.RefreshToken, testClient)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, ok := tokens.GetUserId(refreshed.AccessToken); !ok || !id.Equals(userId) {
+			t.Error()
+		}
+	})
+
+	t.Run("署名付きのアクセストークンは発行時刻を返却する", func(t *testing.T) {
+		tokens := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		if _, issuedAt, ok := tokens.Authenticate(pair.AccessToken); !ok || !issuedAt.Equal(clock.Now().Truncate(time.Second)) {
+			t.Error(issuedAt)
+		}
+	})
+
+	t.Run("サインアウトすると、リフレッシュトークンが無効になる", func(t *testing.T) {
+		tokens := security.NewSignedTokens(security.NewMemorySessionStore(), config, clock, signer)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		if err := tokens.Invalidate(pair.AccessToken); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tokens.Refresh(pair.RefreshToken, testClient); err != security.ErrInvalidRefreshToken {
+			t.Error(err)
+		}
+	})
+}
+
+func TestListSessions(t *testing.T) {
+	t.Run("サインインごとにまとめられ、最後に利用したクライアントの情報になる", func(t *testing.T) {
+		tokens, clock := newTestTokens()
+		userId := users.NewUserId(1)
+		first, _ := tokens.GenereteToken(userId, testClient)
+		clock.Advance(time.Minute)
+		second, _ := tokens.GenereteToken(userId, testClient)
+		tokens.GenereteToken(users.NewUserId(2), testClient)
+		clock.Advance(time.Minute)
+		phone := security.NewClientInfo("198.51.100.1:443", "phone")
+		tokens.Refresh(first.RefreshToken, phone)
+
+		infos, err := tokens.ListSessions(userId)
+		if err != nil || len(infos) != 2 {
+			t.Fatal(infos, err)
+		}
+		firstId, _ := tokens.GetFamilyId(first.AccessToken)
+		secondId, _ := tokens.GetFamilyId(second.AccessToken)
+		if infos[0].FamilyId != firstId || infos[0].Client != phone || !infos[0].LastUsedAt.Equal(clock.Now()) {
+			t.Error(infos[0])
+		}
+		if infos[1].FamilyId != secondId || infos[1].Client.RemoteAddr != "192.0.2.1" {
+			t.Error(infos[1])
+		}
+	})
+
+	t.Run("有効期限が切れたセッションは含まれない", func(t *testing.T) {
+		tokens, clock := newTestTokens()
+		userId := users.NewUserId(1)
+		tokens.GenereteToken(userId, testClient)
+		clock.Advance(30 * time.Minute)
+		if infos, _ := tokens.ListSessions(userId); len(infos) != 0 {
+			t.Error(infos)
+		}
+	})
+}
+
+func TestRevokeSessions(t *testing.T) {
+	t.Run("指定したセッションのみ無効化できる", func(t *testing.T) {
+		tokens, _ := newTestTokens()
+		userId := users.NewUserId(1)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		other, _ := tokens.GenereteToken(userId, testClient)
+		familyId, _ := tokens.GetFamilyId(pair.AccessToken)
+		if err := tokens.RevokeSession(userId, familyId); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tokens.GetUserId(pair.AccessToken); ok {
+			t.Error()
+		}
+		if _, ok := tokens.GetUserId(other.AccessToken); !ok {
+			t.Error()
+		}
+	})
+
+	t.Run("ほかのユーザのセッションは無効化できない", func(t *testing.T) {
+		tokens, _ := newTestTokens()
+		pair, _ := tokens.GenereteToken(users.NewUserId(1), testClient)
+		familyId, _ := tokens.GetFamilyId(pair.AccessToken)
+		if err := tokens.RevokeSession(users.NewUserId(2), familyId); !errors.Is(err, security.ErrSessionNotFound) {
+			t.Error(err)
+		}
+		if _, ok := tokens.GetUserId(pair.AccessToken); !ok {
+			t.Error()
+		}
+	})
+
+	t.Run("ユーザのセッションをすべて無効化すると、リフレッシュもできなくなる", func(t *testing.T) {
+		tokens, _ := newTestTokens()
+		userId := users.NewUserId(1)
+		pair, _ := tokens.GenereteToken(userId, testClient)
+		refreshed, _ := tokens.Refresh(pair.RefreshToken, testClient)
+		stranger, _ := tokens.GenereteToken(users.NewUserId(2), testClient)
+		if err := tokens.RevokeAllSessions(userId); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := tokens.GetUserId(refreshed.AccessToken); ok {
+			t.Error()
+		}
+		if _, err := tokens.Refresh(refreshed.RefreshToken, testClient); !errors.Is(err, security.ErrInvalidRefreshToken) {
+			t.Error(err)
+		}
+		if _, ok := tokens.GetUserId(stranger.AccessToken); !ok {
+			t.Error()
+		}
+	})
+
+	t.Run("リフレッシュと同時に無効化されても、トークンは残らない", func(t *testing.T) {
+		tokens, _ := newTestTokens()
+		userId := users.NewUserId(1)
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			pair, _ := tokens.GenereteToken(userId, testClient)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tokens.Refresh(pair.RefreshToken, testClient)
+			}()
+		}
+		tokens.RevokeAllSessions(userId)
+		wg.Wait()
+		// 無効化より後に発行が完了したトークンは、リフレッシュ元が削除されていることを検知して無効化される。
+		if infos, _ := tokens.ListSessions(userId); len(infos) != 0 {
+			t.Error(len(infos))
+		}
+	})
+
+	t.Run("現在のセッション以外をすべて無効化できる", func(t *testing.T) {
+		tokens, _ := newTestTokens()
+		userId := users.NewUserId(1)
+		current, _ := tokens.GenereteToken(userId, testClient)
+		other1, _ := tokens.GenereteToken(userId, testClient)
+		other2, _ := tokens.GenereteToken(userId, testClient)
+		stranger, _ := tokens.GenereteToken(users.NewUserId(2), testClient)
+		familyId, _ := tokens.GetFamilyId(current.AccessToken)
+		if err := tokens.RevokeOtherSessions(userId, familyId); err != nil {
+			t.Fatal(err)
+		}
+		for _, pair := range []*security.TokenPair{other1, other2} {
+			if _, ok := tokens.GetUserId(pair.AccessToken); ok {
+				t.Error()
+			}
+		}
+		for _, pair := range []*security.TokenPair{current, stranger} {
+			if _, ok := tokens.GetUserId(pair.AccessToken); !ok {
+				t.Error()
+			}
+		}
+	})
+}
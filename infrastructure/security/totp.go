@@ -0,0 +1,110 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// totpPeriod は、TOTPの時間ステップの長さです。
+	totpPeriod = 30 * time.Second
+	// totpDigits は、TOTPのコードの桁数です。
+	totpDigits = 6
+	// totpSkew は、時計のずれを考慮して、前後に許容する時間ステップの数です。
+	totpSkew = 1
+	// totpSecretLength は、TOTPの共有鍵のバイト長です。RFC 4226で推奨されている160ビットです。
+	totpSecretLength = 20
+)
+
+// totpEncoding は、TOTPの共有鍵のエンコーディングです。認証アプリに合わせて、パディングなしのBase32を用います。
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Totp は、RFC 6238のTOTP(HMAC-SHA1、6桁、30秒)のコードを生成・検証する構造体です。
+type Totp struct {
+	clock IClock
+}
+
+// GenerateSecret は、新しい共有鍵を生成し、Base32で返却します。
+func (totp *Totp) GenerateSecret() (secret string, err error) {
+	bytes := make([]byte, totpSecretLength)
+	if _, err = rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(bytes), nil
+}
+
+// GetURI は、認証アプリに登録するためのotpauth://形式のURIを返却します。
+func (totp *Totp) GetURI(issuer string, accountName string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(accountName)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Verify は、コードが現在の時刻の前後の時間ステップのいずれかのコードと一致するかを検証し、一致した時間ステップを返却します。
+// lastUsedStep以前の時間ステップのコードは、再使用とみなして受け付けません。
+func (totp *Totp) Verify(secret string, code string, lastUsedStep int64) (step int64, ok bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := totp.GetStep(totp.clock.Now())
+	for step = current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(generateTotpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GetCode は、時刻におけるコードを返却します。
+func (totp *Totp) GetCode(secret string, t time.Time) (code string, err error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	return generateTotpCode(key, totp.GetStep(t)), nil
+}
+
+// GetStep は、時刻の時間ステップを返却します。
+func (totp *Totp) GetStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// generateTotpCode は、RFC 4226のHOTPのアルゴリズムで、時間ステップのコードを生成します。
+func generateTotpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// 動的切り捨て
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo)
+}
+
+// NewTotp は、Totp構造体を初期化し、返却します。
+func NewTotp(clock IClock) *Totp {
+	return &Totp{clock: clock}
+}
@@ -0,0 +1,73 @@
+package security_test
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret は、RFC 6238の付録Bのテストベクタで使用されているSHA-1の共有鍵です。
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTotpGetCode(t *testing.T) {
+	// RFC 6238の付録Bのテストベクタ(8桁)の下6桁
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+	totp := security.NewTotp(security.SystemClock{})
+	for _, testCase := range testCases {
+		code, err := totp.GetCode(rfc6238Secret, time.Unix(testCase.unix, 0))
+		if err != nil || code != testCase.code {
+			t.Error(testCase.unix, code, err)
+		}
+	}
+}
+
+func TestTotpVerify(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1234567890, 0)}
+	totp := security.NewTotp(clock)
+
+	t.Run("前後の時間ステップのコードを受け付ける", func(t *testing.T) {
+		for _, offset := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+			code, _ := totp.GetCode(rfc6238Secret, clock.Now().Add(offset))
+			if _, ok := totp.Verify(rfc6238Secret, code, 0); !ok {
+				t.Error(offset)
+			}
+		}
+		code, _ := totp.GetCode(rfc6238Secret, clock.Now().Add(-90*time.Second))
+		if _, ok := totp.Verify(rfc6238Secret, code, 0); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("使用済みの時間ステップのコードは受け付けない", func(t *testing.T) {
+		code, _ := totp.GetCode(rfc6238Secret, clock.Now())
+		step, ok := totp.Verify(rfc6238Secret, code, 0)
+		if !ok || step != totp.GetStep(clock.Now()) {
+			t.Fatal(step)
+		}
+		if _, ok := totp.Verify(rfc6238Secret, code, step); ok {
+			t.Error()
+		}
+	})
+
+	t.Run("生成した共有鍵でotpauth形式のURIを作成できる", func(t *testing.T) {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			t.Fatal(err)
+		}
+		uri := totp.GetURI("FrogNote", "frog", secret)
+		if !strings.HasPrefix(uri, "otpauth://totp/FrogNote:frog?") || !strings.Contains(uri, "secret="+secret) {
+			t.Error(uri)
+		}
+	})
+}
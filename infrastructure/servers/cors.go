@@ -0,0 +1,91 @@
+package servers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig は、オリジン間リソース共有(CORS)の設定を表現する構造体です。
+type CORSConfig struct {
+	// AllowedOrigins は、許可するオリジンです。"https://*.example.com"のように、サブドメインをワイルドカードで指定できます。"*"はすべてのオリジンを許可します。
+	AllowedOrigins []string
+	// AllowedMethods は、プリフライトリクエストで許可するHTTPメソッドです。
+	AllowedMethods []string
+	// AllowedHeaders は、プリフライトリクエストで許可するリクエストヘッダです。
+	AllowedHeaders []string
+	// ExposedHeaders は、JavaScriptから読み取れるようにするレスポンスヘッダです。
+	ExposedHeaders []string
+	// AllowCredentials は、クッキーやAuthorizationヘッダを含むリクエストを許可するかを表します。
+	AllowCredentials bool
+	// MaxAge は、プリフライトリクエストの結果をキャッシュできる時間です。0の場合はヘッダを設定しません。
+	MaxAge time.Duration
+}
+
+// IsOriginAllowed は、オリジンが許可されているかを判定します。
+func (config *CORSConfig) IsOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range config.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// ワイルドカードは、1つ以上のラベルからなるサブドメインに一致し、ドメイン自体には一致しない。
+		scheme, domain, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		prefix, suffix := scheme+"://", "."+domain
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) && len(origin) > len(prefix)+len(suffix) {
+			subdomain := origin[len(prefix) : len(origin)-len(suffix)]
+			if !strings.ContainsAny(subdomain, ":/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CORS は、許可されたオリジンからのリクエストに、CORSのレスポンスヘッダを設定するミドルウェアを返却します。
+// 許可されたオリジンからのプリフライトリクエストには、ハンドラを呼び出さずに204 No Contentを返却します。
+// 許可されていないオリジンからのリクエストには、ヘッダを設定せずにハンドラを呼び出すため、ブラウザがレスポンスを破棄します。
+func CORS(config CORSConfig) Middleware {
+	allowedMethods := strings.Join(config.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.FormatInt(int64(config.MaxAge/time.Second), 10)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(writer http.ResponseWriter, req *http.Request, logger *Logger) (status int, body []byte) {
+			header := writer.Header()
+			// オリジンによってレスポンスが変わるので、キャッシュがオリジンを区別するようにする。
+			header.Add("Vary", "Origin")
+			origin := req.Header.Get("Origin")
+			if !config.IsOriginAllowed(origin) {
+				return next(writer, req, logger)
+			}
+
+			header.Set("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+			isPreflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+			if !isPreflight {
+				if exposedHeaders != "" {
+					header.Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+				return next(writer, req, logger)
+			}
+
+			header.Add("Vary", "Access-Control-Request-Method")
+			header.Add("Vary", "Access-Control-Request-Headers")
+			header.Set("Access-Control-Allow-Methods", allowedMethods)
+			header.Set("Access-Control-Allow-Headers", allowedHeaders)
+			if config.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			return http.StatusNoContent, nil
+		}
+	}
+}
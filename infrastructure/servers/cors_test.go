@@ -0,0 +1,107 @@
+package servers_test
+
+import (
+	"FrogNote_database/infrastructure/servers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestCORSConfig は、テスト用のCORSの設定を返却します。
+func newTestCORSConfig() servers.CORSConfig {
+	return servers.CORSConfig{
+		AllowedOrigins:   []string{"http://localhost:5173", "https://*.frognote.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"Retry-After"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+func TestCORSConfig(t *testing.T) {
+	config := newTestCORSConfig()
+	cases := map[string]bool{
+		"http://localhost:5173":                  true,
+		"https://staging.frognote.example.com":   true,
+		"https://a.staging.frognote.example.com": true,
+		"https://frognote.example.com":           false,
+		"http://staging.frognote.example.com":    false,
+		"https://evil.com/.frognote.example.com": false,
+		"https://evilfrognote.example.com":       false,
+		"http://localhost:8080":                  false,
+		"":                                       false,
+	}
+	for origin, expected := range cases {
+		if config.IsOriginAllowed(origin) != expected {
+			t.Errorf("%sの判定が%vではありません。", origin, expected)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	called := false
+	next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+		called = true
+		return http.StatusOK, nil
+	}
+	handler := servers.CORS(newTestCORSConfig())(next)
+
+	t.Run("許可されたオリジンにはオリジンをそのまま返却する", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodGet, "/backup/list", nil)
+		req.Header.Set("Origin", "https://staging.frognote.example.com")
+		recorder := httptest.NewRecorder()
+		if status, _ := handler(recorder, req, nil); status != http.StatusOK || !called {
+			t.Fatal("ハンドラが呼び出されませんでした。")
+		}
+		header := recorder.Header()
+		if header.Get("Access-Control-Allow-Origin") != "https://staging.frognote.example.com" ||
+			header.Get("Access-Control-Allow-Credentials") != "true" ||
+			header.Get("Access-Control-Expose-Headers") != "Retry-After" ||
+			header.Get("Vary") != "Origin" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("許可されていないオリジンにはヘッダを設定しない", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/backup/list", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		recorder := httptest.NewRecorder()
+		handler(recorder, req, nil)
+		header := recorder.Header()
+		if header.Get("Access-Control-Allow-Origin") != "" || header.Get("Vary") != "Origin" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("許可されたオリジンのプリフライトリクエストにはハンドラを呼び出さずに応答する", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodOptions, "/backup/save", nil)
+		req.Header.Set("Origin", "http://localhost:5173")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		recorder := httptest.NewRecorder()
+		if status, _ := handler(recorder, req, nil); status != http.StatusNoContent || called {
+			t.Fatal("プリフライトリクエストに応答しませんでした。")
+		}
+		header := recorder.Header()
+		if header.Get("Access-Control-Allow-Methods") != "GET, POST" ||
+			header.Get("Access-Control-Allow-Headers") != "Content-Type, Authorization" ||
+			header.Get("Access-Control-Max-Age") != "600" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("許可されていないオリジンのプリフライトリクエストはハンドラに渡す", func(t *testing.T) {
+		called = false
+		req := httptest.NewRequest(http.MethodOptions, "/backup/save", nil)
+		req.Header.Set("Origin", "https://evil.example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		recorder := httptest.NewRecorder()
+		handler(recorder, req, nil)
+		if !called || recorder.Header().Get("Access-Control-Allow-Methods") != "" {
+			t.Error("許可されていないオリジンのプリフライトリクエストに応答しました。")
+		}
+	})
+}
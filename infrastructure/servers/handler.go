@@ -2,8 +2,20 @@ package servers
 
 import "net/http"
 
+// patternKey は、リクエストのコンテキストに、ハンドラのパターンを設定するためのキーです。
+type patternKey struct{}
+
+// HandlerFunc は、リクエストを処理し、ステータスコードとレスポンスボディを返却する関数の型です。
+type HandlerFunc func(http.ResponseWriter, *http.Request, *Logger) (status int, body []byte)
+
 // Handler は、ハンドラの構造を表現した構造体です。
 type Handler struct {
 	Pattern     string
-	HandlerFunc func(http.ResponseWriter, *http.Request, *Logger) (status int, body []byte)
+	HandlerFunc HandlerFunc
+}
+
+// GetPattern は、リクエストを処理しているハンドラのパターンを返却します。サーバを経由しないリクエストの場合は、空文字列を返却します。
+func GetPattern(req *http.Request) string {
+	pattern, _ := req.Context().Value(patternKey{}).(string)
+	return pattern
 }
@@ -0,0 +1,324 @@
+package admin
+
+import (
+	"FrogNote_database/domain/audits"
+	domainBackups "FrogNote_database/domain/backups"
+	domainUsers "FrogNote_database/domain/users"
+	dbBackups "FrogNote_database/infrastructure/db/backups"
+	dbUsers "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// userIdObj は、操作対象のユーザIDを表現する構造体です。
+type userIdObj struct {
+	Id int `json:"id"`
+}
+
+// backupIdObj は、操作対象のバックアップIDを表現する構造体です。
+type backupIdObj struct {
+	Value int `json:"value"`
+}
+
+// adminUserObj は、管理者に見せるユーザ情報を表現する構造体です。パスワードのハッシュ値や二要素認証の共有鍵は含めません。
+type adminUserObj struct {
+	Id               int    `json:"id"`
+	SignInId         string `json:"signInId"`
+	ScreenName       string `json:"screenName"`
+	Role             string `json:"role"`
+	Suspended        bool   `json:"suspended"`
+	TwoFactorEnabled bool   `json:"twoFactorEnabled"`
+	BackupCount      int    `json:"backupCount"`
+	StorageBytes     int64  `json:"storageBytes"`
+}
+
+// storageUsageObj は、ユーザの使用容量を表現する構造体です。
+type storageUsageObj struct {
+	UserId      int   `json:"userId"`
+	BackupCount int   `json:"backupCount"`
+	TotalBytes  int64 `json:"totalBytes"`
+}
+
+// storageObj は、全体の使用容量を表現する構造体です。
+type storageObj struct {
+	TotalBackups int               `json:"totalBackups"`
+	TotalBytes   int64             `json:"totalBytes"`
+	Users        []storageUsageObj `json:"users"`
+}
+
+// registerClientCertificateObj は、ユーザを指定したクライアント証明書の登録要求を表現する構造体です。
+type registerClientCertificateObj struct {
+	UserId int `json:"userId"`
+	handlers.RegisterClientCertificateObj
+}
+
+// backupMetaObj は、バックアップデータのメタデータを表現する構造体です。
+type backupMetaObj struct {
+	BackupId int    `json:"backupId"`
+	SavedAt  string `json:"savedAt"`
+}
+
+// ListUsers は、すべてのユーザを、使用容量とともに取得するためのハンドラです。
+func ListUsers(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	userSlice, err := dbUsers.NewUserRepository(handlers.GetDBConnector()).FindAll()
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find users")
+	}
+	usages, err := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing()).FindStorageUsages()
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find storage usages")
+	}
+	usageMap := make(map[int]*domainBackups.StorageUsage, len(usages))
+	for _, usage := range usages {
+		usageMap[usage.UserId.GetValue()] = usage
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resUsers := make([]adminUserObj, len(userSlice))
+	for i, user := range userSlice {
+		resUsers[i] = adminUserObj{
+			Id:               user.Id.GetValue(),
+			SignInId:         user.SignInId.GetValue(),
+			ScreenName:       user.ScreenName,
+			Role:             string(user.Role),
+			Suspended:        user.Suspended,
+			TwoFactorEnabled: user.TwoFactor.Enabled,
+		}
+		if usage, exists := usageMap[user.Id.GetValue()]; exists {
+			resUsers[i].BackupCount = usage.BackupCount
+			resUsers[i].StorageBytes = usage.TotalBytes
+		}
+	}
+	return marshal(resUsers, logger)
+}
+
+// GetStorage は、全体の使用容量と、ユーザごとの使用容量を多い順に取得するためのハンドラです。
+func GetStorage(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	usages, err := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing()).FindStorageUsages()
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find storage usages")
+	}
+	resStorage := storageObj{Users: make([]storageUsageObj, len(usages))}
+	for i, usage := range usages {
+		resStorage.TotalBackups += usage.BackupCount
+		resStorage.TotalBytes += usage.TotalBytes
+		resStorage.Users[i] = storageUsageObj{UserId: usage.UserId.GetValue(), BackupCount: usage.BackupCount, TotalBytes: usage.TotalBytes}
+	}
+	return marshal(resStorage, logger)
+}
+
+// SuspendUser は、アカウントを停止するためのハンドラです。停止したユーザのセッションはすべて無効になり、パーソナルアクセストークンやクライアント証明書は停止中のみ認証されなくなります。
+func SuspendUser(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	return setSuspended(req, true, logger)
+}
+
+// UnsuspendUser は、アカウントの停止を解除するためのハンドラです。
+func UnsuspendUser(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	return setSuspended(req, false, logger)
+}
+
+// setSuspended は、リクエストボディで指定されたユーザのアカウントを停止、または停止を解除します。
+func setSuspended(req *http.Request, suspended bool, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := userIdObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	targetId := domainUsers.NewUserId(parsed.Id)
+	// 管理者がいなくならないよう、自身のアカウントは停止できない。
+	currentId, _ := handlers.GetUserId(req)
+	if suspended && targetId.Equals(currentId) {
+		return http.StatusBadRequest, []byte("Could not suspend yourself")
+	}
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	target, err := repos.FindByUserId(targetId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound, []byte("User not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	err = repos.UpdateSuspended(targetId, suspended)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+	if !suspended {
+		handlers.RecordAuditEvent(req, audits.EventAccountUnsuspended, targetId, target.SignInId.GetValue(), "", logger)
+		return http.StatusOK, []byte("")
+	}
+	handlers.RecordAuditEvent(req, audits.EventAccountSuspended, targetId, target.SignInId.GetValue(), "", logger)
+
+	// 停止中のアカウントは、リクエストを認証する際に拒否される。
+	// パーソナルアクセストークンやクライアント証明書の登録は、停止を解除した際に再び使用できるよう削除せず、セッションのみを無効化する。
+	err = handlers.GetTokens().RevokeAllSessions(targetId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke sessions")
+	}
+	return http.StatusOK, []byte("")
+}
+
+// RegisterClientCertificate は、指定したユーザにクライアント証明書を登録するためのハンドラです。
+// ユーザ自身では登録できない、サブジェクトでの対応付けも登録できます。
+func RegisterClientCertificate(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := registerClientCertificateObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	targetId := domainUsers.NewUserId(parsed.UserId)
+	_, err = dbUsers.NewUserRepository(handlers.GetDBConnector()).FindByUserId(targetId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound, []byte("User not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	return handlers.RegisterClientCertificate(targetId, &parsed.RegisterClientCertificateObj, logger)
+}
+
+// ListBackups は、クエリパラメータuserIdで指定したユーザのバックアップデータのメタデータを取得するためのハンドラです。
+func ListBackups(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	userIdValue, err := strconv.Atoi(req.URL.Query().Get("userId"))
+	if err != nil {
+		return http.StatusBadRequest, []byte("'userId' must be a number")
+	}
+
+	backups, err := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing()).FindBackupMetas(domainUsers.NewUserId(userIdValue))
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find backups")
+	}
+	metas := make([]backupMetaObj, len(backups))
+	for i, backup := range backups {
+		metas[i] = backupMetaObj{BackupId: backup.BackupId.GetValue(), SavedAt: backup.SavedAt}
+	}
+	return marshal(metas, logger)
+}
+
+// DeleteBackup は、所有者にかかわらず、バックアップデータを削除するためのハンドラです。
+func DeleteBackup(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "DELETE") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := backupIdObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	backupId := domainBackups.NewBackupId(parsed.Value)
+	repos := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing())
+	backup, err := repos.FindByBackupId(backupId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return http.StatusNotFound, []byte("Backup not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find backup")
+	}
+	err = repos.DeleteByBackupId(backupId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not delete.")
+	}
+	handlers.RecordAuditEvent(req, audits.EventBackupDeleted, &backup.UserId, "", handlers.BackupAuditDetail(backupId), logger)
+	return http.StatusOK, []byte("")
+}
+
+// ListAuditEvents は、監査ログを検索するためのハンドラです。クエリパラメータuserIdでユーザを、type、since、until、limitで種類、期間、件数を指定できます。
+func ListAuditEvents(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	values := req.URL.Query()
+	query, err := handlers.ParseAuditQuery(values)
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+	if value := values.Get("userId"); value != "" {
+		userIdValue, err := strconv.Atoi(value)
+		if err != nil {
+			return http.StatusBadRequest, []byte("'userId' must be a number")
+		}
+		query.UserId = domainUsers.NewUserId(userIdValue)
+	}
+	return handlers.FindAuditEvents(query, logger)
+}
+
+// rateLimitsObj は、リクエスト数の制限で拒否したリクエストの数を表現する構造体です。
+type rateLimitsObj struct {
+	Enabled bool `json:"enabled"`
+	// Rejections は、ハンドラのパターンごとの、起動してから拒否したリクエストの数です。
+	Rejections map[string]uint64 `json:"rejections"`
+}
+
+// GetRateLimits は、リクエスト数の制限で拒否したリクエストの数を、ハンドラのパターンごとに取得するためのハンドラです。
+func GetRateLimits(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	limiter := handlers.GetRateLimiter()
+	if limiter == nil {
+		return marshal(rateLimitsObj{Rejections: map[string]uint64{}}, logger)
+	}
+	return marshal(rateLimitsObj{Enabled: true, Rejections: limiter.Rejections()}, logger)
+}
+
+// marshal は、objをJSONに変換してレスポンスとして返却します。
+func marshal(obj any, logger *servers.Logger) (status int, body []byte) {
+	json, err := json.Marshal(obj)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// GetHandlers は、ハンドラのスライスを返却します。すべてのハンドラは、管理者のみが呼び出せます。
+func GetHandlers() []servers.Handler {
+	return handlers.RequireRoleAll(domainUsers.RoleAdmin, []servers.Handler{
+		{Pattern: "/admin/users", HandlerFunc: ListUsers},
+		{Pattern: "/admin/users/suspend", HandlerFunc: SuspendUser},
+		{Pattern: "/admin/users/unsuspend", HandlerFunc: UnsuspendUser},
+		{Pattern: "/admin/certificates/register", HandlerFunc: RegisterClientCertificate},
+		{Pattern: "/admin/storage", HandlerFunc: GetStorage},
+		{Pattern: "/admin/backups", HandlerFunc: ListBackups},
+		{Pattern: "/admin/backups/delete", HandlerFunc: DeleteBackup},
+		{Pattern: "/admin/audit-events", HandlerFunc: ListAuditEvents},
+		{Pattern: "/admin/rate-limits", HandlerFunc: GetRateLimits},
+	})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"FrogNote_database/domain/audits"
+	domainBackups "FrogNote_database/domain/backups"
+	domainUsers "FrogNote_database/domain/users"
+	dbAudits "FrogNote_database/infrastructure/db/audits"
+	"FrogNote_database/infrastructure/servers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAuditLimit は、監査ログを検索する際に、件数が指定されなかった場合の最大の件数です。
+	defaultAuditLimit = 100
+	// maxAuditLimit は、監査ログを一度に取得できる最大の件数です。
+	maxAuditLimit = 1000
+	// maxAuditFieldLength は、利用者が入力したサインインIDなどを監査ログに記録する際の最大のバイト数です。
+	maxAuditFieldLength = 255
+)
+
+// auditEventObj は、監査ログのイベントを表現する構造体です。
+type auditEventObj struct {
+	Id         int64     `json:"id"`
+	Type       string    `json:"type"`
+	UserId     *int      `json:"userId"`
+	ActorId    *int      `json:"actorId"`
+	SignInId   string    `json:"signInId"`
+	RemoteAddr string    `json:"remoteAddr"`
+	UserAgent  string    `json:"userAgent"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// RecordAuditEvent は、リクエストに関するイベントを監査ログに記録します。userIdは、イベントの対象のユーザで、特定できない場合はnilです。
+// 記録に失敗した場合も、リクエストの処理は継続できるよう、エラーをログに出力するだけにします。
+func RecordAuditEvent(req *http.Request, eventType audits.EventType, userId *domainUsers.UserId, signInId string, detail string, logger *servers.Logger) {
+	client := GetClientInfo(req)
+	event := &audits.AuditEvent{
+		Type:       eventType,
+		UserId:     userId,
+		SignInId:   truncate(signInId),
+		RemoteAddr: client.RemoteAddr,
+		UserAgent:  client.UserAgent,
+		Detail:     truncate(detail),
+		CreatedAt:  time.Now(),
+	}
+	if actorId, ok := GetUserId(req); ok {
+		event.ActorId = actorId
+	}
+	err := dbAudits.NewAuditEventRepository(dbConnector).Create(event)
+	if err != nil {
+		logger.FPrintErrorLog(err, "could not record audit event")
+	}
+}
+
+// ParseAuditQuery は、クエリパラメータtype、since、until(RFC 3339)、limitから、監査ログの検索条件を組み立てます。
+func ParseAuditQuery(values url.Values) (query *audits.Query, err error) {
+	query = &audits.Query{Limit: defaultAuditLimit}
+	if value := values.Get("type"); value != "" {
+		query.Type, err = audits.NewEventType(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value := values.Get("since"); value != "" {
+		query.Since, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("'since' must be RFC 3339")
+		}
+	}
+	if value := values.Get("until"); value != "" {
+		query.Until, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("'until' must be RFC 3339")
+		}
+	}
+	if value := values.Get("limit"); value != "" {
+		query.Limit, err = strconv.Atoi(value)
+		if err != nil || query.Limit < 1 || query.Limit > maxAuditLimit {
+			return nil, errors.New("'limit' must be between 1 to 1000")
+		}
+	}
+	return query, nil
+}
+
+// FindAuditEvents は、条件に一致する監査ログのイベントを新しい順に取得し、レスポンスとして返却します。
+func FindAuditEvents(query *audits.Query, logger *servers.Logger) (status int, body []byte) {
+	events, err := dbAudits.NewAuditEventRepository(dbConnector).Find(query)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find audit events")
+	}
+	resEvents := make([]auditEventObj, len(events))
+	for i, event := range events {
+		resEvents[i] = auditEventObj{
+			Id:         event.Id,
+			Type:       string(event.Type),
+			UserId:     toUserIdValue(event.UserId),
+			ActorId:    toUserIdValue(event.ActorId),
+			SignInId:   event.SignInId,
+			RemoteAddr: event.RemoteAddr,
+			UserAgent:  event.UserAgent,
+			Detail:     event.Detail,
+			CreatedAt:  event.CreatedAt,
+		}
+	}
+	json, err := json.Marshal(resEvents)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// BackupAuditDetail は、バックアップデータに関するイベントの補足情報として、バックアップIDを記録するための文字列を返却します。
+func BackupAuditDetail(backupId *domainBackups.BackupId) string {
+	return "backupId=" + strconv.Itoa(backupId.GetValue())
+}
+
+// toUserIdValue は、nilのユーザIDをJSONのnullとして出力するために変換します。
+func toUserIdValue(userId *domainUsers.UserId) *int {
+	if userId == nil {
+		return nil
+	}
+	value := userId.GetValue()
+	return &value
+}
+
+// truncate は、valueをmaxAuditFieldLengthバイトまでに切り詰めます。
+func truncate(value string) string {
+	if len(value) > maxAuditFieldLength {
+		return strings.ToValidUTF8(value[:maxAuditFieldLength], "")
+	}
+	return value
+}
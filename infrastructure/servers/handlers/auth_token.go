@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"crypto/x509"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// AccessTokenCookieName は、クッキーモードでアクセストークンを保存するクッキーの名前です。
+	AccessTokenCookieName = "frognote_access"
+	// RefreshTokenCookieName は、クッキーモードでリフレッシュトークンを保存するクッキーの名前です。
+	RefreshTokenCookieName = "frognote_refresh"
+	// CsrfCookieName は、クッキーモードでCSRFトークンを保存するクッキーの名前です。JavaScriptから読み取れるよう、HttpOnlyにはしません。
+	CsrfCookieName = "frognote_csrf"
+	// CsrfHeaderName は、クッキーで認証する状態を変更するリクエストで、CSRFトークンを送信するヘッダの名前です。
+	CsrfHeaderName = "X-CSRF-Token"
+	// refreshTokenCookiePath は、リフレッシュトークンのクッキーを送信するパスです。リフレッシュ以外のリクエストには含めません。
+	refreshTokenCookiePath = "/user/token/refresh"
+	// authenticateRealm は、WWW-Authenticateヘッダのrealmです。
+	authenticateRealm = "FrogNote"
+)
+
+// CookieConfig は、クッキーモードで発行するクッキーの属性を表現する構造体です。
+type CookieConfig struct {
+	// Secure は、HTTPSの場合のみクッキーを送信するかを表します。
+	Secure bool
+	// SameSite は、別のサイトからのリクエストにクッキーを含めるかを表します。
+	SameSite http.SameSite
+}
+
+// cookieConfig は、クッキーモードで発行するクッキーの属性です。
+var cookieConfig = CookieConfig{Secure: true, SameSite: http.SameSiteStrictMode}
+
+// UseCookieConfig は、クッキーモードで発行するクッキーの属性を差し替えます。
+func UseCookieConfig(newConfig CookieConfig) {
+	cookieConfig = newConfig
+}
+
+// GetToken は、リクエストからアクセストークンを取り出します。Authorizationヘッダがある場合はそれを優先し、ない場合はクッキーから取り出します。
+// クッキーで認証する状態を変更するリクエストで、CSRFトークンが一致しない場合は、空文字列を返却します。
+func GetToken(req *http.Request) string {
+	if value := req.Header.Get("Authorization"); value != "" {
+		token, _ := security.ParseAuthorization(value)
+		return token
+	}
+	cookie, err := req.Cookie(AccessTokenCookieName)
+	if err != nil {
+		return ""
+	}
+	if !isSafeMethod(req.Method) && !IsValidCsrf(req) {
+		return ""
+	}
+	return cookie.Value
+}
+
+// IsValidCsrf は、CSRFトークンのクッキーとヘッダが一致する場合にtrueを返却します。
+func IsValidCsrf(req *http.Request) bool {
+	cookie, err := req.Cookie(CsrfCookieName)
+	if err != nil {
+		return false
+	}
+	return security.VerifyCsrfToken(cookie.Value, req.Header.Get(CsrfHeaderName))
+}
+
+// GetRefreshTokenCookie は、クッキーモードのリフレッシュトークンを取り出します。CSRFトークンが一致しない場合は、空文字列を返却します。
+func GetRefreshTokenCookie(req *http.Request) string {
+	cookie, err := req.Cookie(RefreshTokenCookieName)
+	if err != nil || !IsValidCsrf(req) {
+		return ""
+	}
+	return cookie.Value
+}
+
+// SetTokenCookies は、クッキーモードのために、アクセストークン、リフレッシュトークン、CSRFトークンをクッキーに設定し、CSRFトークンを返却します。
+func SetTokenCookies(writer http.ResponseWriter, pair *security.TokenPair) (csrfToken string, err error) {
+	csrfToken, err = security.NewCsrfToken()
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(writer, newCookie(AccessTokenCookieName, pair.AccessToken, "/", pair.AccessTokenExpiresAt, true))
+	http.SetCookie(writer, newCookie(RefreshTokenCookieName, pair.RefreshToken, refreshTokenCookiePath, pair.RefreshTokenExpiresAt, true))
+	http.SetCookie(writer, newCookie(CsrfCookieName, csrfToken, "/", pair.RefreshTokenExpiresAt, false))
+	return csrfToken, nil
+}
+
+// ClearTokenCookies は、クッキーモードのクッキーをすべて削除します。
+func ClearTokenCookies(writer http.ResponseWriter) {
+	expired := time.Unix(0, 0)
+	http.SetCookie(writer, newCookie(AccessTokenCookieName, "", "/", expired, true))
+	http.SetCookie(writer, newCookie(RefreshTokenCookieName, "", refreshTokenCookiePath, expired, true))
+	http.SetCookie(writer, newCookie(CsrfCookieName, "", "/", expired, false))
+}
+
+// SetInsufficientScope は、パーソナルアクセストークンのスコープが足りないことを、WWW-Authenticateヘッダに設定します。
+func SetInsufficientScope(writer http.ResponseWriter, scopes ...security.Scope) {
+	values := make([]string, len(scopes))
+	for i, scope := range scopes {
+		values[i] = string(scope)
+	}
+	writer.Header().Set("WWW-Authenticate", `Bearer realm="`+authenticateRealm+`", error="insufficient_scope", scope="`+strings.Join(values, " ")+`"`)
+}
+
+// BearerChallenge は、401 Unauthorizedを返却する際に、RFC 6750のWWW-Authenticateヘッダを設定するミドルウェアです。
+// トークンが送信されていた場合は、トークンが無効であることを表すinvalid_tokenを含めます。
+func BearerChallenge(next servers.HandlerFunc) servers.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+		status, body = next(writer, req, logger)
+		if status != http.StatusUnauthorized || writer.Header().Get("WWW-Authenticate") != "" {
+			return status, body
+		}
+		challenge := `Bearer realm="` + authenticateRealm + `"`
+		if HasToken(req) {
+			challenge += `, error="invalid_token"`
+		}
+		writer.Header().Set("WWW-Authenticate", challenge)
+		return status, body
+	}
+}
+
+// HasToken は、Authorizationヘッダ、またはアクセストークンのクッキーが送信されている場合にtrueを返却します。
+func HasToken(req *http.Request) bool {
+	if req.Header.Get("Authorization") != "" {
+		return true
+	}
+	_, err := req.Cookie(AccessTokenCookieName)
+	return err == nil
+}
+
+// HasCredentials は、アクセストークン、または検証されたクライアント証明書が送信されている場合にtrueを返却します。
+func HasCredentials(req *http.Request) bool {
+	return HasToken(req) || GetClientCertificate(req) != nil
+}
+
+// GetClientCertificate は、相互TLS認証のハンドシェイクで検証されたクライアント証明書を返却します。送信されていない場合はnilです。
+// 検証されていない証明書は信頼できないため、PeerCertificatesは参照しません。
+func GetClientCertificate(req *http.Request) *x509.Certificate {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return req.TLS.VerifiedChains[0][0]
+}
+
+// isSafeMethod は、状態を変更しないHTTPメソッドの場合にtrueを返却します。
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// newCookie は、設定された属性のクッキーを初期化し、返却します。
+func newCookie(name string, value string, path string, expiresAt time.Time, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Expires:  expiresAt,
+		HttpOnly: httpOnly,
+		Secure:   cookieConfig.Secure,
+		SameSite: cookieConfig.SameSite,
+	}
+}
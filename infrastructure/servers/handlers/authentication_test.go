@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+)
+
+// memoryAccountStatusStore は、データベースを使用せずにアカウントの状態を返却する、テスト用のストアです。
+type memoryAccountStatusStore struct {
+	mutex    sync.Mutex
+	statuses map[int]security.AccountStatus
+	// finds は、アカウントの状態を取得した回数です。
+	finds int
+}
+
+// FindAccountStatus は、ユーザのアカウントの状態を返却します。登録されていないユーザの場合は、sql.ErrNoRowsを返却します。
+func (store *memoryAccountStatusStore) FindAccountStatus(userId *dom_users.UserId) (status *security.AccountStatus, err error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.finds++
+	found, ok := store.statuses[userId.GetValue()]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &found, nil
+}
+
+// put は、ユーザのアカウントの状態を登録します。
+func (store *memoryAccountStatusStore) put(userId *dom_users.UserId, status security.AccountStatus) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.statuses[userId.GetValue()] = status
+}
+
+// useMemoryAccountStatusStore は、テストの間だけ、アカウントの状態をメモリから取得するようにします。
+func useMemoryAccountStatusStore(t *testing.T) *memoryAccountStatusStore {
+	store := &memoryAccountStatusStore{statuses: make(map[int]security.AccountStatus)}
+	handlers.UseAccountStatusStore(store)
+	// データベースへの接続を差し替えるテストに影響しないよう、既定のnilに戻す。
+	t.Cleanup(func() { handlers.UseAccountStatusStore(nil) })
+	return store
+}
+
+// newBearerRequest は、tokenをAuthorizationヘッダに設定したリクエストを返却します。
+func newBearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/backup/list", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestAuthenticationAccountStatus(t *testing.T) {
+	store := useMemoryAccountStatusStore(t)
+	previousTokens := handlers.GetTokens()
+	previousPersonalTokens := handlers.GetPersonalTokens()
+	defer handlers.UseTokens(previousTokens)
+	defer handlers.UsePersonalTokens(previousPersonalTokens)
+
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	key, _ := security.NewHMACSigningKey("test", secret)
+	signer, err := security.NewTokenSigner("test", []*security.SigningKey{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handlers.UseTokens(security.NewSignedTokens(security.NewMemorySessionStore(), security.DefaultSessionConfig(), security.SystemClock{}, signer))
+	handlers.UsePersonalTokens(security.NewPersonalTokens(security.NewMemoryPersonalTokenStore(), 0, security.SystemClock{}))
+
+	userId := dom_users.NewUserId(1)
+	pair, err := handlers.GetTokens().GenereteToken(userId, security.NewClientInfo("192.0.2.1:1234", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	personalToken, _, err := handlers.GetPersonalTokens().Create(userId, "test", []security.Scope{security.ScopeBackupRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("有効なアカウントは認証される", func(t *testing.T) {
+		store.put(userId, security.AccountStatus{})
+		if handlers.IsNotAuthenticate(newBearerRequest(pair.AccessToken)) || handlers.IsNotAuthenticate(newBearerRequest(personalToken), security.ScopeBackupRead) {
+			t.Error("認証されませんでした。")
+		}
+	})
+
+	t.Run("停止中のアカウントは、署名付きのアクセストークンやパーソナルアクセストークンでも認証されない", func(t *testing.T) {
+		store.put(userId, security.AccountStatus{Suspended: true})
+		if _, ok := handlers.GetUserId(newBearerRequest(pair.AccessToken)); ok {
+			t.Error("署名付きのアクセストークンで認証されました。")
+		}
+		if !handlers.IsNotAuthenticate(newBearerRequest(personalToken), security.ScopeBackupRead) {
+			t.Error("パーソナルアクセストークンで認証されました。")
+		}
+	})
+
+	t.Run("トークンを無効化する前に発行された署名付きのアクセストークンは認証されない", func(t *testing.T) {
+		store.put(userId, security.AccountStatus{TokensRevokedAt: time.Now().Add(time.Second)})
+		if _, ok := handlers.GetUserId(newBearerRequest(pair.AccessToken)); ok {
+			t.Error("無効化されたアクセストークンで認証されました。")
+		}
+		store.put(userId, security.AccountStatus{TokensRevokedAt: time.Now().Add(-time.Minute)})
+		if _, ok := handlers.GetUserId(newBearerRequest(pair.AccessToken)); !ok {
+			t.Error("無効化した後に発行されたアクセストークンで認証されませんでした。")
+		}
+	})
+
+	t.Run("削除されたユーザは認証されない", func(t *testing.T) {
+		if _, ok := handlers.GetUserId(newBearerRequest(pair.AccessToken)); !ok {
+			t.Fatal("認証されませんでした。")
+		}
+		store.mutex.Lock()
+		delete(store.statuses, userId.GetValue())
+		store.mutex.Unlock()
+		if _, ok := handlers.GetUserId(newBearerRequest(pair.AccessToken)); ok {
+			t.Error("削除されたユーザで認証されました。")
+		}
+	})
+}
+
+// countingPersonalTokenStore は、最終利用時刻を更新した回数を数える、テスト用のストアです。
+type countingPersonalTokenStore struct {
+	*security.MemoryPersonalTokenStore
+	touches int
+}
+
+// Touch は、最終利用時刻を更新し、回数を数えます。
+func (store *countingPersonalTokenStore) Touch(key string, lastUsedAt time.Time) error {
+	store.touches++
+	return store.MemoryPersonalTokenStore.Touch(key, lastUsedAt)
+}
+
+func TestCacheAuthentication(t *testing.T) {
+	accountStatuses := useMemoryAccountStatusStore(t)
+	previous := handlers.GetPersonalTokens()
+	defer handlers.UsePersonalTokens(previous)
+	store := &countingPersonalTokenStore{MemoryPersonalTokenStore: security.NewMemoryPersonalTokenStore()}
+	handlers.UsePersonalTokens(security.NewPersonalTokens(store, 0, security.SystemClock{}))
+
+	userId := dom_users.NewUserId(1)
+	accountStatuses.put(userId, security.AccountStatus{})
+	token, _, err := handlers.GetPersonalTokens().Create(userId, "test", []security.Scope{security.ScopeBackupRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// リクエスト数の制限とハンドラで、何度も認証する。
+	handlerFunc := handlers.CacheAuthentication(func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+		handlers.RateLimitKey(req)
+		if handlers.IsInsufficientScope(req, security.ScopeBackupRead) || handlers.IsNotAuthenticate(req, security.ScopeBackupRead) {
+			return http.StatusUnauthorized, nil
+		}
+		if _, ok := handlers.GetUserId(req); !ok {
+			return http.StatusUnauthorized, nil
+		}
+		return http.StatusOK, nil
+	})
+
+	t.Run("一つのリクエストでは、一度だけ照合して最終利用時刻を更新する", func(t *testing.T) {
+		status, _ := handlerFunc(httptest.NewRecorder(), newBearerRequest(token), servers.NewLogger())
+		if status != http.StatusOK {
+			t.Fatal(status)
+		}
+		if store.touches != 1 || accountStatuses.finds != 1 {
+			t.Error(store.touches, accountStatuses.finds)
+		}
+	})
+
+	t.Run("リクエストごとに認証し直す", func(t *testing.T) {
+		handlerFunc(httptest.NewRecorder(), newBearerRequest(token), servers.NewLogger())
+		if store.touches != 2 || accountStatuses.finds != 2 {
+			t.Error(store.touches, accountStatuses.finds)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	domainUsers "FrogNote_database/domain/users"
+	dbUsers "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/servers"
+	"net/http"
+)
+
+// RequireRole は、roleをもつユーザのみが呼び出せるように、ハンドラをラップして返却します。
+// パーソナルアクセストークンでは呼び出せません。停止中のアカウントの場合は、役割にかかわらず拒否します。
+func RequireRole(role domainUsers.Role, handlerFunc servers.HandlerFunc) servers.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+		if IsNotAuthenticate(req) {
+			return http.StatusUnauthorized, []byte("Unauthorized")
+		}
+		userId, _ := GetUserId(req)
+		repos := dbUsers.NewUserRepository(dbConnector)
+		user, err := repos.FindByUserId(userId)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("Could not find user")
+		}
+		if user.Suspended || user.Role != role {
+			return http.StatusForbidden, []byte("Forbidden")
+		}
+		return handlerFunc(writer, req, logger)
+	}
+}
+
+// RequireRoleAll は、すべてのハンドラを、roleをもつユーザのみが呼び出せるようにラップして返却します。
+func RequireRoleAll(role domainUsers.Role, handlerSlice []servers.Handler) []servers.Handler {
+	wrapped := make([]servers.Handler, len(handlerSlice))
+	for i, handler := range handlerSlice {
+		wrapped[i] = servers.Handler{Pattern: handler.Pattern, HandlerFunc: RequireRole(role, handler.HandlerFunc)}
+	}
+	return wrapped
+}
@@ -1,9 +1,10 @@
 package backups
 
 import (
+	"FrogNote_database/domain/audits"
 	domainBackups "FrogNote_database/domain/backups"
-	"FrogNote_database/infrastructure/db"
 	dbBackups "FrogNote_database/infrastructure/db/backups"
+	"FrogNote_database/infrastructure/security"
 	"FrogNote_database/infrastructure/servers"
 	"FrogNote_database/infrastructure/servers/handlers"
 	"encoding/json"
@@ -18,7 +19,11 @@ type backupId struct {
 
 // Delete は、バックアップデータ（本体・メタデータ）を削除するハンドラです。
 func Delete(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
-	if handlers.IsNotAuthenticate(req) {
+	if handlers.IsInsufficientScope(req, security.ScopeBackupWrite) {
+		handlers.SetInsufficientScope(writer, security.ScopeBackupWrite)
+		return http.StatusForbidden, []byte("Insufficient scope")
+	}
+	if handlers.IsNotAuthenticate(req, security.ScopeBackupWrite) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 	if handlers.IsNotJsonReq(req, "DELETE") {
@@ -31,7 +36,7 @@ func Delete(writer http.ResponseWriter, req *http.Request, logger *servers.Logge
 		return http.StatusInternalServerError, []byte("Could not parse json")
 	}
 
-	repos := dbBackups.NewBackupRepository(db.NewDBConnector())
+	repos := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing())
 	backup, err := repos.FindByBackupId(parsedId)
 	// バックアップデータが見つからなかった場合。
 	if err != nil {
@@ -50,12 +55,17 @@ func Delete(writer http.ResponseWriter, req *http.Request, logger *servers.Logge
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not delete.")
 	}
+	handlers.RecordAuditEvent(req, audits.EventBackupDeleted, &backup.UserId, "", handlers.BackupAuditDetail(parsedId), logger)
 	return http.StatusOK, []byte("")
 }
 
 // Save は、バックアップデータ（本体のバイナリ）を保存するハンドラです。
 func Save(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
-	if handlers.IsNotAuthenticate(req) {
+	if handlers.IsInsufficientScope(req, security.ScopeBackupWrite) {
+		handlers.SetInsufficientScope(writer, security.ScopeBackupWrite)
+		return http.StatusForbidden, []byte("Insufficient scope")
+	}
+	if handlers.IsNotAuthenticate(req, security.ScopeBackupWrite) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 	if req.Method != "POST" {
@@ -63,7 +73,7 @@ func Save(writer http.ResponseWriter, req *http.Request, logger *servers.Logger)
 	}
 
 	userId, _ := handlers.GetUserId(req)
-	repos := dbBackups.NewBackupRepository(db.NewDBConnector())
+	repos := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing())
 
 	file, _, err := req.FormFile("backup")
 	if err != nil {
@@ -87,7 +97,11 @@ func Save(writer http.ResponseWriter, req *http.Request, logger *servers.Logger)
 
 // Download は、バックアップデータ（本体のバイナリ）をダウンロードするためのハンドラです。
 func Download(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
-	if handlers.IsNotAuthenticate(req) {
+	if handlers.IsInsufficientScope(req, security.ScopeBackupRead) {
+		handlers.SetInsufficientScope(writer, security.ScopeBackupRead)
+		return http.StatusForbidden, []byte("Insufficient scope")
+	}
+	if handlers.IsNotAuthenticate(req, security.ScopeBackupRead) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 	if req.Method != "POST" {
@@ -100,7 +114,7 @@ func Download(writer http.ResponseWriter, req *http.Request, logger *servers.Log
 		return http.StatusInternalServerError, []byte("Could not parse json")
 	}
 
-	repos := dbBackups.NewBackupRepository(db.NewDBConnector())
+	repos := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing())
 	backup, err := repos.FindByBackupId(id)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
@@ -111,16 +125,23 @@ func Download(writer http.ResponseWriter, req *http.Request, logger *servers.Log
 	if !isOwner(req, backup) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
+	handlers.RecordAuditEvent(req, audits.EventBackupDownloaded, &backup.UserId, "", handlers.BackupAuditDetail(id), logger)
+	// バックアップデータはバイナリなので、JSONや文字列として扱われないようにする。
+	writer.Header().Set("Content-Type", "application/octet-stream")
 	return http.StatusOK, backup.Backup
 }
 
 // GetAllmeta は、ユーザが所有するすべてのバックアップデータのメタデータを取得するためのハンドラです。
 func GetAllmeta(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
-	if handlers.IsNotAuthenticate(req) {
+	if handlers.IsInsufficientScope(req, security.ScopeBackupRead) {
+		handlers.SetInsufficientScope(writer, security.ScopeBackupRead)
+		return http.StatusForbidden, []byte("Insufficient scope")
+	}
+	if handlers.IsNotAuthenticate(req, security.ScopeBackupRead) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 	userid, _ := handlers.GetUserId(req)
-	repos := dbBackups.NewBackupRepository(db.NewDBConnector())
+	repos := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing())
 	backups, err := repos.FindBackupMetas(userid)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
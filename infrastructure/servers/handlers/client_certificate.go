@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	domainUsers "FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// maxClientCertificateNameLength は、クライアント証明書の登録の名前の最大の文字数です。
+const maxClientCertificateNameLength = 50
+
+// RegisterClientCertificateObj は、クライアント証明書の登録要求を表現する構造体です。
+type RegisterClientCertificateObj struct {
+	Name string `json:"name"`
+	// Match は、証明書を対応付ける方法です。"subject"または"fingerprint"を指定します。
+	Match string `json:"match"`
+	// Value は、Matchに応じた、証明書のサブジェクトの識別名か、SHA-256のフィンガープリントです。
+	Value  string   `json:"value"`
+	Scopes []string `json:"scopes"`
+}
+
+// ClientCertificateObj は、クライアント証明書の登録を表現する構造体です。
+type ClientCertificateObj struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	Match      string     `json:"match"`
+	Value      string     `json:"value"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// RegisterClientCertificate は、登録要求を検証し、userIdのユーザにクライアント証明書を登録します。登録した証明書をレスポンスとして返却します。
+// 対応付ける方法を利用者が選べるかは、呼び出し元のハンドラで確認します。
+func RegisterClientCertificate(userId *domainUsers.UserId, parsed *RegisterClientCertificateObj, logger *servers.Logger) (status int, body []byte) {
+	nameLen := utf8.RuneCountInString(parsed.Name)
+	if nameLen < 1 || nameLen > maxClientCertificateNameLength {
+		return http.StatusBadRequest, []byte("'name' must be between 1 to 50 characters")
+	}
+	match, err := security.NewCertificateMatch(parsed.Match)
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+	scopes, err := ParseScopes(parsed.Scopes)
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+
+	certificate, err := GetClientCertificates().Register(userId, parsed.Name, match, parsed.Value, scopes)
+	if errors.Is(err, security.ErrInvalidClientCertificate) {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+	if errors.Is(err, security.ErrClientCertificateExists) {
+		return http.StatusConflict, []byte("Client certificate already registered")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not register client certificate")
+	}
+
+	json, err := json.Marshal(NewClientCertificateObj(certificate))
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// ParseScopes は、リクエストのスコープを変換します。重複したスコープは一つにまとめます。空の場合や、未知のスコープを含む場合は、エラーを返却します。
+func ParseScopes(values []string) (scopes []security.Scope, err error) {
+	if len(values) == 0 {
+		return nil, errors.New("'scopes' must not be empty")
+	}
+	seen := make(map[security.Scope]bool)
+	for _, value := range values {
+		scope, err := security.NewScope(value)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// NewClientCertificateObj は、レスポンス用のクライアント証明書の登録のオブジェクトを組み立てます。最終利用時刻がない場合は、nullになります。
+func NewClientCertificateObj(certificate *security.ClientCertificate) ClientCertificateObj {
+	obj := ClientCertificateObj{
+		Id:        certificate.Id,
+		Name:      certificate.Name,
+		Match:     string(certificate.Match),
+		Value:     certificate.Value,
+		Scopes:    make([]string, len(certificate.Scopes)),
+		CreatedAt: certificate.CreatedAt,
+	}
+	for i, scope := range certificate.Scopes {
+		obj.Scopes[i] = string(scope)
+	}
+	if !certificate.LastUsedAt.IsZero() {
+		obj.LastUsedAt = &certificate.LastUsedAt
+	}
+	return obj
+}
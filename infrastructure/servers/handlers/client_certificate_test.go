@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/users"
+)
+
+// newVerifiedRequest は、相互TLS認証のハンドシェイクでcertificateが検証されたリクエストを返却します。certificateがnilの場合は、HTTPSのみのリクエストです。
+func newVerifiedRequest(certificate *x509.Certificate) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "https://localhost/backup/list", nil)
+	req.TLS = &tls.ConnectionState{}
+	if certificate != nil {
+		req.TLS.PeerCertificates = []*x509.Certificate{certificate}
+		req.TLS.VerifiedChains = [][]*x509.Certificate{{certificate}}
+	}
+	return req
+}
+
+// newClientCertificate は、サブジェクトのCNがcommonNameのクライアント証明書を生成します。
+func newClientCertificate(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func TestClientCertificateAuthentication(t *testing.T) {
+	previous := handlers.GetClientCertificates()
+	defer handlers.UseClientCertificates(previous)
+	certificates := security.NewClientCertificates(security.NewMemoryClientCertificateStore(), security.SystemClock{})
+	handlers.UseClientCertificates(certificates)
+	accountStatuses := useMemoryAccountStatusStore(t)
+
+	userId := dom_users.NewUserId(1)
+	accountStatuses.put(userId, security.AccountStatus{})
+	certificate := newClientCertificate(t, "backup-host")
+	_, err := certificates.Register(userId, "backup-host", security.CertificateMatchFingerprint, security.CertificateFingerprint(certificate), []security.Scope{security.ScopeBackupRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("登録したクライアント証明書で認証できる", func(t *testing.T) {
+		req := newVerifiedRequest(certificate)
+		if id, ok := handlers.GetUserId(req); !ok || !id.Equals(userId) {
+			t.Error("ユーザIDを取得できません。")
+		}
+		if handlers.IsNotAuthenticate(req, security.ScopeBackupRead) || handlers.IsInsufficientScope(req, security.ScopeBackupRead) {
+			t.Error("認証されませんでした。")
+		}
+		if !handlers.HasCredentials(req) {
+			t.Error()
+		}
+	})
+
+	t.Run("スコープが足りない場合やスコープを指定しない場合は認証されない", func(t *testing.T) {
+		req := newVerifiedRequest(certificate)
+		if !handlers.IsNotAuthenticate(req, security.ScopeBackupWrite) || !handlers.IsInsufficientScope(req, security.ScopeBackupWrite) {
+			t.Error("スコープが足りないのに認証されました。")
+		}
+		// パスワードの変更など、セッションでのみ行える操作はできない。
+		if !handlers.IsNotAuthenticate(req) {
+			t.Error("スコープを指定しない操作で認証されました。")
+		}
+	})
+
+	t.Run("登録されていない証明書や検証されていない証明書では認証されない", func(t *testing.T) {
+		if _, ok := handlers.GetUserId(newVerifiedRequest(newClientCertificate(t, "backup-host"))); ok {
+			t.Error("登録されていない証明書で認証されました。")
+		}
+		req := newVerifiedRequest(nil)
+		req.TLS.PeerCertificates = []*x509.Certificate{certificate}
+		if _, ok := handlers.GetUserId(req); ok || handlers.HasCredentials(req) {
+			t.Error("検証されていない証明書で認証されました。")
+		}
+	})
+
+	t.Run("停止中のアカウントは、登録したクライアント証明書でも認証されない", func(t *testing.T) {
+		accountStatuses.put(userId, security.AccountStatus{Suspended: true})
+		defer accountStatuses.put(userId, security.AccountStatus{})
+		req := newVerifiedRequest(certificate)
+		if _, ok := handlers.GetUserId(req); ok || !handlers.IsNotAuthenticate(req, security.ScopeBackupRead) {
+			t.Error("停止中のアカウントで認証されました。")
+		}
+	})
+
+	t.Run("アクセストークンが送信された場合は、クライアント証明書より優先する", func(t *testing.T) {
+		req := newVerifiedRequest(certificate)
+		req.Header.Set("Authorization", "Bearer invalid")
+		if _, ok := handlers.GetUserId(req); ok || !handlers.IsNotAuthenticate(req, security.ScopeBackupRead) {
+			t.Error("無効なアクセストークンで認証されました。")
+		}
+	})
+}
+
+// postRegisterClientCertificate は、アクセストークンで認証した/user/certificates/registerへのリクエストを送信し、ステータスコードを返却します。
+func postRegisterClientCertificate(accessToken string, match string, value string) int {
+	body := fmt.Sprintf(`{"name": "backup-host", "match": %q, "value": %q, "scopes": ["backup:read"]}`, match, value)
+	req := httptest.NewRequest(http.MethodPost, "/user/certificates/register", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	status, _ := users.RegisterClientCertificate(httptest.NewRecorder(), req, servers.NewLogger())
+	return status
+}
+
+func TestRegisterClientCertificate(t *testing.T) {
+	previous := handlers.GetClientCertificates()
+	defer handlers.UseClientCertificates(previous)
+	certificates := security.NewClientCertificates(security.NewMemoryClientCertificateStore(), security.SystemClock{})
+	handlers.UseClientCertificates(certificates)
+	accountStatuses := useMemoryAccountStatusStore(t)
+
+	userId := dom_users.NewUserId(1)
+	accountStatuses.put(userId, security.AccountStatus{})
+	pair, err := handlers.GetTokens().GenereteToken(userId, security.NewClientInfo("192.0.2.1:1234", "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := newClientCertificate(t, "backup-host")
+
+	t.Run("ユーザは、サブジェクトで証明書を登録できない", func(t *testing.T) {
+		if status := postRegisterClientCertificate(pair.AccessToken, "subject", security.CertificateSubject(certificate)); status != http.StatusForbidden {
+			t.Error(status)
+		}
+		if _, ok := handlers.GetUserId(newVerifiedRequest(certificate)); ok {
+			t.Error("サブジェクトで登録した証明書で認証されました。")
+		}
+	})
+
+	t.Run("ユーザは、フィンガープリントで証明書を登録できる", func(t *testing.T) {
+		if status := postRegisterClientCertificate(pair.AccessToken, "fingerprint", security.CertificateFingerprint(certificate)); status != http.StatusOK {
+			t.Fatal(status)
+		}
+		if id, ok := handlers.GetUserId(newVerifiedRequest(certificate)); !ok || !id.Equals(userId) {
+			t.Error("登録した証明書で認証されませんでした。")
+		}
+	})
+}
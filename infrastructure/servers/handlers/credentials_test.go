@@ -0,0 +1,214 @@
+// handlers_test は、ハンドラのテスト用パッケージです。テスト用データベースを使用する結合テストにあたります。
+package handlers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	inf_backups "FrogNote_database/infrastructure/db/backups"
+	inf_users "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/admin"
+	"FrogNote_database/infrastructure/servers/handlers/backups"
+	"FrogNote_database/infrastructure/servers/handlers/users"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// TestDBConnector は、テスト用データベースに接続するための構造体です。
+type TestDBConnector struct {
+	dbConfig string
+}
+
+// Connect は、テスト用データベースに接続します。
+func (connector *TestDBConnector) Connect() (database *sql.DB, err error) {
+	database, err = sql.Open("mysql", connector.dbConfig)
+	if err != nil {
+		return nil, err
+	}
+	err = database.Ping()
+	if err != nil {
+		database.Close()
+		return nil, err
+	}
+	return
+}
+
+// NewTestDBConnector は、TestDBConnector構造体を初期化し、返却します。
+func NewTestDBConnector() *TestDBConnector {
+	pass := os.Getenv("FROGNOTE_DB_TESTER_PASS")
+	dbconf := fmt.Sprintf("frognote_db_tester:%s@tcp(localhost:3306)/frognote_test?charset=utf8mb4", pass)
+	return &TestDBConnector{dbConfig: dbconf}
+}
+
+// destructiveHandlers は、テスト用のユーザやバックアップデータを削除するため、最後に呼び出すハンドラのパターンです。
+var destructiveHandlers = map[string]bool{
+	"/backup/delete":        true,
+	"/admin/backups/delete": true,
+	"/user/leave":           true,
+}
+
+// credentialFixture は、レスポンスに含まれてはならない認証情報を保存したユーザです。
+type credentialFixture struct {
+	userId   *dom_users.UserId
+	signInId string
+	password string
+	backupId int
+	// secrets は、どのレスポンスにも含まれてはならない値です。
+	secrets map[string]string
+}
+
+// TestNoCredentialsInResponses は、登録されたすべてのハンドラを呼び出し、レスポンスに認証情報が含まれないことを確認します。
+func TestNoCredentialsInResponses(t *testing.T) {
+	connector := NewTestDBConnector()
+	database, err := connector.Connect()
+	if err != nil {
+		t.Skip("テスト用データベースに接続できません。", err)
+	}
+	database.Close()
+
+	// エラーログがパッケージのディレクトリに出力されないようにする。
+	workDir, _ := os.Getwd()
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(workDir)
+
+	masterKey, _ := security.NewMasterKey("test-1", []byte("frognote-test-master-key-1-32byt"))
+	keyRing, _ := security.NewKeyRing("test-1", []*security.MasterKey{masterKey})
+	handlers.UseDBConnector(connector)
+	handlers.UseKeyRing(keyRing)
+	fixture := newCredentialFixture(t, connector, keyRing)
+
+	var registered []servers.Handler
+	registered = append(registered, users.GetHandlers()...)
+	registered = append(registered, backups.GetHandlers()...)
+	registered = append(registered, admin.GetHandlers()...)
+	sort.SliceStable(registered, func(i, j int) bool {
+		return !destructiveHandlers[registered[i].Pattern] && destructiveHandlers[registered[j].Pattern]
+	})
+
+	logger := servers.NewLogger()
+	for _, handler := range registered {
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete, http.MethodPatch} {
+			req, pair := newFixtureRequest(t, method, handler.Pattern, fixture)
+			recorder := httptest.NewRecorder()
+			status, body := handler.HandlerFunc(recorder, req, logger)
+
+			secrets := map[string]string{"アクセストークン": pair.AccessToken, "リフレッシュトークン": pair.RefreshToken}
+			for name, secret := range fixture.secrets {
+				secrets[name] = secret
+			}
+			response := string(body) + fmt.Sprint(recorder.Header())
+			for name, secret := range secrets {
+				if strings.Contains(response, secret) {
+					t.Errorf("%s %s (%d) のレスポンスに%sが含まれています。", method, handler.Pattern, status, name)
+				}
+			}
+			if strings.Contains(strings.ToLower(string(body)), `"password":`) {
+				t.Errorf("%s %s (%d) のレスポンスにpasswordが含まれています。", method, handler.Pattern, status)
+			}
+		}
+	}
+}
+
+// newCredentialFixture は、パスワード、二要素認証の共有鍵、リカバリーコード、パーソナルアクセストークン、暗号化したバックアップデータをもつ管理者を作成します。
+func newCredentialFixture(t *testing.T, connector *TestDBConnector, keyRing *security.KeyRing) *credentialFixture {
+	fixture := &credentialFixture{
+		signInId: fmt.Sprintf("leak_%d", time.Now().UnixNano()%1e12),
+		password: "Leak-Test-Password-1",
+		secrets:  make(map[string]string),
+	}
+	signInId, _ := dom_users.NewSignInId(fixture.signInId)
+	userRepos := inf_users.NewUserRepository(connector)
+	user, err := userRepos.Create(signInId, fixture.password, "leak_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture.userId = &user.Id
+	// 途中で失敗した場合も、テスト用のユーザが残らないようにする。
+	t.Cleanup(func() { userRepos.Delete(signInId) })
+	fixture.secrets["パスワード"] = fixture.password
+	fixture.secrets["パスワードのハッシュ値"] = user.Password
+	fixture.secrets["パスワードのハッシュ値の一部"] = user.Password[strings.LastIndex(user.Password, "$")+1:]
+
+	database, err := connector.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	// 管理用のAPIも呼び出せるよう、管理者にする。
+	if _, err = database.Exec("update users set role = 'admin' where id = ?", user.Id.GetValue()); err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := security.NewTotp(security.SystemClock{}).GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = userRepos.UpdateTwoFactor(fixture.userId, &dom_users.TwoFactor{Secret: secret, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	fixture.secrets["二要素認証の共有鍵"] = secret
+
+	codes, hashes, err := security.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = userRepos.ReplaceRecoveryCodes(fixture.userId, hashes); err != nil {
+		t.Fatal(err)
+	}
+	fixture.secrets["リカバリーコード"] = codes[0]
+	fixture.secrets["リカバリーコードのハッシュ値"] = hashes[0]
+
+	token, _, err := handlers.GetPersonalTokens().Create(fixture.userId, "leak", []security.Scope{security.ScopeUserRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixture.secrets["パーソナルアクセストークン"] = token
+
+	if err = inf_backups.NewBackupRepository(connector, keyRing).Create(fixture.userId, []byte(`{"note": "frog"}`)); err != nil {
+		t.Fatal(err)
+	}
+	var dataKey, userKey []byte
+	row := database.QueryRow("select id, data_key from backups where user_id = ?", user.Id.GetValue())
+	if err = row.Scan(&fixture.backupId, &dataKey); err != nil {
+		t.Fatal(err)
+	}
+	row = database.QueryRow("select wrapped_key from user_keys where user_id = ?", user.Id.GetValue())
+	if err = row.Scan(&userKey); err != nil {
+		t.Fatal(err)
+	}
+	fixture.secrets["ラップしたデータ鍵"] = base64.StdEncoding.EncodeToString(dataKey)
+	fixture.secrets["ラップしたユーザの鍵"] = base64.StdEncoding.EncodeToString(userKey)
+	return fixture
+}
+
+// newFixtureRequest は、ユーザのセッションのアクセストークンで認証された、すべてのハンドラの入力を含むリクエストを返却します。
+func newFixtureRequest(t *testing.T, method string, pattern string, fixture *credentialFixture) (*http.Request, *security.TokenPair) {
+	pair, err := handlers.GetTokens().GenereteToken(fixture.userId, security.NewClientInfo("192.0.2.1:1234", "leak-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"signInId": %q, "password": %q, "currentPassword": %q, "newPassword": %q, "screenName": "leak_test", "keepCurrentSession": true, "value": %d, "refreshToken": %q, "name": "leak", "scopes": ["user:read"]}`,
+		fixture.signInId, fixture.password, fixture.password, fixture.password, fixture.backupId, pair.RefreshToken)
+	url := fmt.Sprintf("%s?userId=%d", pattern, fixture.userId.GetValue())
+	req := httptest.NewRequest(method, url, bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	return req, pair
+}
@@ -2,25 +2,197 @@ package handlers
 
 import (
 	domainUsers "FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/db"
+	dbUsers "FrogNote_database/infrastructure/db/users"
 	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"sync"
+	"time"
 )
 
+var (
+	// tokens は、ハンドラがトークンの生成・検証に使用するTokensです。セッションの保存先はISessionStoreとして差し替えられます。
+	tokens = security.NewTokens(security.NewMemorySessionStore(), security.DefaultSessionConfig(), security.SystemClock{})
+	// challenges は、チャレンジ&レスポンス認証のナンスを発行・消費するChallengesです。
+	challenges = security.NewChallenges(time.Minute, security.SystemClock{})
+	// twoFactorChallenges は、パスワードを照合したユーザに、二要素認証のコードの入力を求めるためのトークンを発行・消費するChallengesです。
+	twoFactorChallenges = security.NewChallenges(5*time.Minute, security.SystemClock{})
+	// lockout は、認証の失敗を記録し、失敗が続いたサインインIDやリモートアドレスをロックアウトするLockoutです。
+	lockout = security.NewLockout(security.NewMemoryAttemptStore(), security.DefaultLockoutConfig(), security.SystemClock{})
+	// personalTokens は、スクリプトなどから使用するパーソナルアクセストークンの発行・検証を行うPersonalTokensです。
+	personalTokens = security.NewPersonalTokens(security.NewMemoryPersonalTokenStore(), 0, security.SystemClock{})
+	// clientCertificates は、相互TLS認証で検証されたクライアント証明書をユーザに対応付けるClientCertificatesです。
+	clientCertificates = security.NewClientCertificates(security.NewMemoryClientCertificateStore(), security.SystemClock{})
+	// accountStatusStore は、リクエストを認証する際に、アカウントの状態を取得するストアです。nilの場合は、データベースのユーザから取得します。
+	accountStatusStore security.IAccountStatusStore
+	// policy は、ユーザの作成時やパスワードの変更時に確認する、パスワードとサインインIDのポリシーです。
+	policy = domainUsers.DefaultPolicy()
+	// keyRing は、バックアップデータの暗号化に使用するマスター鍵のキーリングです。nilの場合は、バックアップデータを暗号化しません。
+	keyRing *security.KeyRing
+	// dbConnector は、ハンドラがリポジトリを作成する際に使用するデータベースへの接続です。
+	dbConnector db.IDBConnector = db.NewDBConnector()
+	// rateLimiter は、リクエスト数を制限するRateLimiterです。nilの場合は、制限しません。
+	rateLimiter *servers.RateLimiter
+)
+
+// UseTokens は、ハンドラがトークンの生成・検証に使用するTokensを差し替えます。
+func UseTokens(newTokens *security.Tokens) {
+	tokens = newTokens
+}
+
+// GetTokens は、ハンドラがトークンの生成・検証に使用するTokensを返却します。
+func GetTokens() *security.Tokens {
+	return tokens
+}
+
+// UseChallenges は、チャレンジ&レスポンス認証に使用するChallengesを差し替えます。
+func UseChallenges(newChallenges *security.Challenges) {
+	challenges = newChallenges
+}
+
+// GetChallenges は、チャレンジ&レスポンス認証に使用するChallengesを返却します。
+func GetChallenges() *security.Challenges {
+	return challenges
+}
+
+// UseTwoFactorChallenges は、二要素認証のコードの入力を求めるためのChallengesを差し替えます。
+func UseTwoFactorChallenges(newChallenges *security.Challenges) {
+	twoFactorChallenges = newChallenges
+}
+
+// GetTwoFactorChallenges は、二要素認証のコードの入力を求めるためのChallengesを返却します。
+func GetTwoFactorChallenges() *security.Challenges {
+	return twoFactorChallenges
+}
+
+// UseLockout は、認証の失敗の記録に使用するLockoutを差し替えます。
+func UseLockout(newLockout *security.Lockout) {
+	lockout = newLockout
+}
+
+// GetLockout は、認証の失敗の記録に使用するLockoutを返却します。
+func GetLockout() *security.Lockout {
+	return lockout
+}
+
+// UsePersonalTokens は、パーソナルアクセストークンの発行・検証に使用するPersonalTokensを差し替えます。
+func UsePersonalTokens(newPersonalTokens *security.PersonalTokens) {
+	personalTokens = newPersonalTokens
+}
+
+// GetPersonalTokens は、パーソナルアクセストークンの発行・検証に使用するPersonalTokensを返却します。
+func GetPersonalTokens() *security.PersonalTokens {
+	return personalTokens
+}
+
+// UseClientCertificates は、クライアント証明書の登録・検証に使用するClientCertificatesを差し替えます。
+func UseClientCertificates(newClientCertificates *security.ClientCertificates) {
+	clientCertificates = newClientCertificates
+}
+
+// GetClientCertificates は、クライアント証明書の登録・検証に使用するClientCertificatesを返却します。
+func GetClientCertificates() *security.ClientCertificates {
+	return clientCertificates
+}
+
+// UseAccountStatusStore は、リクエストを認証する際に、アカウントの状態を取得するストアを差し替えます。
+func UseAccountStatusStore(store security.IAccountStatusStore) {
+	accountStatusStore = store
+}
+
+// GetAccountStatusStore は、リクエストを認証する際に、アカウントの状態を取得するストアを返却します。
+func GetAccountStatusStore() security.IAccountStatusStore {
+	if accountStatusStore == nil {
+		return dbUsers.NewUserRepository(dbConnector)
+	}
+	return accountStatusStore
+}
+
+// UsePolicy は、パスワードとサインインIDのポリシーを差し替えます。
+func UsePolicy(newPolicy *domainUsers.Policy) {
+	policy = newPolicy
+}
+
+// GetPolicy は、パスワードとサインインIDのポリシーを返却します。
+func GetPolicy() *domainUsers.Policy {
+	return policy
+}
+
+// UseKeyRing は、バックアップデータの暗号化に使用するキーリングを差し替えます。
+func UseKeyRing(newKeyRing *security.KeyRing) {
+	keyRing = newKeyRing
+}
+
+// GetKeyRing は、バックアップデータの暗号化に使用するキーリングを返却します。暗号化しない場合はnilです。
+func GetKeyRing() *security.KeyRing {
+	return keyRing
+}
+
+// UseDBConnector は、ハンドラがリポジトリを作成する際に使用するデータベースへの接続を差し替えます。
+func UseDBConnector(newDBConnector db.IDBConnector) {
+	dbConnector = newDBConnector
+}
+
+// GetDBConnector は、ハンドラがリポジトリを作成する際に使用するデータベースへの接続を返却します。
+func GetDBConnector() db.IDBConnector {
+	return dbConnector
+}
+
+// UseRateLimiter は、リクエスト数の制限に使用するRateLimiterを差し替えます。
+func UseRateLimiter(newRateLimiter *servers.RateLimiter) {
+	rateLimiter = newRateLimiter
+}
+
+// GetRateLimiter は、リクエスト数の制限に使用するRateLimiterを返却します。制限しない場合はnilです。
+func GetRateLimiter() *servers.RateLimiter {
+	return rateLimiter
+}
+
+// RateLimitKey は、リクエスト数の制限でクライアントを区別するキーを返却します。
+// 認証されている場合はユーザIDごと、されていない場合はリモートアドレスごとに制限します。
+func RateLimitKey(req *http.Request) string {
+	if userId, ok := GetUserId(req); ok {
+		return "user:" + strconv.Itoa(userId.GetValue())
+	}
+	return "addr:" + security.RemoteHost(req.RemoteAddr)
+}
+
+// SetRetryAfter は、再試行できるまでの秒数をRetry-Afterヘッダに設定します。1秒未満は切り上げます。
+func SetRetryAfter(writer http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int64((retryAfter + time.Second - 1) / time.Second)
+	writer.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
+
 // IsNotJsonReq は、リクエストボディがJSONであることと、想定されたHTTPメソッドかを判定します。異なった場合はtrueが返却されます。
 func IsNotJsonReq(req *http.Request, httpMethod string) bool {
 	return req.Method != httpMethod || req.Header.Get("Content-Type") != "application/json"
 }
 
-// GetUserId は、リクエストのAuthorizationヘッダをもとにユーザIDを取得します。
+// GetUserId は、リクエストのアクセストークンをもとにユーザIDを取得します。パーソナルアクセストークンの場合は、トークンを発行したユーザのIDです。
+// アクセストークンが送信されていない場合は、クライアント証明書を登録したユーザのIDです。
 func GetUserId(req *http.Request) (id *domainUsers.UserId, ok bool) {
-	tokens := security.Tokens{}
-	token := req.Header.Get("Authorization")
-	id, ok = tokens.GetUserId(token)
-	return
+	auth, ok := authenticate(req)
+	if !ok {
+		return nil, false
+	}
+	return auth.userId, true
+}
+
+// GetSessionId は、リクエストのアクセストークンが属するセッションのIDを取得します。
+func GetSessionId(req *http.Request) (sessionId string, ok bool) {
+	token := GetToken(req)
+	return tokens.GetFamilyId(token)
+}
+
+// GetClientInfo は、リクエストを送信したクライアントの情報を取得します。
+func GetClientInfo(req *http.Request) security.ClientInfo {
+	return security.NewClientInfo(req.RemoteAddr, req.UserAgent())
 }
 
 // ParseJson は、リクエストボディをもとに与えられたT型のオブジェクトにJSONをアンマーシャルします。
@@ -44,9 +216,108 @@ func ParseJson[T any](req *http.Request, obj *T) (err error) {
 }
 
 // IsNotAuthenticate は、認証されているかを判定し、されていない場合はtrueを返却します。
-func IsNotAuthenticate(req *http.Request) bool {
-	token := req.Header.Get("Authorization")
-	tokens := security.Tokens{}
-	_, ok := tokens.GetUserId(token)
-	return !ok
+// scopesを指定した場合は、セッションのアクセストークンに加えて、scopesをすべてもつパーソナルアクセストークンやクライアント証明書でも認証されます。
+// scopesを指定しない場合は、パーソナルアクセストークンやクライアント証明書では認証されません。
+func IsNotAuthenticate(req *http.Request, scopes ...security.Scope) bool {
+	auth, ok := authenticate(req)
+	if !ok {
+		return true
+	}
+	if auth.scoped == nil {
+		return false
+	}
+	return len(scopes) == 0 || !auth.scoped.HasScopes(scopes...)
+}
+
+// IsInsufficientScope は、有効なパーソナルアクセストークンやクライアント証明書で認証されているが、scopesの一部をもたない場合にtrueを返却します。
+// 認証されていない場合は、falseを返却します。
+func IsInsufficientScope(req *http.Request, scopes ...security.Scope) bool {
+	auth, ok := authenticate(req)
+	return ok && auth.scoped != nil && !auth.scoped.HasScopes(scopes...)
+}
+
+// scopedCredential は、パーソナルアクセストークンやクライアント証明書のように、スコープをもつ認証情報のインターフェースです。
+type scopedCredential interface {
+	HasScopes(scopes ...security.Scope) bool
+}
+
+// authentication は、リクエストを認証した結果を表現する構造体です。
+type authentication struct {
+	userId *domainUsers.UserId
+	// scoped は、パーソナルアクセストークンやクライアント証明書で認証された場合の、その認証情報です。セッションのアクセストークンの場合はnilです。
+	scoped scopedCredential
+}
+
+// authenticationKey は、リクエストのコンテキストに、認証の結果を保存するためのキーです。
+type authenticationKey struct{}
+
+// authenticationResult は、一つのリクエストで認証を一度だけ行うために、認証の結果を保存する構造体です。
+type authenticationResult struct {
+	once sync.Once
+	auth *authentication
+	ok   bool
+}
+
+// CacheAuthentication は、リクエストの認証を一度だけ行うためのミドルウェアです。
+// リクエスト数の制限やハンドラで何度認証しても、パーソナルアクセストークンやクライアント証明書の照合と最終利用時刻の更新、アカウントの状態の取得は一度だけ行われます。
+// 認証を行うミドルウェアより外側で使用する必要があります。
+func CacheAuthentication(next servers.HandlerFunc) servers.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+		req = req.WithContext(context.WithValue(req.Context(), authenticationKey{}, &authenticationResult{}))
+		return next(writer, req, logger)
+	}
+}
+
+// authenticate は、リクエストを認証します。CacheAuthenticationを使用している場合は、最初に認証した結果を返却します。
+func authenticate(req *http.Request) (auth *authentication, ok bool) {
+	result, cached := req.Context().Value(authenticationKey{}).(*authenticationResult)
+	if !cached {
+		return authenticateCredentials(req)
+	}
+	result.once.Do(func() {
+		result.auth, result.ok = authenticateCredentials(req)
+	})
+	return result.auth, result.ok
+}
+
+// authenticateCredentials は、アクセストークン、パーソナルアクセストークン、クライアント証明書のいずれかでリクエストを認証します。
+// 認証情報が有効でも、アカウントが停止されている場合や、署名付きのアクセストークンがパスワードの変更などで無効化される前に発行された場合は、falseを返却します。
+func authenticateCredentials(req *http.Request) (auth *authentication, ok bool) {
+	var issuedAt time.Time
+	if !HasToken(req) {
+		certificate, ok := authenticateClientCertificate(req)
+		if !ok {
+			return nil, false
+		}
+		auth = &authentication{userId: &certificate.UserId, scoped: certificate}
+	} else if token := GetToken(req); security.IsPersonalToken(token) {
+		info, ok := personalTokens.Authenticate(token)
+		if !ok {
+			return nil, false
+		}
+		auth = &authentication{userId: &info.UserId, scoped: info}
+	} else {
+		userId, signedAt, ok := tokens.Authenticate(token)
+		if !ok {
+			return nil, false
+		}
+		auth, issuedAt = &authentication{userId: userId}, signedAt
+	}
+
+	// 署名付きのアクセストークンやクライアント証明書はセッションを削除しても無効にならないため、アカウントの状態をここで確認する。
+	status, err := GetAccountStatusStore().FindAccountStatus(auth.userId)
+	if err != nil || !status.IsActive(issuedAt) {
+		return nil, false
+	}
+	return auth, true
+}
+
+// authenticateClientCertificate は、相互TLS認証で検証されたクライアント証明書に対応する登録を返却します。
+// クライアント証明書が送信されていない場合や、登録されていない場合は、falseを返却します。
+func authenticateClientCertificate(req *http.Request) (certificate *security.ClientCertificate, ok bool) {
+	verified := GetClientCertificate(req)
+	if verified == nil {
+		return nil, false
+	}
+	return clientCertificates.Authenticate(verified)
 }
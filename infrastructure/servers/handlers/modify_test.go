@@ -0,0 +1,165 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	inf_users "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/users"
+)
+
+// patchModify は、アクセストークンで認証した/user/modifyへのリクエストを送信し、ステータスコードを返却します。
+func patchModify(t *testing.T, accessToken string, body string) int {
+	req := httptest.NewRequest(http.MethodPatch, "/user/modify", bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.2:1234"
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	status, _ := users.Modify(httptest.NewRecorder(), req, servers.NewLogger())
+	return status
+}
+
+// TestModify は、ユーザ情報の編集で、パスワードを送信しない場合にパスワードとセッションが維持されることを確認します。
+func TestModify(t *testing.T) {
+	connector := NewTestDBConnector()
+	database, err := connector.Connect()
+	if err != nil {
+		t.Skip("テスト用データベースに接続できません。", err)
+	}
+	database.Close()
+
+	// エラーログがパッケージのディレクトリに出力されないようにする。
+	workDir, _ := os.Getwd()
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(workDir)
+	handlers.UseDBConnector(connector)
+
+	password := "Modify-Test-Password-1"
+	signInId, _ := dom_users.NewSignInId(fmt.Sprintf("modify_%d", time.Now().UnixNano()%1e12))
+	userRepos := inf_users.NewUserRepository(connector)
+	user, err := userRepos.Create(signInId, password, "modify_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		// サインインIDを変更しているため、ユーザIDから探して削除する。
+		if found, err := userRepos.FindByUserId(&user.Id); err == nil {
+			userRepos.Delete(&found.SignInId)
+		}
+	}()
+	pair, err := handlers.GetTokens().GenereteToken(&user.Id, security.NewClientInfo("192.0.2.2:1234", "modify-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := handlers.GetPersonalTokens().Create(&user.Id, "modify", []security.Scope{security.ScopeUserRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("プロフィールのみの編集では、パスワードとセッションを維持する", func(t *testing.T) {
+		newSignInId := signInId.GetValue() + "_r"
+		if status := patchModify(t, pair.AccessToken, fmt.Sprintf(`{"screenName": "renamed", "signInId": %q}`, newSignInId)); status != http.StatusOK {
+			t.Fatal(status)
+		}
+		found, err := userRepos.FindByUserId(&user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.ScreenName != "renamed" || found.SignInId.GetValue() != newSignInId || found.Password != user.Password {
+			t.Error("プロフィールのみが変更されていません。")
+		}
+		if _, ok := handlers.GetTokens().GetUserId(pair.AccessToken); !ok {
+			t.Error("セッションが無効化されました。")
+		}
+		if _, ok := handlers.GetPersonalTokens().Authenticate(token); !ok {
+			t.Error("パーソナルアクセストークンが無効化されました。")
+		}
+	})
+
+	t.Run("省略した項目は変更しない", func(t *testing.T) {
+		before, _ := userRepos.FindByUserId(&user.Id)
+		if status := patchModify(t, pair.AccessToken, `{"screenName": "renamed_2"}`); status != http.StatusOK {
+			t.Fatal(status)
+		}
+		after, _ := userRepos.FindByUserId(&user.Id)
+		if after.ScreenName != "renamed_2" || !after.SignInId.Equals(&before.SignInId) || after.Password != before.Password {
+			t.Error("省略した項目が変更されました。")
+		}
+	})
+
+	t.Run("以前の形式のpasswordのみを送信した場合は、エラーになる", func(t *testing.T) {
+		if status := patchModify(t, pair.AccessToken, `{"password": "Modify-Test-Password-2"}`); status != http.StatusBadRequest {
+			t.Error(status)
+		}
+		if found, _ := userRepos.FindByUserId(&user.Id); found.Password != user.Password {
+			t.Error("パスワードが変更されました。")
+		}
+	})
+
+	t.Run("現在のパスワードが違う場合は、パスワードを変更しない", func(t *testing.T) {
+		if status := patchModify(t, pair.AccessToken, `{"currentPassword": "wrong", "newPassword": "Modify-Test-Password-2"}`); status != http.StatusBadRequest {
+			t.Error(status)
+		}
+		if found, _ := userRepos.FindByUserId(&user.Id); found.Password != user.Password {
+			t.Error("パスワードが変更されました。")
+		}
+	})
+
+	t.Run("現在のパスワードを照合して、パスワードを変更する", func(t *testing.T) {
+		body := fmt.Sprintf(`{"currentPassword": %q, "newPassword": "Modify-Test-Password-2"}`, password)
+		if status := patchModify(t, pair.AccessToken, body); status != http.StatusOK {
+			t.Fatal(status)
+		}
+		found, _ := userRepos.FindByUserId(&user.Id)
+		if ok, _, _ := (&security.Passwords{}).Verify("Modify-Test-Password-2", found.Password); !ok {
+			t.Error("パスワードが変更されていません。")
+		}
+		if _, ok := handlers.GetTokens().GetUserId(pair.AccessToken); ok {
+			t.Error("セッションが無効化されていません。")
+		}
+	})
+
+	t.Run("署名付きのアクセストークンでも、リクエストしたセッションを維持できる", func(t *testing.T) {
+		previous := handlers.GetTokens()
+		defer handlers.UseTokens(previous)
+		secret := make([]byte, 32)
+		rand.Read(secret)
+		key, _ := security.NewHMACSigningKey("test", secret)
+		signer, err := security.NewTokenSigner("test", []*security.SigningKey{key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handlers.UseTokens(security.NewSignedTokens(security.NewMemorySessionStore(), security.DefaultSessionConfig(), security.SystemClock{}, signer))
+		current, err := handlers.GetTokens().GenereteToken(&user.Id, security.NewClientInfo("192.0.2.2:1234", "modify-test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := handlers.GetTokens().GenereteToken(&user.Id, security.NewClientInfo("192.0.2.2:1234", "modify-test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body := `{"currentPassword": "Modify-Test-Password-2", "newPassword": "Modify-Test-Password-3", "keepCurrentSession": true}`
+		if status := patchModify(t, current.AccessToken, body); status != http.StatusOK {
+			t.Fatal(status)
+		}
+		if _, ok := handlers.GetUserId(newBearerRequest(current.AccessToken)); !ok {
+			t.Error("リクエストしたアクセストークンが無効化されました。")
+		}
+		if _, err := handlers.GetTokens().Refresh(other.RefreshToken, security.NewClientInfo("192.0.2.2:1234", "modify-test")); err == nil {
+			t.Error("他のセッションが無効化されていません。")
+		}
+	})
+}
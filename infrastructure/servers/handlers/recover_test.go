@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	inf_users "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/users"
+)
+
+// failingPersonalTokenStore は、パーソナルアクセストークンの一括削除に失敗する、テスト用のストアです。
+type failingPersonalTokenStore struct {
+	*security.MemoryPersonalTokenStore
+}
+
+// DeleteByUserId は、常にエラーを返却します。
+func (store *failingPersonalTokenStore) DeleteByUserId(userId *dom_users.UserId) error {
+	return errors.New("could not delete personal tokens")
+}
+
+// postRecover は、/user/recoverへのリクエストを送信し、ステータスコードを返却します。
+func postRecover(t *testing.T, signInId string, code string, newPassword string) int {
+	body := fmt.Sprintf(`{"signInId": %q, "recoveryCode": %q, "newPassword": %q}`, signInId, code, newPassword)
+	req := httptest.NewRequest(http.MethodPost, "/user/recover", bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.4:1234"
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	status, _ := users.Recover(httptest.NewRecorder(), req, servers.NewLogger())
+	return status
+}
+
+// TestRecover は、リカバリーコードによるパスワードの再設定で、古い認証情報を無効化できない場合や停止中のアカウントでは、パスワードを変更しないことを確認します。
+func TestRecover(t *testing.T) {
+	connector := NewTestDBConnector()
+	database, err := connector.Connect()
+	if err != nil {
+		t.Skip("テスト用データベースに接続できません。", err)
+	}
+	database.Close()
+
+	// エラーログがパッケージのディレクトリに出力されないようにする。
+	workDir, _ := os.Getwd()
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(workDir)
+	handlers.UseDBConnector(connector)
+
+	signInId, _ := dom_users.NewSignInId(fmt.Sprintf("recover_%d", time.Now().UnixNano()%1e12))
+	userRepos := inf_users.NewUserRepository(connector)
+	user, err := userRepos.Create(signInId, "Recover-Test-Password-1", "recover_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer userRepos.Delete(signInId)
+	codes := []string{"aaaa-bbbb-cccc-dddd", "eeee-ffff-gggg-hhhh"}
+	if err = userRepos.ReplaceRecoveryCodes(&user.Id, []string{security.HashRecoveryCode(codes[0]), security.HashRecoveryCode(codes[1])}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("古い認証情報を無効化できない場合は、パスワードを変更しない", func(t *testing.T) {
+		previous := handlers.GetPersonalTokens()
+		defer handlers.UsePersonalTokens(previous)
+		handlers.UsePersonalTokens(security.NewPersonalTokens(&failingPersonalTokenStore{security.NewMemoryPersonalTokenStore()}, 0, security.SystemClock{}))
+
+		if status := postRecover(t, signInId.GetValue(), codes[0], "Recover-Test-Password-2"); status != http.StatusInternalServerError {
+			t.Error(status)
+		}
+		if found, _ := userRepos.FindByUserId(&user.Id); found.Password != user.Password {
+			t.Error("パスワードが変更されました。")
+		}
+	})
+
+	t.Run("停止中のアカウントは、パスワードを再設定できない", func(t *testing.T) {
+		if err := userRepos.UpdateSuspended(&user.Id, true); err != nil {
+			t.Fatal(err)
+		}
+		defer userRepos.UpdateSuspended(&user.Id, false)
+		if status := postRecover(t, signInId.GetValue(), codes[1], "Recover-Test-Password-2"); status != http.StatusForbidden {
+			t.Error(status)
+		}
+		if found, _ := userRepos.FindByUserId(&user.Id); found.Password != user.Password {
+			t.Error("パスワードが変更されました。")
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package handlers_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	dom_users "FrogNote_database/domain/users"
+	inf_users "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/users"
+)
+
+// postDisableTwoFactor は、アクセストークンで認証した/user/2fa/disableへのリクエストを送信し、ステータスコードを返却します。
+func postDisableTwoFactor(t *testing.T, accessToken string, code string) int {
+	body := fmt.Sprintf(`{"code": %q}`, code)
+	req := httptest.NewRequest(http.MethodPost, "/user/2fa/disable", bytes.NewBufferString(body))
+	req.RemoteAddr = "192.0.2.3:1234"
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Length", fmt.Sprint(len(body)))
+	status, _ := users.DisableTwoFactor(httptest.NewRecorder(), req, servers.NewLogger())
+	return status
+}
+
+// TestDisableTwoFactor は、二要素認証の無効化で、コードの総当たりと再送が防がれることを確認します。
+func TestDisableTwoFactor(t *testing.T) {
+	connector := NewTestDBConnector()
+	database, err := connector.Connect()
+	if err != nil {
+		t.Skip("テスト用データベースに接続できません。", err)
+	}
+	database.Close()
+
+	// エラーログがパッケージのディレクトリに出力されないようにする。
+	workDir, _ := os.Getwd()
+	if err = os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(workDir)
+	handlers.UseDBConnector(connector)
+	lockout := handlers.GetLockout()
+	defer handlers.UseLockout(lockout)
+	handlers.UseLockout(security.NewLockout(security.NewMemoryAttemptStore(), security.LockoutConfig{
+		MaxFailuresPerSignInId: 2,
+		MaxFailuresPerAddress:  10,
+		BaseDelay:              time.Minute,
+		MaxDelay:               time.Minute,
+		ResetAfter:             time.Hour,
+		ReapInterval:           time.Hour,
+	}, security.SystemClock{}))
+
+	signInId, _ := dom_users.NewSignInId(fmt.Sprintf("totp_%d", time.Now().UnixNano()%1e12))
+	userRepos := inf_users.NewUserRepository(connector)
+	user, err := userRepos.Create(signInId, "Two-Factor-Test-Password-1", "two_factor_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer userRepos.Delete(signInId)
+	totp := security.NewTotp(security.SystemClock{})
+	secret, _ := totp.GenerateSecret()
+	if err = userRepos.UpdateTwoFactor(&user.Id, &dom_users.TwoFactor{Secret: secret, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := handlers.GetTokens().GenereteToken(&user.Id, security.NewClientInfo("192.0.2.3:1234", "two-factor-test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	isEnabled := func() bool {
+		found, _ := userRepos.FindByUserId(&user.Id)
+		return found.TwoFactor.Enabled
+	}
+
+	t.Run("使用済みのコードでは無効にできない", func(t *testing.T) {
+		now := time.Now()
+		code, _ := totp.GetCode(secret, now)
+		// サインインで使用されたコードを再送した場合と同じ状態にする。
+		if ok, err := userRepos.UseTotpStep(&user.Id, totp.GetStep(now)); !ok || err != nil {
+			t.Fatal(err)
+		}
+		if status := postDisableTwoFactor(t, pair.AccessToken, code); status != http.StatusBadRequest {
+			t.Error(status)
+		}
+		if !isEnabled() {
+			t.Error("二要素認証が無効になりました。")
+		}
+	})
+
+	t.Run("コードを誤り続けると、待ち時間の間は正しいコードでも照合しない", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if status := postDisableTwoFactor(t, pair.AccessToken, "abcdef"); status != http.StatusBadRequest {
+				t.Error(status)
+			}
+		}
+		code, _ := totp.GetCode(secret, time.Now().Add(30*time.Second))
+		if status := postDisableTwoFactor(t, pair.AccessToken, code); status != http.StatusTooManyRequests {
+			t.Error(status)
+		}
+		if !isEnabled() {
+			t.Error("二要素認証が無効になりました。")
+		}
+	})
+}
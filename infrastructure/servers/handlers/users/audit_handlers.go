@@ -0,0 +1,23 @@
+package users
+
+import (
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"net/http"
+)
+
+// ListAuditEvents は、自身に関する監査ログを取得するためのハンドラです。クエリパラメータtype、since、until、limitで、種類、期間、件数を指定できます。
+func ListAuditEvents(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	query, err := handlers.ParseAuditQuery(req.URL.Query())
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+	query.UserId, _ = handlers.GetUserId(req)
+	return handlers.FindAuditEvents(query, logger)
+}
@@ -0,0 +1,101 @@
+package users
+
+import (
+	domainUsers "FrogNote_database/domain/users"
+	dbUsers "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// challengeRequestObj は、チャレンジの発行要求を表現する構造体です。
+type challengeRequestObj struct {
+	SignInId string `json:"signInId"`
+}
+
+// challengeObj は、発行したチャレンジと、クライアントがパスワードから鍵を導出するためのパラメータを表現する構造体です。
+type challengeObj struct {
+	Nonce string `json:"nonce"`
+	// ExpiresIn は、ナンスが無効になるまでの秒数です。
+	ExpiresIn   int64  `json:"expiresIn"`
+	Algorithm   string `json:"algorithm"`
+	Salt        string `json:"salt,omitempty"`
+	Memory      uint32 `json:"memory,omitempty"`
+	Iterations  uint32 `json:"iterations,omitempty"`
+	Parallelism uint8  `json:"parallelism,omitempty"`
+	KeyLength   uint32 `json:"keyLength,omitempty"`
+}
+
+// Challenge は、チャレンジ&レスポンス認証のナンスを発行するためのハンドラです。
+// ユーザが存在するかを推測されないよう、存在しないサインインIDに対してもダミーのパラメータでナンスを発行します。
+func Challenge(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := challengeRequestObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	params, err := getChallengeParams(parsed.SignInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	nonce, expiresAt, err := handlers.GetChallenges().Issue(parsed.SignInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not issue challenge")
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resChallenge := challengeObj{
+		Nonce:     nonce,
+		ExpiresIn: int64(time.Until(expiresAt).Seconds()),
+		Algorithm: params.Algorithm,
+	}
+	if params.Algorithm == security.ChallengeAlgorithmScramArgon2id {
+		resChallenge.Salt = base64.RawURLEncoding.EncodeToString(params.Salt)
+		resChallenge.Memory = params.Argon2id.Memory
+		resChallenge.Iterations = params.Argon2id.Iterations
+		resChallenge.Parallelism = params.Argon2id.Parallelism
+		resChallenge.KeyLength = params.Argon2id.KeyLength
+	}
+	json, err := json.Marshal(resChallenge)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// getChallengeParams は、サインインIDのユーザのパスワードから鍵を導出するためのパラメータを返却します。
+// ユーザが存在しない場合や、ハッシュ値の形式がチャレンジ&レスポンス認証に対応していない場合は、ダミーのパラメータを返却します。
+func getChallengeParams(signInIdStr string) (params *security.ChallengeParams, err error) {
+	passwords := security.Passwords{}
+	signInId, err := domainUsers.NewSignInId(signInIdStr)
+	if err != nil {
+		return passwords.GetDummyChallengeParams(signInIdStr), nil
+	}
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	user, err := repos.FindBySignInId(signInId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return passwords.GetDummyChallengeParams(signInIdStr), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	params, err = passwords.GetChallengeParams(user.Password)
+	if errors.Is(err, security.ErrChallengeNotSupported) {
+		return passwords.GetDummyChallengeParams(signInIdStr), nil
+	}
+	return params, err
+}
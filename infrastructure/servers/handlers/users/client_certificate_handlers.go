@@ -0,0 +1,95 @@
+package users
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// revokeClientCertificateObj は、クライアント証明書の登録の削除要求を表現する構造体です。
+type revokeClientCertificateObj struct {
+	Id string `json:"id"`
+}
+
+// RegisterClientCertificate は、相互TLS認証で使用するクライアント証明書を登録するためのハンドラです。パーソナルアクセストークンやクライアント証明書では登録できません。
+// ユーザが登録できるのはフィンガープリントのみで、サブジェクトでの登録は管理者のみが行えます。
+func RegisterClientCertificate(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := handlers.RegisterClientCertificateObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+	// サブジェクトは証明書を持っていなくても指定できるため、他人の証明書を自身に対応付けられないよう、管理者のみが登録できる。
+	if parsed.Match == string(security.CertificateMatchSubject) {
+		return http.StatusForbidden, []byte("Only administrators can register a client certificate by subject")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	return handlers.RegisterClientCertificate(userId, &parsed, logger)
+}
+
+// ListClientCertificates は、ユーザが登録したクライアント証明書を、登録された順に取得するためのハンドラです。
+func ListClientCertificates(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	certificates, err := handlers.GetClientCertificates().List(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find client certificates")
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resCertificates := make([]handlers.ClientCertificateObj, len(certificates))
+	for i, certificate := range certificates {
+		resCertificates[i] = handlers.NewClientCertificateObj(certificate)
+	}
+	json, err := json.Marshal(resCertificates)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// RevokeClientCertificate は、ユーザが登録したクライアント証明書を指定して削除するためのハンドラです。
+func RevokeClientCertificate(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "DELETE") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := revokeClientCertificateObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	err = handlers.GetClientCertificates().Revoke(userId, parsed.Id)
+	if errors.Is(err, security.ErrClientCertificateNotFound) {
+		return http.StatusNotFound, []byte("Client certificate not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke client certificate")
+	}
+	return http.StatusOK, []byte("")
+}
@@ -0,0 +1,161 @@
+package users
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+// maxPersonalTokenNameLength は、パーソナルアクセストークンの名前の最大の文字数です。
+const maxPersonalTokenNameLength = 50
+
+// createPersonalTokenObj は、パーソナルアクセストークンの発行要求を表現する構造体です。
+type createPersonalTokenObj struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresIn は、発行から有効期限までの秒数です。0の場合は、無期限になります。
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+// personalTokenObj は、パーソナルアクセストークンを表現する構造体です。トークンそのものは、発行した際のレスポンスにのみ含まれます。
+type personalTokenObj struct {
+	Id         string     `json:"id"`
+	Token      string     `json:"token,omitempty"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// revokePersonalTokenObj は、パーソナルアクセストークンの無効化要求を表現する構造体です。
+type revokePersonalTokenObj struct {
+	Id string `json:"id"`
+}
+
+// CreatePersonalToken は、パーソナルアクセストークンを発行するためのハンドラです。パーソナルアクセストークンでは発行できません。
+func CreatePersonalToken(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := createPersonalTokenObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	nameLen := utf8.RuneCountInString(parsed.Name)
+	if nameLen < 1 || nameLen > maxPersonalTokenNameLength {
+		return http.StatusBadRequest, []byte("'name' must be between 1 to 50 characters")
+	}
+	scopes, err := handlers.ParseScopes(parsed.Scopes)
+	if err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+	if parsed.ExpiresIn < 0 {
+		return http.StatusBadRequest, []byte("'expiresIn' must not be negative")
+	}
+	var expiresAt time.Time
+	if parsed.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	token, info, err := handlers.GetPersonalTokens().Create(userId, parsed.Name, scopes, expiresAt)
+	if errors.Is(err, security.ErrPersonalTokenLifetimeExceeded) {
+		return http.StatusBadRequest, []byte("'expiresIn' exceeds the maximum lifetime")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not create personal access token")
+	}
+
+	resToken := newPersonalTokenObj(info)
+	resToken.Token = token
+	json, err := json.Marshal(resToken)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// ListPersonalTokens は、ユーザの有効なパーソナルアクセストークンを、発行された順に取得するためのハンドラです。
+func ListPersonalTokens(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	infos, err := handlers.GetPersonalTokens().List(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find personal access tokens")
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resTokens := make([]personalTokenObj, len(infos))
+	for i, info := range infos {
+		resTokens[i] = newPersonalTokenObj(info)
+	}
+	json, err := json.Marshal(resTokens)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// RevokePersonalToken は、ユーザのパーソナルアクセストークンを指定して無効化するためのハンドラです。
+func RevokePersonalToken(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "DELETE") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := revokePersonalTokenObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	err = handlers.GetPersonalTokens().Revoke(userId, parsed.Id)
+	if errors.Is(err, security.ErrPersonalTokenNotFound) {
+		return http.StatusNotFound, []byte("Personal access token not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke personal access token")
+	}
+	return http.StatusOK, []byte("")
+}
+
+// newPersonalTokenObj は、レスポンス用のパーソナルアクセストークンのオブジェクトを組み立てます。有効期限や最終利用時刻がない場合は、nullになります。
+func newPersonalTokenObj(info *security.PersonalToken) personalTokenObj {
+	obj := personalTokenObj{Id: info.Id, Name: info.Name, Scopes: make([]string, len(info.Scopes)), CreatedAt: info.CreatedAt}
+	for i, scope := range info.Scopes {
+		obj.Scopes[i] = string(scope)
+	}
+	if !info.ExpiresAt.IsZero() {
+		obj.ExpiresAt = &info.ExpiresAt
+	}
+	if !info.LastUsedAt.IsZero() {
+		obj.LastUsedAt = &info.LastUsedAt
+	}
+	return obj
+}
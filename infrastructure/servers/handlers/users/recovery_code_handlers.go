@@ -0,0 +1,218 @@
+package users
+
+import (
+	"FrogNote_database/domain/audits"
+	domainUsers "FrogNote_database/domain/users"
+	dbUsers "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// recoveryCodesObj は、発行したリカバリーコードを表現する構造体です。コードは、発行した際のレスポンスにのみ含まれます。
+type recoveryCodesObj struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// recoveryCodeCountObj は、未使用のリカバリーコードの数を表現する構造体です。
+type recoveryCodeCountObj struct {
+	Remaining int `json:"remaining"`
+}
+
+// regenerateRecoveryCodesObj は、リカバリーコードの再発行要求を表現する構造体です。
+type regenerateRecoveryCodesObj struct {
+	Password string `json:"password"`
+}
+
+// recoverObj は、リカバリーコードによるパスワードの再設定要求を表現する構造体です。
+type recoverObj struct {
+	SignInId     string `json:"signInId"`
+	RecoveryCode string `json:"recoveryCode"`
+	NewPassword  string `json:"newPassword"`
+}
+
+// issueRecoveryCodes は、ユーザのリカバリーコードを生成して保存し、レスポンス用のJSONを返却します。それまでのコードは使用できなくなります。
+func issueRecoveryCodes(repos *dbUsers.UserRepository, userId *domainUsers.UserId, logger *servers.Logger) (status int, body []byte) {
+	codes, hashes, err := security.GenerateRecoveryCodes()
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not generate recovery codes")
+	}
+	err = repos.ReplaceRecoveryCodes(userId, hashes)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+	json, err := json.Marshal(recoveryCodesObj{RecoveryCodes: codes})
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// CountRecoveryCodes は、未使用のリカバリーコードの数を取得するためのハンドラです。
+func CountRecoveryCodes(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	userId, _ := handlers.GetUserId(req)
+	count, err := repos.CountRecoveryCodes(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not read data")
+	}
+	json, err := json.Marshal(recoveryCodeCountObj{Remaining: count})
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// RegenerateRecoveryCodes は、リカバリーコードを再発行するためのハンドラです。
+// 盗まれたアクセストークンでパスワードを再設定されないよう、現在のパスワードの入力を求めます。
+func RegenerateRecoveryCodes(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := regenerateRecoveryCodesObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	userId, _ := handlers.GetUserId(req)
+	user, err := repos.FindByUserId(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	passwords := security.Passwords{}
+	ok, _, err := passwords.Verify(parsed.Password, user.Password)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	if !ok {
+		return http.StatusBadRequest, []byte("Password is incorrect")
+	}
+	return issueRecoveryCodes(repos, userId, logger)
+}
+
+// Recover は、サインインIDとリカバリーコードで、パスワードを再設定するためのハンドラです。
+// 使用したリカバリーコードは無効になり、既存のセッションとパーソナルアクセストークンもすべて無効になります。
+func Recover(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := recoverObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	// リカバリーコードの総当たりを防ぐため、認証と同じく失敗が続いている場合は受け付けない。
+	lockout := handlers.GetLockout()
+	retryAfter, err := lockout.Check(parsed.SignInId, req.RemoteAddr)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	if retryAfter > 0 {
+		handlers.SetRetryAfter(writer, retryAfter)
+		return http.StatusTooManyRequests, []byte("Too many failed attempts")
+	}
+
+	// コードを消費する前にポリシーを確認する。違反していても、コードは使用済みにならない。
+	if violations := handlers.GetPolicy().ValidatePassword(parsed.NewPassword, parsed.SignInId); len(violations) > 0 {
+		return responseViolations(violations, logger)
+	}
+
+	// 存在しないサインインIDも、コードが違う場合と同様に失敗として記録する。
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	signInId, err := domainUsers.NewSignInId(parsed.SignInId)
+	if err != nil {
+		recordAuthFailure(req, audits.EventPasswordResetFailed, parsed.SignInId, nil, "unknown_sign_in_id", logger)
+		return http.StatusUnauthorized, []byte("Recovery code is incorrect")
+	}
+	user, err := repos.FindBySignInId(signInId)
+	// 応答時間からサインインIDが存在するかを推測されないよう、存在しないユーザのIDで同じようにコードを照合してから返却する。
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = repos.UseRecoveryCode(domainUsers.NewUserId(0), security.HashRecoveryCode(parsed.RecoveryCode))
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+		}
+		recordAuthFailure(req, audits.EventPasswordResetFailed, parsed.SignInId, nil, "unknown_sign_in_id", logger)
+		return http.StatusUnauthorized, []byte("Recovery code is incorrect")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	ok, err := repos.UseRecoveryCode(&user.Id, security.HashRecoveryCode(parsed.RecoveryCode))
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	if !ok {
+		recordAuthFailure(req, audits.EventPasswordResetFailed, parsed.SignInId, &user.Id, "incorrect_recovery_code", logger)
+		return http.StatusUnauthorized, []byte("Recovery code is incorrect")
+	}
+	// 停止中のアカウントは、サインインと同じく、コードを照合した後に拒否する。コードを知らない第三者に停止中かを知られないようにするためである。
+	if user.Suspended {
+		handlers.RecordAuditEvent(req, audits.EventPasswordResetFailed, &user.Id, parsed.SignInId, "account_suspended", logger)
+		return http.StatusForbidden, []byte("Account suspended")
+	}
+
+	// パスワードを知る第三者が、既存のセッションやパーソナルアクセストークン、クライアント証明書を使い続けられないよう、すべて無効化する。
+	// 無効化に失敗した場合に、古い認証情報が残ったまま新しいパスワードが有効にならないよう、パスワードは最後に更新する。
+	err = handlers.GetTokens().RevokeAllSessions(&user.Id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke sessions")
+	}
+	err = repos.RevokeTokens(&user.Id, time.Now())
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke sessions")
+	}
+	err = handlers.GetPersonalTokens().RevokeAll(&user.Id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke personal access tokens")
+	}
+	err = handlers.GetClientCertificates().RevokeAll(&user.Id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke client certificates")
+	}
+
+	err = repos.UpdatePassword(&user.Id, parsed.NewPassword)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+	err = lockout.Succeed(parsed.SignInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "could not reset failed attempts")
+	}
+	handlers.RecordAuditEvent(req, audits.EventPasswordReset, &user.Id, parsed.SignInId, "", logger)
+	return http.StatusOK, []byte("")
+}
@@ -0,0 +1,110 @@
+package users
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// sessionObj は、サインイン中のセッションを表現する構造体です。
+type sessionObj struct {
+	Id         string    `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	RemoteAddr string    `json:"remoteAddr"`
+	UserAgent  string    `json:"userAgent"`
+	// Current は、リクエストしたアクセストークンのセッションかを表します。
+	Current bool `json:"current"`
+}
+
+// revokeSessionObj は、セッションの無効化要求を表現する構造体です。
+type revokeSessionObj struct {
+	Id string `json:"id"`
+}
+
+// ListSessions は、ユーザのサインイン中のセッションを、最後に利用された順に取得するためのハンドラです。
+func ListSessions(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "GET" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	currentId, _ := handlers.GetSessionId(req)
+	infos, err := handlers.GetTokens().ListSessions(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find sessions")
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resSessions := make([]sessionObj, len(infos))
+	for i, info := range infos {
+		resSessions[i] = sessionObj{
+			Id:         info.FamilyId,
+			CreatedAt:  info.CreatedAt,
+			LastUsedAt: info.LastUsedAt,
+			RemoteAddr: info.Client.RemoteAddr,
+			UserAgent:  info.Client.UserAgent,
+			Current:    info.FamilyId == currentId,
+		}
+	}
+	json, err := json.Marshal(resSessions)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// RevokeSession は、ユーザのセッションを指定して無効化するためのハンドラです。
+func RevokeSession(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "DELETE") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := revokeSessionObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	err = handlers.GetTokens().RevokeSession(userId, parsed.Id)
+	if errors.Is(err, security.ErrSessionNotFound) {
+		return http.StatusNotFound, []byte("Session not found")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke session")
+	}
+	return http.StatusOK, []byte("")
+}
+
+// RevokeOtherSessions は、リクエストしたアクセストークン以外のユーザのセッションをすべて無効化するためのハンドラです。
+func RevokeOtherSessions(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "DELETE" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	userId, _ := handlers.GetUserId(req)
+	currentId, _ := handlers.GetSessionId(req)
+	err := handlers.GetTokens().RevokeOtherSessions(userId, currentId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke sessions")
+	}
+	return http.StatusOK, []byte("")
+}
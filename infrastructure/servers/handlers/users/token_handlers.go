@@ -0,0 +1,92 @@
+package users
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// tokenPairObj は、アクセストークンとリフレッシュトークンの組を表現する構造体です。
+type tokenPairObj struct {
+	AccessToken string `json:"accessToken,omitempty"`
+	// ExpiresIn は、アクセストークンが無効になるまでの秒数です。
+	ExpiresIn    int64  `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// RefreshExpiresIn は、リフレッシュトークンが無効になるまでの秒数です。
+	RefreshExpiresIn int64 `json:"refreshExpiresIn"`
+	// CsrfToken は、クッキーモードの場合に、状態を変更するリクエストのX-CSRF-Tokenヘッダに指定するトークンです。
+	CsrfToken string `json:"csrfToken,omitempty"`
+}
+
+// refreshObj は、トークンのリフレッシュ要求を表現する構造体です。
+// クッキーモードの場合は、RefreshTokenを空にし、クッキーのリフレッシュトークンとX-CSRF-Tokenヘッダを送信します。
+type refreshObj struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshToken は、リフレッシュトークンを使用して、新しいアクセストークンとリフレッシュトークンを発行するためのハンドラです。
+// 使用済みのリフレッシュトークンが再び使用された場合は、同じサインインで発行されたトークンがすべて無効になります。
+func RefreshToken(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := refreshObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	refreshToken := parsed.RefreshToken
+	useCookie := refreshToken == ""
+	if useCookie {
+		refreshToken = handlers.GetRefreshTokenCookie(req)
+	}
+
+	tokens := handlers.GetTokens()
+	pair, err := tokens.Refresh(refreshToken, handlers.GetClientInfo(req))
+	if errors.Is(err, security.ErrRefreshTokenReused) {
+		logger.FPrintErrorLog(err, "all tokens of the session were revoked")
+		return http.StatusUnauthorized, []byte("Refresh token reused")
+	}
+	if errors.Is(err, security.ErrInvalidRefreshToken) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not refresh token")
+	}
+	return respondTokenPair(writer, pair, useCookie, logger)
+}
+
+// respondTokenPair は、トークンの組をレスポンス用のJSONに変換します。
+// クッキーモードの場合は、トークンをHttpOnlyのクッキーに設定し、JavaScriptから読み取れないようレスポンスボディには含めません。
+func respondTokenPair(writer http.ResponseWriter, pair *security.TokenPair, useCookie bool, logger *servers.Logger) (status int, body []byte) {
+	now := time.Now()
+	resPair := tokenPairObj{
+		AccessToken:      pair.AccessToken,
+		ExpiresIn:        int64(pair.AccessTokenExpiresAt.Sub(now).Seconds()),
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresIn: int64(pair.RefreshTokenExpiresAt.Sub(now).Seconds()),
+	}
+	if useCookie {
+		csrfToken, err := handlers.SetTokenCookies(writer, pair)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("Could not generate token")
+		}
+		resPair.AccessToken = ""
+		resPair.RefreshToken = ""
+		resPair.CsrfToken = csrfToken
+	}
+	json, err := json.Marshal(resPair)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
@@ -0,0 +1,244 @@
+package users
+
+import (
+	"FrogNote_database/domain/audits"
+	domainUsers "FrogNote_database/domain/users"
+	dbUsers "FrogNote_database/infrastructure/db/users"
+	"FrogNote_database/infrastructure/security"
+	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// totpIssuer は、認証アプリに表示される発行者名です。
+const totpIssuer = "FrogNote"
+
+// twoFactorEnrollmentObj は、二要素認証の登録に必要な情報を表現する構造体です。
+type twoFactorEnrollmentObj struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// twoFactorCodeObj は、認証アプリのコードを表現する構造体です。
+type twoFactorCodeObj struct {
+	Code string `json:"code"`
+}
+
+// twoFactorRequiredObj は、二要素認証のコードの入力が必要であることを表現する構造体です。
+type twoFactorRequiredObj struct {
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	TwoFactorToken    string `json:"twoFactorToken"`
+	// ExpiresIn は、TwoFactorTokenが無効になるまでの秒数です。
+	ExpiresIn int64 `json:"expiresIn"`
+}
+
+// EnrollTwoFactor は、二要素認証を登録するためのハンドラです。共有鍵を生成し、認証アプリに登録するためのURIを返却します。
+// ConfirmTwoFactorでコードを確認するまで、二要素認証は有効になりません。
+func EnrollTwoFactor(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if req.Method != "POST" {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	userId, _ := handlers.GetUserId(req)
+	user, err := repos.FindByUserId(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	if user.TwoFactor.Enabled {
+		return http.StatusConflict, []byte("Two-factor authentication is already enabled")
+	}
+
+	totp := security.NewTotp(security.SystemClock{})
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not generate secret")
+	}
+	err = repos.UpdateTwoFactor(userId, &domainUsers.TwoFactor{Secret: secret})
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+
+	// レスポンス用にオブジェクトを組み立てる。
+	resEnrollment := twoFactorEnrollmentObj{
+		Secret: secret,
+		URI:    totp.GetURI(totpIssuer, user.SignInId.GetValue(), secret),
+	}
+	json, err := json.Marshal(resEnrollment)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// ConfirmTwoFactor は、登録中の共有鍵で生成されたコードを確認し、二要素認証を有効にするためのハンドラです。
+func ConfirmTwoFactor(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := twoFactorCodeObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	userId, _ := handlers.GetUserId(req)
+	user, err := repos.FindByUserId(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	if !user.TwoFactor.IsEnrolling() {
+		return http.StatusConflict, []byte("Two-factor authentication is not being enrolled")
+	}
+
+	step, status, body := verifyTwoFactorCode(writer, req, repos, user, parsed.Code, logger)
+	if status != http.StatusOK {
+		return status, body
+	}
+	err = repos.UpdateTwoFactor(userId, &domainUsers.TwoFactor{Secret: user.TwoFactor.Secret, Enabled: true, LastUsedStep: step})
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+	return http.StatusOK, []byte("")
+}
+
+// DisableTwoFactor は、認証アプリのコードを確認し、二要素認証を無効にするためのハンドラです。
+func DisableTwoFactor(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
+	if handlers.IsNotAuthenticate(req) {
+		return http.StatusUnauthorized, []byte("Unauthorized")
+	}
+	if handlers.IsNotJsonReq(req, "POST") {
+		return http.StatusBadRequest, []byte("Bad request")
+	}
+	parsed := twoFactorCodeObj{}
+	err := handlers.ParseJson(req, &parsed)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not parse json")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	userId, _ := handlers.GetUserId(req)
+	user, err := repos.FindByUserId(userId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find user")
+	}
+	if !user.TwoFactor.Enabled {
+		return http.StatusConflict, []byte("Two-factor authentication is not enabled")
+	}
+
+	if _, status, body := verifyTwoFactorCode(writer, req, repos, user, parsed.Code, logger); status != http.StatusOK {
+		return status, body
+	}
+	err = repos.UpdateTwoFactor(userId, &domainUsers.TwoFactor{})
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not write data")
+	}
+	return http.StatusOK, []byte("")
+}
+
+// verifyTwoFactorCode は、二要素認証の有効化や無効化のために、認証アプリのコードを照合し、時間ステップを使用済みにします。
+// アクセストークンを盗まれた場合にコードを総当たりされないよう、サインインと同じく失敗を記録し、待ち時間の間は照合しません。
+// 照合に成功した場合は、http.StatusOKと使用した時間ステップを返却します。
+func verifyTwoFactorCode(writer http.ResponseWriter, req *http.Request, repos *dbUsers.UserRepository, user *domainUsers.User, code string, logger *servers.Logger) (step int64, status int, body []byte) {
+	signInId := user.SignInId.GetValue()
+	retryAfter, err := handlers.GetLockout().Check(signInId, req.RemoteAddr)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return 0, http.StatusInternalServerError, []byte("internal error")
+	}
+	if retryAfter > 0 {
+		handlers.SetRetryAfter(writer, retryAfter)
+		return 0, http.StatusTooManyRequests, []byte("Too many failed attempts")
+	}
+
+	totp := security.NewTotp(security.SystemClock{})
+	step, ok := totp.Verify(user.TwoFactor.Secret, code, user.TwoFactor.LastUsedStep)
+	if !ok {
+		recordAuthFailure(req, audits.EventTwoFactorFailed, signInId, &user.Id, "incorrect_code", logger)
+		return 0, http.StatusBadRequest, []byte("Code is incorrect")
+	}
+	// 通信経路で見られたコードを再送されても、受け付けるのは一度だけにする。
+	ok, err = repos.UseTotpStep(&user.Id, step)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return 0, http.StatusInternalServerError, []byte("internal error")
+	}
+	if !ok {
+		return 0, http.StatusBadRequest, []byte("Code is incorrect")
+	}
+	return step, http.StatusOK, nil
+}
+
+// requireTwoFactor は、パスワードを照合したユーザに、二要素認証のコードの入力を求めるレスポンスを返却します。
+func requireTwoFactor(signInId string, logger *servers.Logger) (status int, body []byte) {
+	token, expiresAt, err := handlers.GetTwoFactorChallenges().Issue(signInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not issue two-factor token")
+	}
+	resRequired := twoFactorRequiredObj{
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+		ExpiresIn:         int64(time.Until(expiresAt).Seconds()),
+	}
+	json, err := json.Marshal(resRequired)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusOK, json
+}
+
+// authenticateTwoFactor は、二要素認証の二段階目として、認証アプリのコードを照合し、トークンを発行します。
+// TwoFactorTokenは一度しか使用できないため、コードを誤った場合はパスワードの照合からやりなおす必要があります。
+func authenticateTwoFactor(writer http.ResponseWriter, req *http.Request, authObj *authenticationObj, logger *servers.Logger) (status int, body []byte) {
+	err := handlers.GetTwoFactorChallenges().Consume(authObj.TwoFactorToken, authObj.SignInId)
+	if err != nil {
+		return http.StatusUnauthorized, []byte("Invalid two-factor token")
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	signInId, _ := domainUsers.NewSignInId(authObj.SignInId)
+	user, err := repos.FindBySignInId(signInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+
+	totp := security.NewTotp(security.SystemClock{})
+	step, ok := totp.Verify(user.TwoFactor.Secret, authObj.Code, user.TwoFactor.LastUsedStep)
+	if !user.TwoFactor.Enabled || !ok {
+		recordAuthFailure(req, audits.EventSignInFailed, authObj.SignInId, &user.Id, "incorrect_code", logger)
+		return http.StatusUnauthorized, []byte("Code is incorrect")
+	}
+	// 同じコードが同時に使用された場合でも、受け付けるのは一度だけにする。
+	ok, err = repos.UseTotpStep(&user.Id, step)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	if !ok {
+		return http.StatusUnauthorized, []byte("Code is incorrect")
+	}
+
+	return completeAuthentication(writer, req, authObj, user, logger)
+}
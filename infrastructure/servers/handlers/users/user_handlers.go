@@ -1,27 +1,101 @@
 package users
 
 import (
+	"FrogNote_database/domain/audits"
 	domainUsers "FrogNote_database/domain/users"
-	"FrogNote_database/infrastructure/db"
+	dbBackups "FrogNote_database/infrastructure/db/backups"
 	dbUsers "FrogNote_database/infrastructure/db/users"
 	"FrogNote_database/infrastructure/security"
 	"FrogNote_database/infrastructure/servers"
 	"FrogNote_database/infrastructure/servers/handlers"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 )
 
-// userObj は、ユーザ情報を表現する構造体です。
+// userObj は、ユーザの作成・編集の要求を表現する構造体です。パスワードを含むため、レスポンスには使用しません。
 type userObj struct {
 	Password   string `json:"password"`
 	ScreenName string `json:"screenName"`
 	SignInId   string `json:"signInId"`
 }
 
+// profileObj は、ユーザ自身に返却するプロフィールを表現する構造体です。
+// パスワードのハッシュ値や二要素認証の共有鍵などの認証情報は含めません。
+type profileObj struct {
+	Id         int    `json:"id"`
+	SignInId   string `json:"signInId"`
+	ScreenName string `json:"screenName"`
+	// CreatedAt は、ユーザを作成した時刻です。作成時刻を記録する前に作成されたユーザの場合は、nullです。
+	CreatedAt        *time.Time `json:"createdAt"`
+	BackupCount      int        `json:"backupCount"`
+	StorageBytes     int64      `json:"storageBytes"`
+	TwoFactorEnabled bool       `json:"twoFactorEnabled"`
+}
+
+// modifyUserObj は、ユーザ情報の編集要求を表現する構造体です。空の項目は変更しません。
+type modifyUserObj struct {
+	ScreenName string `json:"screenName"`
+	SignInId   string `json:"signInId"`
+	// CurrentPassword は、パスワードを変更する場合に照合する、現在のパスワードです。
+	CurrentPassword string `json:"currentPassword"`
+	// NewPassword は、新しいパスワードです。空の場合は、パスワードを変更しません。
+	NewPassword string `json:"newPassword"`
+	// Password は、以前の形式のパスワードです。保存済みのハッシュ値を送り返す形式は廃止したため、newPasswordなしで送信された場合はエラーにします。
+	Password string `json:"password"`
+	// KeepCurrentSession は、パスワードを変更した際に、リクエストしたセッションを維持するかを表します。falseの場合は、すべてのセッションが無効になります。
+	KeepCurrentSession bool `json:"keepCurrentSession"`
+}
+
 // authenticationObj は、認証情報を表現する構造体です。
+// チャレンジ&レスポンス認証の場合は、Passwordの代わりに、発行されたNonceと、それに対するHMACであるProofが送信されます。
+// 二要素認証の二段階目の場合は、パスワードの照合後に発行されたTwoFactorTokenと、認証アプリのCodeが送信されます。
 type authenticationObj struct {
-	SignInId string `json:"signInId"`
-	Password string `json:"password"`
+	SignInId       string `json:"signInId"`
+	Password       string `json:"password"`
+	Nonce          string `json:"nonce"`
+	Proof          string `json:"proof"`
+	TwoFactorToken string `json:"twoFactorToken"`
+	Code           string `json:"code"`
+	// Cookie は、トークンをレスポンスボディではなく、HttpOnlyのクッキーで受け取るかを表します。ブラウザのクライアントで使用します。
+	Cookie bool `json:"cookie"`
+}
+
+// violationObj は、ポリシーへの違反を表現する構造体です。
+type violationObj struct {
+	Field          string `json:"field"`
+	Rule           string `json:"rule"`
+	Limit          int    `json:"limit,omitempty"`
+	CharacterClass string `json:"characterClass,omitempty"`
+	Message        string `json:"message"`
+}
+
+// violationsObj は、ポリシーへの違反の一覧を表現する構造体です。
+type violationsObj struct {
+	Violations []violationObj `json:"violations"`
+}
+
+// responseViolations は、ポリシーへの違反の一覧をレスポンスとして返却します。
+func responseViolations(violations []domainUsers.Violation, logger *servers.Logger) (status int, body []byte) {
+	resViolations := violationsObj{Violations: make([]violationObj, len(violations))}
+	for i, violation := range violations {
+		resViolations.Violations[i] = violationObj{
+			Field:          violation.Field,
+			Rule:           string(violation.Rule),
+			Limit:          violation.Limit,
+			CharacterClass: string(violation.CharacterClass),
+			Message:        violation.Message(),
+		}
+	}
+	json, err := json.Marshal(resViolations)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not convert json.")
+	}
+	return http.StatusBadRequest, json
 }
 
 // Modify は、ユーザ情報を編集するためのハンドラです。
@@ -34,30 +108,84 @@ func Modify(writer http.ResponseWriter, req *http.Request, logger *servers.Logge
 	}
 
 	// jsonをパース
-	parsedUser := userObj{}
+	parsedUser := modifyUserObj{}
 	err := handlers.ParseJson(req, &parsedUser)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not parse json")
 	}
+	// 以前のクライアントが、パスワードを変更したつもりで変更されないことがないようにする。
+	if parsedUser.Password != "" && parsedUser.NewPassword == "" {
+		return http.StatusBadRequest, []byte("'password' is no longer supported; use 'currentPassword' and 'newPassword'")
+	}
 
 	// 既存のユーザ情報を取得]
-	repos := dbUsers.NewUserRepository(db.NewDBConnector())
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
 	userId, _ := handlers.GetUserId(req)
-	signInId, _ := domainUsers.NewSignInId(parsedUser.SignInId)
 
-	oldUser, err := repos.FindBySignInId(signInId)
+	oldUser, err := repos.FindByUserId(userId)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not found user")
 	}
+	if parsedUser.SignInId == "" {
+		parsedUser.SignInId = oldUser.SignInId.GetValue()
+	}
+	if parsedUser.ScreenName == "" {
+		parsedUser.ScreenName = oldUser.ScreenName
+	}
+	passwordChanged := parsedUser.NewPassword != ""
+
+	// パスワードを変更する場合は、アクセストークンを盗んだ第三者が変更できないよう、現在のパスワードを照合する。
+	// 照合の失敗は認証の失敗として記録し、現在のパスワードを総当たりできないようにする。
+	if passwordChanged {
+		lockout := handlers.GetLockout()
+		retryAfter, err := lockout.Check(oldUser.SignInId.GetValue(), req.RemoteAddr)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("internal error")
+		}
+		if retryAfter > 0 {
+			handlers.SetRetryAfter(writer, retryAfter)
+			return http.StatusTooManyRequests, []byte("Too many failed attempts")
+		}
+		passwords := security.Passwords{}
+		ok, _, err := passwords.Verify(parsedUser.CurrentPassword, oldUser.Password)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("internal error")
+		}
+		if !ok {
+			recordAuthFailure(req, audits.EventSignInFailed, oldUser.SignInId.GetValue(), userId, "incorrect_current_password", logger)
+			return http.StatusBadRequest, []byte("Current password is incorrect")
+		}
+	}
+
+	// 変更された項目だけをポリシーで確認する。ポリシーが厳しくなる前に登録された値は、そのまま維持できる。
+	policy := handlers.GetPolicy()
+	var violations []domainUsers.Violation
+	if parsedUser.SignInId != oldUser.SignInId.GetValue() {
+		violations = append(violations, policy.ValidateSignInId(parsedUser.SignInId)...)
+	}
+	if passwordChanged {
+		violations = append(violations, policy.ValidatePassword(parsedUser.NewPassword, parsedUser.SignInId)...)
+	}
+	if len(violations) > 0 {
+		return responseViolations(violations, logger)
+	}
+	signInId, err := domainUsers.NewSignInId(parsedUser.SignInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusBadRequest, []byte(err.Error())
+	}
 	// パースしたユーザ情報をもとに組み立てる
-	// もし、パスワードが変更されていた場合、ハッシュ化する。
-	if parsedUser.Password != oldUser.Password {
-		hash := security.Hash{}
-		parsedUser.Password = hash.GetHash(parsedUser.Password)
+	// パスワードを変更しない場合は、保存済みのハッシュ値をそのまま引き継ぐ。新しいパスワードはリポジトリでハッシュ化される。
+	var user *domainUsers.User
+	if passwordChanged {
+		user, err = domainUsers.NewUser(*userId, parsedUser.ScreenName, *signInId, parsedUser.NewPassword)
+	} else {
+		user, err = domainUsers.NewUserWithPasswordHash(*userId, parsedUser.ScreenName, *signInId, oldUser.Password)
 	}
-	user, err := domainUsers.NewUser(*userId, parsedUser.ScreenName, *signInId, parsedUser.Password)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not create user")
@@ -69,19 +197,59 @@ func Modify(writer http.ResponseWriter, req *http.Request, logger *servers.Logge
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not write data")
 	}
+
+	// パスワードが変更された場合は、古いパスワードで発行されたセッションをすべて無効化する。
+	if passwordChanged {
+		handlers.RecordAuditEvent(req, audits.EventPasswordChanged, userId, user.SignInId.GetValue(), "", logger)
+		tokens := handlers.GetTokens()
+		currentId, _ := handlers.GetSessionId(req)
+		if parsedUser.KeepCurrentSession {
+			err = tokens.RevokeOtherSessions(userId, currentId)
+		} else {
+			err = tokens.RevokeAllSessions(userId)
+		}
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("Could not revoke sessions")
+		}
+		// 署名付きのアクセストークンはセッションを削除しても有効期限まで有効なため、これより前に発行されたものを認証しないようにする。
+		// リクエストしたセッションを維持する場合は、そのアクセストークンも認証できなくなるため、無効化しない。
+		if !parsedUser.KeepCurrentSession {
+			err = repos.RevokeTokens(userId, time.Now())
+			if err != nil {
+				logger.FPrintErrorLog(err, "")
+				return http.StatusInternalServerError, []byte("Could not revoke sessions")
+			}
+		}
+		// 古いパスワードを知る第三者が発行したパーソナルアクセストークンや、登録したクライアント証明書が残らないよう、すべて無効化する。
+		err = handlers.GetPersonalTokens().RevokeAll(userId)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("Could not revoke personal access tokens")
+		}
+		err = handlers.GetClientCertificates().RevokeAll(userId)
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("Could not revoke client certificates")
+		}
+	}
 	return http.StatusOK, []byte("")
 }
 
-// Get は、ユーザ情報を取得するためのハンドラです。
+// Get は、ユーザのプロフィールを取得するためのハンドラです。パスワードのハッシュ値などの認証情報は返却しません。
 func Get(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
-	if handlers.IsNotAuthenticate(req) {
+	if handlers.IsInsufficientScope(req, security.ScopeUserRead) {
+		handlers.SetInsufficientScope(writer, security.ScopeUserRead)
+		return http.StatusForbidden, []byte("Insufficient scope")
+	}
+	if handlers.IsNotAuthenticate(req, security.ScopeUserRead) {
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 	if req.Method != "GET" {
 		return http.StatusBadRequest, []byte("Bad request")
 	}
 
-	repos := dbUsers.NewUserRepository(db.NewDBConnector())
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
 	id, _ := handlers.GetUserId(req)
 	user, err := repos.FindByUserId(id)
 	if err != nil {
@@ -89,13 +257,25 @@ func Get(writer http.ResponseWriter, req *http.Request, logger *servers.Logger)
 		return http.StatusInternalServerError, []byte("Could not find user")
 	}
 
+	usage, err := dbBackups.NewBackupRepository(handlers.GetDBConnector(), handlers.GetKeyRing()).FindStorageUsage(id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not find storage usage")
+	}
+
 	// レスポンス用にオブジェクトを組み立てる。
-	resUser := userObj{
-		Password:   user.Password,
-		ScreenName: user.ScreenName,
-		SignInId:   user.SignInId.GetValue(),
+	resProfile := profileObj{
+		Id:               user.Id.GetValue(),
+		SignInId:         user.SignInId.GetValue(),
+		ScreenName:       user.ScreenName,
+		BackupCount:      usage.BackupCount,
+		StorageBytes:     usage.TotalBytes,
+		TwoFactorEnabled: user.TwoFactor.Enabled,
 	}
-	json, err := json.Marshal(resUser)
+	if !user.CreatedAt.IsZero() {
+		resProfile.CreatedAt = &user.CreatedAt
+	}
+	json, err := json.Marshal(resProfile)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not convert json.")
@@ -103,13 +283,13 @@ func Get(writer http.ResponseWriter, req *http.Request, logger *servers.Logger)
 	return http.StatusOK, json
 }
 
-// Create は、新規ユーザー作成用のハンドラです。
+// Create は、新規ユーザー作成用のハンドラです。作成したユーザのリカバリーコードを返却します。
 func Create(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
 	if handlers.IsNotJsonReq(req, "POST") {
 		return http.StatusBadRequest, []byte("Bad request")
 	}
 
-	repos := dbUsers.NewUserRepository(db.NewDBConnector())
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
 	user := userObj{}
 	err := handlers.ParseJson(req, &user)
 	if err != nil {
@@ -117,18 +297,24 @@ func Create(writer http.ResponseWriter, req *http.Request, logger *servers.Logge
 		return http.StatusInternalServerError, []byte("Could not parse json")
 	}
 
+	var violationErr *domainUsers.PolicyViolationError
+	if errors.As(handlers.GetPolicy().Validate(user.SignInId, user.Password), &violationErr) {
+		return responseViolations(violationErr.Violations, logger)
+	}
+
 	signInId, err := domainUsers.NewSignInId(user.SignInId)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte(err.Error())
 	}
 
-	_, err = repos.Create(signInId, user.Password, user.ScreenName)
+	created, err := repos.Create(signInId, user.Password, user.ScreenName)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not create user")
 	}
-	return http.StatusOK, []byte("")
+	// パスワードを忘れた場合に備えて、リカバリーコードを発行する。
+	return issueRecoveryCodes(repos, &created.Id, logger)
 }
 
 // Leave は、ユーザーを削除するためのハンドラです。
@@ -141,13 +327,31 @@ func Leave(writer http.ResponseWriter, req *http.Request, logger *servers.Logger
 	}
 
 	id, _ := handlers.GetUserId(req)
-	repos := dbUsers.NewUserRepository(db.NewDBConnector())
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
 	user, _ := repos.FindByUserId(id)
 	err := repos.Delete(&user.SignInId)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("Could not delete user")
 	}
+	handlers.RecordAuditEvent(req, audits.EventAccountDeleted, id, user.SignInId.GetValue(), "", logger)
+
+	// 削除したユーザのセッション、パーソナルアクセストークン、クライアント証明書をすべて無効化する。
+	err = handlers.GetTokens().RevokeAllSessions(id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke sessions")
+	}
+	err = handlers.GetPersonalTokens().RevokeAll(id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke personal access tokens")
+	}
+	err = handlers.GetClientCertificates().RevokeAll(id)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not revoke client certificates")
+	}
 	return http.StatusOK, []byte("")
 }
 
@@ -163,31 +367,138 @@ func Authenticate(writer http.ResponseWriter, req *http.Request, logger *servers
 		return http.StatusInternalServerError, []byte("Could not parse json")
 	}
 
-	repos := dbUsers.NewUserRepository(db.NewDBConnector())
-	signInId, _ := domainUsers.NewSignInId(authObj.SignInId)
+	// 失敗が続いているサインインID、リモートアドレスからの認証は、待ち時間が経過するまで受け付けない。
+	lockout := handlers.GetLockout()
+	retryAfter, err := lockout.Check(authObj.SignInId, req.RemoteAddr)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
+	if retryAfter > 0 {
+		handlers.SetRetryAfter(writer, retryAfter)
+		return http.StatusTooManyRequests, []byte("Too many failed attempts")
+	}
+
+	// 二要素認証の二段階目
+	if authObj.TwoFactorToken != "" {
+		return authenticateTwoFactor(writer, req, &authObj, logger)
+	}
+
+	// チャレンジ&レスポンス認証の場合は、ユーザを取得する前にナンスを消費する。照合に失敗しても、同じナンスは二度と使用できない。
+	if authObj.Proof != "" {
+		err = handlers.GetChallenges().Consume(authObj.Nonce, authObj.SignInId)
+		if err != nil {
+			return http.StatusUnauthorized, []byte("Invalid challenge")
+		}
+	}
+
+	repos := dbUsers.NewUserRepository(handlers.GetDBConnector())
+	signInId, err := domainUsers.NewSignInId(authObj.SignInId)
+	if err != nil {
+		recordAuthFailure(req, audits.EventSignInFailed, authObj.SignInId, nil, "unknown_sign_in_id", logger)
+		return http.StatusUnauthorized, []byte("Password is incorrect")
+	}
 	// ユーザ情報を取得
 	user, err := repos.FindBySignInId(signInId)
+	// 存在しないサインインIDも、パスワードが違う場合と同様に失敗として記録する。
+	// 応答時間からサインインIDが存在するかを推測されないよう、ダミーのハッシュ値で同じように照合してから返却する。
+	if errors.Is(err, sql.ErrNoRows) {
+		passwords := security.Passwords{}
+		dummy, err := passwords.DummyHash()
+		if err != nil {
+			logger.FPrintErrorLog(err, "")
+			return http.StatusInternalServerError, []byte("internal error")
+		}
+		verifyCredential(&authObj, dummy)
+		recordAuthFailure(req, audits.EventSignInFailed, authObj.SignInId, nil, "unknown_sign_in_id", logger)
+		return http.StatusUnauthorized, []byte("Password is incorrect")
+	}
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("internal error")
+	}
 
-	// リクエスト内のパスワードをハッシュ化
-	hash := security.Hash{}
-	password := hash.GetHash(authObj.Password)
+	// リクエスト内のパスワード、またはチャレンジに対するHMACを、データベース内のハッシュ値と照合
+	ok, needsRehash, err := verifyCredential(&authObj, user.Password)
 	if err != nil {
 		logger.FPrintErrorLog(err, "")
 		return http.StatusInternalServerError, []byte("internal error")
 	}
 
 	// パスワードが違う場合
-	if user.Password != password {
+	if !ok {
+		recordAuthFailure(req, audits.EventSignInFailed, authObj.SignInId, &user.Id, "incorrect_password", logger)
 		return http.StatusUnauthorized, []byte("Password is incorrect")
 	}
 
-	// アクセストークンを生成
-	tokens := security.Tokens{}
-	token := tokens.GenereteToken(&user.Id)
-	return http.StatusOK, []byte(token)
+	// 旧形式のハッシュ値や、設定が古いハッシュ値の場合は、現在の形式でハッシュ化し直して保存する。
+	if needsRehash {
+		err = repos.UpdatePassword(&user.Id, authObj.Password)
+		if err != nil {
+			logger.FPrintErrorLog(err, "could not rehash password")
+		}
+	}
+
+	// 二要素認証が有効な場合は、トークンを発行せずに、コードの入力を求める。
+	// 失敗の記録は、コードを照合するまでリセットしない。パスワードを知っている攻撃者が、コードを総当たりできないようにするためである。
+	if user.TwoFactor.Enabled {
+		return requireTwoFactor(authObj.SignInId, logger)
+	}
+	return completeAuthentication(writer, req, &authObj, user, logger)
 }
 
-// SignOut は、サインアウトするためのハンドラです。セッションを切断します。
+// recordAuthFailure は、サインインIDとリモートアドレスの認証の失敗を記録し、監査ログにも記録します。
+// userIdは、サインインIDに対応するユーザで、存在しない場合はnilです。
+func recordAuthFailure(req *http.Request, eventType audits.EventType, signInId string, userId *domainUsers.UserId, detail string, logger *servers.Logger) {
+	err := handlers.GetLockout().Fail(signInId, req.RemoteAddr)
+	if err != nil {
+		logger.FPrintErrorLog(err, "could not record failed attempt")
+	}
+	handlers.RecordAuditEvent(req, eventType, userId, signInId, detail, logger)
+}
+
+// completeAuthentication は、認証に成功したユーザの失敗の記録をリセットし、アクセストークンとリフレッシュトークンを発行します。
+// アカウントが停止されている場合は、トークンを発行しません。
+func completeAuthentication(writer http.ResponseWriter, req *http.Request, authObj *authenticationObj, user *domainUsers.User, logger *servers.Logger) (status int, body []byte) {
+	if user.Suspended {
+		handlers.RecordAuditEvent(req, audits.EventSignInFailed, &user.Id, authObj.SignInId, "account_suspended", logger)
+		return http.StatusForbidden, []byte("Account suspended")
+	}
+	err := handlers.GetLockout().Succeed(authObj.SignInId)
+	if err != nil {
+		logger.FPrintErrorLog(err, "could not reset failed attempts")
+	}
+
+	// アクセストークンとリフレッシュトークンを生成
+	tokens := handlers.GetTokens()
+	pair, err := tokens.GenereteToken(&user.Id, handlers.GetClientInfo(req))
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not generate token")
+	}
+	handlers.RecordAuditEvent(req, audits.EventSignInSucceeded, &user.Id, authObj.SignInId, "", logger)
+	return respondTokenPair(writer, pair, authObj.Cookie, logger)
+}
+
+// verifyCredential は、パスワード、またはチャレンジに対する証明を、保存済みのハッシュ値と照合します。
+// チャレンジ&レスポンス認証ではパスワードの平文を受け取らないため、ハッシュ化し直すことはできません。
+func verifyCredential(authObj *authenticationObj, stored string) (ok bool, needsRehash bool, err error) {
+	passwords := security.Passwords{}
+	if authObj.Proof == "" {
+		return passwords.Verify(authObj.Password, stored)
+	}
+	proof, err := base64.RawURLEncoding.DecodeString(authObj.Proof)
+	if err != nil {
+		return false, false, nil
+	}
+	ok, err = passwords.VerifyProof(stored, authObj.Nonce, proof)
+	if errors.Is(err, security.ErrChallengeNotSupported) {
+		return false, false, nil
+	}
+	return ok, false, err
+}
+
+// SignOut は、サインアウトするためのハンドラです。セッションを切断し、同じサインインで発行されたリフレッシュトークンも無効にします。
 func SignOut(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (status int, body []byte) {
 	if req.Method != "DELETE" {
 		return http.StatusBadRequest, []byte("bad request")
@@ -196,11 +507,19 @@ func SignOut(writer http.ResponseWriter, req *http.Request, logger *servers.Logg
 		return http.StatusUnauthorized, []byte("Unauthorized")
 	}
 
-	tokens := security.Tokens{}
-	token := req.Header.Get("Authorization")
+	tokens := handlers.GetTokens()
+	token := handlers.GetToken(req)
+	// トークンを無効化すると操作したユーザがわからなくなるため、先に記録する。
+	userId, _ := handlers.GetUserId(req)
+	handlers.RecordAuditEvent(req, audits.EventSignOut, userId, "", "", logger)
 
 	// トークンを削除（無効化）する
-	tokens.Invalidate(token)
+	err := tokens.Invalidate(token)
+	if err != nil {
+		logger.FPrintErrorLog(err, "")
+		return http.StatusInternalServerError, []byte("Could not sign out")
+	}
+	handlers.ClearTokenCookies(writer)
 	return http.StatusOK, []byte("")
 }
 
@@ -209,9 +528,27 @@ func GetHandlers() []servers.Handler {
 	return []servers.Handler{
 		{Pattern: "/user/modify", HandlerFunc: Modify},
 		{Pattern: "/user/auth", HandlerFunc: Authenticate},
+		{Pattern: "/user/auth/challenge", HandlerFunc: Challenge},
+		{Pattern: "/user/2fa/enroll", HandlerFunc: EnrollTwoFactor},
+		{Pattern: "/user/2fa/confirm", HandlerFunc: ConfirmTwoFactor},
+		{Pattern: "/user/2fa/disable", HandlerFunc: DisableTwoFactor},
+		{Pattern: "/user/token/refresh", HandlerFunc: RefreshToken},
 		{Pattern: "/user/create", HandlerFunc: Create},
 		{Pattern: "/user/leave", HandlerFunc: Leave},
 		{Pattern: "/user/signout", HandlerFunc: SignOut},
+		{Pattern: "/user/sessions", HandlerFunc: ListSessions},
+		{Pattern: "/user/sessions/revoke", HandlerFunc: RevokeSession},
+		{Pattern: "/user/sessions/others", HandlerFunc: RevokeOtherSessions},
+		{Pattern: "/user/tokens", HandlerFunc: ListPersonalTokens},
+		{Pattern: "/user/tokens/create", HandlerFunc: CreatePersonalToken},
+		{Pattern: "/user/tokens/revoke", HandlerFunc: RevokePersonalToken},
+		{Pattern: "/user/certificates", HandlerFunc: ListClientCertificates},
+		{Pattern: "/user/certificates/register", HandlerFunc: RegisterClientCertificate},
+		{Pattern: "/user/certificates/revoke", HandlerFunc: RevokeClientCertificate},
+		{Pattern: "/user/recovery-codes", HandlerFunc: CountRecoveryCodes},
+		{Pattern: "/user/recovery-codes/regenerate", HandlerFunc: RegenerateRecoveryCodes},
+		{Pattern: "/user/recover", HandlerFunc: Recover},
+		{Pattern: "/user/audit-events", HandlerFunc: ListAuditEvents},
 		{Pattern: "/user", HandlerFunc: Get},
 	}
 }
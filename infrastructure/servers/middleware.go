@@ -0,0 +1,4 @@
+package servers
+
+// Middleware は、ハンドラの前後に共通の処理を追加するために、ハンドラをラップする関数の型です。
+type Middleware func(next HandlerFunc) HandlerFunc
@@ -0,0 +1,196 @@
+package servers
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRoute は、ルートごとの制限が設定されていないハンドラで共有するバケットのルート名です。
+const defaultRoute = "*"
+
+// RateLimitRule は、トークンバケットによるリクエスト数の制限を表現する構造体です。
+// バケットには最大でBurst個のトークンが貯まり、Perの間にRequests個のトークンが補充されます。リクエストごとにトークンを1個消費します。
+type RateLimitRule struct {
+	// Requests は、Perの間に受け付けるリクエストの数です。0の場合は制限しません。
+	Requests int
+	Per      time.Duration
+	// Burst は、連続して受け付けられるリクエストの数です。0の場合は、Requestsと同じになります。
+	Burst int
+}
+
+// capacity は、バケットの容量を返却します。
+func (rule RateLimitRule) capacity() float64 {
+	if rule.Burst > 0 {
+		return float64(rule.Burst)
+	}
+	return float64(rule.Requests)
+}
+
+// ratePerSecond は、1秒あたりに補充されるトークンの数を返却します。
+func (rule RateLimitRule) ratePerSecond() float64 {
+	return float64(rule.Requests) / rule.Per.Seconds()
+}
+
+// RateLimitConfig は、リクエスト数の制限の設定を表現する構造体です。
+type RateLimitConfig struct {
+	// Default は、Routesに含まれないハンドラの制限です。それらのハンドラへのリクエストは、同じバケットのトークンを消費します。
+	Default RateLimitRule
+	// Routes は、ハンドラのパターンごとの制限です。
+	Routes map[string]RateLimitRule
+	// ReapInterval は、満杯になったバケットを破棄する間隔です。
+	ReapInterval time.Duration
+}
+
+// RateLimitResult は、リクエストを受け付けるかの判定結果を表現する構造体です。
+type RateLimitResult struct {
+	Allowed bool
+	// Limit は、バケットの容量です。
+	Limit int
+	// Remaining は、バケットに残っているトークンの数です。
+	Remaining int
+	// ResetAfter は、バケットが満杯になるまでの時間です。
+	ResetAfter time.Duration
+	// RetryAfter は、拒否した場合に、次にリクエストを受け付けられるまでの時間です。
+	RetryAfter time.Duration
+}
+
+// bucket は、トークンバケットを表現する構造体です。
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rule      RateLimitRule
+}
+
+// refill は、最後に更新してから経過した時間に応じて、トークンを補充します。
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.rule.capacity(), b.tokens+elapsed*b.rule.ratePerSecond())
+		b.updatedAt = now
+	}
+}
+
+// RateLimiter は、トークンバケットで、クライアントごとのリクエスト数を制限する構造体です。
+// クライアントは、keyFuncがリクエストから返却するキーで区別します。
+type RateLimiter struct {
+	config     RateLimitConfig
+	keyFunc    func(req *http.Request) string
+	clock      security.IClock
+	mutex      sync.Mutex
+	buckets    map[string]*bucket
+	rejections map[string]uint64
+}
+
+// Allow は、パターンのハンドラへの、キーのクライアントからのリクエストを受け付けるかを判定し、受け付ける場合はトークンを消費します。
+func (limiter *RateLimiter) Allow(pattern string, key string) (result RateLimitResult) {
+	route, rule := limiter.rule(pattern)
+	if rule.Requests <= 0 {
+		return RateLimitResult{Allowed: true}
+	}
+	now := limiter.clock.Now()
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	bucketKey := route + " " + key
+	b, ok := limiter.buckets[bucketKey]
+	if !ok {
+		b = &bucket{tokens: rule.capacity(), updatedAt: now, rule: rule}
+		limiter.buckets[bucketKey] = b
+	}
+	b.refill(now)
+
+	result.Limit = int(rule.capacity())
+	rate := rule.ratePerSecond()
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+		limiter.rejections[pattern]++
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.ResetAfter = secondsToDuration((rule.capacity() - b.tokens) / rate)
+	return result
+}
+
+// Middleware は、リクエスト数が制限を超えたクライアントに、429 Too Many Requestsを返却するミドルウェアです。
+// 制限があるハンドラのレスポンスには、RateLimit-Limit、RateLimit-Remaining、RateLimit-Resetヘッダを設定します。
+func (limiter *RateLimiter) Middleware(next HandlerFunc) HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request, logger *Logger) (status int, body []byte) {
+		result := limiter.Allow(GetPattern(req), limiter.keyFunc(req))
+		if result.Limit > 0 {
+			header := writer.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.ResetAfter), 10))
+		}
+		if !result.Allowed {
+			writer.Header().Set("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			return http.StatusTooManyRequests, []byte("Too many requests")
+		}
+		return next(writer, req, logger)
+	}
+}
+
+// Rejections は、ハンドラのパターンごとの、拒否したリクエストの数を返却します。
+func (limiter *RateLimiter) Rejections() map[string]uint64 {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	rejections := make(map[string]uint64, len(limiter.rejections))
+	for pattern, count := range limiter.rejections {
+		rejections[pattern] = count
+	}
+	return rejections
+}
+
+// Reap は、満杯になったバケットを破棄します。破棄したバケットは、次のリクエストで満杯の状態から作り直されるため、結果は変わりません。
+func (limiter *RateLimiter) Reap() error {
+	now := limiter.clock.Now()
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	for key, b := range limiter.buckets {
+		b.refill(now)
+		if b.tokens >= b.rule.capacity() {
+			delete(limiter.buckets, key)
+		}
+	}
+	return nil
+}
+
+// StartReaper は、満杯になったバケットを定期的に破棄するゴルーチンを開始します。返却された関数を呼び出すと停止します。
+func (limiter *RateLimiter) StartReaper() (stop func()) {
+	return security.StartTicker(limiter.config.ReapInterval, func() { limiter.Reap() })
+}
+
+// rule は、パターンのハンドラに適用する制限と、バケットを共有するルート名を返却します。
+func (limiter *RateLimiter) rule(pattern string) (route string, rule RateLimitRule) {
+	if rule, ok := limiter.config.Routes[pattern]; ok {
+		return pattern, rule
+	}
+	return defaultRoute, limiter.config.Default
+}
+
+// secondsToDuration は、秒数をtime.Durationに変換します。
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// ceilSeconds は、ヘッダに設定するために、時間を秒単位に切り上げます。
+func ceilSeconds(duration time.Duration) int64 {
+	return int64((duration + time.Second - 1) / time.Second)
+}
+
+// NewRateLimiter は、RateLimiter構造体を初期化し、返却します。keyFuncは、リクエストからクライアントを区別するキーを返却する関数です。
+func NewRateLimiter(config RateLimitConfig, keyFunc func(req *http.Request) string, clock security.IClock) *RateLimiter {
+	return &RateLimiter{
+		config:     config,
+		keyFunc:    keyFunc,
+		clock:      clock,
+		buckets:    make(map[string]*bucket),
+		rejections: make(map[string]uint64),
+	}
+}
@@ -0,0 +1,141 @@
+package servers_test
+
+import (
+	"FrogNote_database/infrastructure/servers"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestRateLimiter は、テスト用の時計で動作するRateLimiterを返却します。
+func newTestRateLimiter() (*servers.RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}
+	config := servers.RateLimitConfig{
+		Default: servers.RateLimitRule{Requests: 3, Per: 3 * time.Second},
+		Routes: map[string]servers.RateLimitRule{
+			"/user/create": {Requests: 1, Per: time.Hour},
+			"/user/health": {Requests: 0},
+		},
+		ReapInterval: time.Minute,
+	}
+	keyFunc := func(req *http.Request) string { return req.RemoteAddr }
+	return servers.NewRateLimiter(config, keyFunc, clock), clock
+}
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("トークンを使い切ると拒否され、時間が経つと補充される", func(t *testing.T) {
+		limiter, clock := newTestRateLimiter()
+		for i := 0; i < 3; i++ {
+			if result := limiter.Allow("/backup/list", "frog"); !result.Allowed || result.Remaining != 2-i {
+				t.Fatalf("%d回目のリクエストの結果が不正です。%+v", i+1, result)
+			}
+		}
+		result := limiter.Allow("/backup/list", "frog")
+		if result.Allowed || result.RetryAfter != time.Second {
+			t.Fatalf("拒否されるべきリクエストの結果が不正です。%+v", result)
+		}
+		clock.Advance(time.Second)
+		if result := limiter.Allow("/backup/list", "frog"); !result.Allowed {
+			t.Fatalf("補充されたトークンで受け付けられませんでした。%+v", result)
+		}
+	})
+
+	t.Run("クライアントごとにバケットが分かれる", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter()
+		limiter.Allow("/user/create", "frog")
+		if result := limiter.Allow("/user/create", "frog"); result.Allowed {
+			t.Fatal("制限を超えたリクエストが受け付けられました。")
+		}
+		if result := limiter.Allow("/user/create", "toad"); !result.Allowed {
+			t.Fatal("別のクライアントのリクエストが拒否されました。")
+		}
+	})
+
+	t.Run("ルートごとの制限はデフォルトのバケットと独立している", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter()
+		limiter.Allow("/user/create", "frog")
+		if result := limiter.Allow("/backup/list", "frog"); !result.Allowed || result.Remaining != 2 {
+			t.Fatalf("デフォルトのバケットが消費されています。%+v", result)
+		}
+		if result := limiter.Allow("/backup/save", "frog"); result.Remaining != 1 {
+			t.Fatalf("デフォルトのバケットが共有されていません。%+v", result)
+		}
+	})
+
+	t.Run("Requestsが0のルートは制限されない", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter()
+		for i := 0; i < 10; i++ {
+			if result := limiter.Allow("/user/health", "frog"); !result.Allowed || result.Limit != 0 {
+				t.Fatalf("制限されないはずのリクエストの結果が不正です。%+v", result)
+			}
+		}
+	})
+
+	t.Run("拒否したリクエストの数がパターンごとに記録される", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter()
+		for i := 0; i < 3; i++ {
+			limiter.Allow("/user/create", "frog")
+		}
+		if rejections := limiter.Rejections(); rejections["/user/create"] != 2 || len(rejections) != 1 {
+			t.Fatalf("拒否したリクエストの数が不正です。%v", rejections)
+		}
+	})
+
+	t.Run("満杯になったバケットを破棄しても結果は変わらない", func(t *testing.T) {
+		limiter, clock := newTestRateLimiter()
+		limiter.Allow("/user/create", "frog")
+		limiter.Reap()
+		if result := limiter.Allow("/user/create", "frog"); result.Allowed {
+			t.Fatal("満杯でないバケットが破棄されました。")
+		}
+		clock.Advance(time.Hour)
+		limiter.Reap()
+		if result := limiter.Allow("/user/create", "frog"); !result.Allowed {
+			t.Fatal("補充されたバケットで受け付けられませんでした。")
+		}
+	})
+
+	t.Run("ミドルウェアは429とヘッダを返却する", func(t *testing.T) {
+		limiter, _ := newTestRateLimiter()
+		next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+			return http.StatusOK, nil
+		}
+		handler := limiter.Middleware(next)
+		req := httptest.NewRequest(http.MethodGet, "/backup/list", nil)
+		req.RemoteAddr = "192.0.2.1"
+		for i := 0; i < 3; i++ {
+			if status, _ := handler(httptest.NewRecorder(), req, nil); status != http.StatusOK {
+				t.Fatalf("%d回目のリクエストが拒否されました。", i+1)
+			}
+		}
+		recorder := httptest.NewRecorder()
+		status, _ := handler(recorder, req, nil)
+		if status != http.StatusTooManyRequests {
+			t.Fatalf("ステータスコードが不正です。%d", status)
+		}
+		header := recorder.Header()
+		if header.Get("Retry-After") != "1" || header.Get("RateLimit-Limit") != "3" ||
+			header.Get("RateLimit-Remaining") != "0" || header.Get("RateLimit-Reset") != "3" {
+			t.Fatalf("ヘッダが不正です。%v", header)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package servers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// SecurityHeadersConfig は、セキュリティに関するレスポンスヘッダの設定を表現する構造体です。
+type SecurityHeadersConfig struct {
+	// ContentSecurityPolicy は、HTMLのレスポンスに設定するContent-Security-Policyヘッダの値です。空文字列の場合は設定しません。
+	ContentSecurityPolicy string
+	// ReferrerPolicy は、Referrer-Policyヘッダの値です。空文字列の場合は設定しません。
+	ReferrerPolicy string
+	// StripHeaders は、サーバを特定できる情報を含むため、レスポンスから削除するヘッダです。
+	StripHeaders []string
+}
+
+// SecurityHeaders は、セキュリティに関するレスポンスヘッダを設定するミドルウェアを返却します。
+// authenticatedは、リクエストが認証情報を含むかを判定する関数です。認証情報を含むリクエストのレスポンスは、キャッシュさせません。
+func SecurityHeaders(config SecurityHeadersConfig, authenticated func(req *http.Request) bool) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(writer http.ResponseWriter, req *http.Request, logger *Logger) (status int, body []byte) {
+			status, body = next(writer, req, logger)
+			header := writer.Header()
+
+			// ブラウザがレスポンスの内容から種類を推測しないよう、Content-Typeを明示する。
+			if header.Get("Content-Type") == "" && len(body) > 0 {
+				header.Set("Content-Type", detectContentType(body))
+			}
+			header.Set("X-Content-Type-Options", "nosniff")
+			if config.ContentSecurityPolicy != "" && strings.HasPrefix(header.Get("Content-Type"), "text/html") {
+				header.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+			}
+			if config.ReferrerPolicy != "" {
+				header.Set("Referrer-Policy", config.ReferrerPolicy)
+			}
+			// 認証されたユーザのデータや、クッキーで発行したトークンが、共有キャッシュに保存されないようにする。
+			if header.Get("Cache-Control") == "" && (authenticated(req) || header.Get("Set-Cookie") != "") {
+				header.Set("Cache-Control", "no-store")
+			}
+			for _, name := range config.StripHeaders {
+				header.Del(name)
+			}
+			return status, body
+		}
+	}
+}
+
+// detectContentType は、ハンドラが返却したレスポンスボディのContent-Typeを返却します。
+// ハンドラはJSONか、エラーメッセージなどの文字列を返却するので、JSONのオブジェクトか配列以外は文字列として扱います。
+func detectContentType(body []byte) string {
+	if (body[0] == '{' || body[0] == '[') && json.Valid(body) {
+		return "application/json; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
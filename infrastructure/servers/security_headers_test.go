@@ -0,0 +1,100 @@
+package servers_test
+
+import (
+	"FrogNote_database/infrastructure/servers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serveWithSecurityHeaders は、ハンドラをSecurityHeadersでラップして呼び出し、レスポンスヘッダを返却します。
+func serveWithSecurityHeaders(next servers.HandlerFunc, authenticated bool) http.Header {
+	config := servers.SecurityHeadersConfig{
+		ContentSecurityPolicy: "default-src 'none'",
+		ReferrerPolicy:        "no-referrer",
+		StripHeaders:          []string{"Server", "X-Powered-By"},
+	}
+	handler := servers.SecurityHeaders(config, func(req *http.Request) bool { return authenticated })(next)
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, "/user", nil), nil)
+	return recorder.Header()
+}
+
+// respond は、ステータスコード200とbodyを返却するハンドラを返却します。
+func respond(body string) servers.HandlerFunc {
+	return func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+		return http.StatusOK, []byte(body)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Run("レスポンスボディに応じてContent-Typeを設定する", func(t *testing.T) {
+		cases := map[string]string{
+			`{"id": 1}`:   "application/json; charset=utf-8",
+			`[1, 2]`:      "application/json; charset=utf-8",
+			"Bad request": "text/plain; charset=utf-8",
+			"{broken":     "text/plain; charset=utf-8",
+			"":            "",
+		}
+		for body, expected := range cases {
+			if header := serveWithSecurityHeaders(respond(body), false); header.Get("Content-Type") != expected {
+				t.Errorf("%sのContent-Typeが%sではありません。%s", body, expected, header.Get("Content-Type"))
+			}
+		}
+	})
+
+	t.Run("ハンドラが設定したContent-Typeは上書きしない", func(t *testing.T) {
+		next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+			writer.Header().Set("Content-Type", "application/octet-stream")
+			return http.StatusOK, []byte("{}")
+		}
+		if header := serveWithSecurityHeaders(next, false); header.Get("Content-Type") != "application/octet-stream" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("常に設定するヘッダ", func(t *testing.T) {
+		header := serveWithSecurityHeaders(respond("{}"), false)
+		if header.Get("X-Content-Type-Options") != "nosniff" || header.Get("Referrer-Policy") != "no-referrer" {
+			t.Error(header)
+		}
+		if header.Get("Content-Security-Policy") != "" || header.Get("Cache-Control") != "" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("HTMLにはContent-Security-Policyを設定する", func(t *testing.T) {
+		next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			return http.StatusOK, []byte("<p>frog</p>")
+		}
+		if header := serveWithSecurityHeaders(next, false); header.Get("Content-Security-Policy") != "default-src 'none'" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("認証されたリクエストとクッキーを発行したレスポンスはキャッシュさせない", func(t *testing.T) {
+		if header := serveWithSecurityHeaders(respond("{}"), true); header.Get("Cache-Control") != "no-store" {
+			t.Error(header)
+		}
+		next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+			http.SetCookie(writer, &http.Cookie{Name: "frognote_access", Value: "token"})
+			return http.StatusOK, nil
+		}
+		if header := serveWithSecurityHeaders(next, false); header.Get("Cache-Control") != "no-store" {
+			t.Error(header)
+		}
+	})
+
+	t.Run("サーバを特定できるヘッダを削除する", func(t *testing.T) {
+		next := func(writer http.ResponseWriter, req *http.Request, logger *servers.Logger) (int, []byte) {
+			writer.Header().Set("Server", "Go")
+			writer.Header().Set("X-Powered-By", "Go")
+			return http.StatusOK, nil
+		}
+		header := serveWithSecurityHeaders(next, false)
+		if header.Get("Server") != "" || header.Get("X-Powered-By") != "" {
+			t.Error(header)
+		}
+	})
+}
@@ -1,6 +1,9 @@
 package servers
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +13,14 @@ import (
 
 // Server 構造体は、サーバの基本操作を提供します。
 type Server struct {
-	port   int
-	logger *Logger
+	port        int
+	logger      *Logger
+	handlers    []Handler
+	middlewares []Middleware
+	tlsConfig   *TLSConfig
+	reloader    *CertificateReloader
+	// clientCAs は、相互TLS認証でクライアント証明書を検証する認証局です。nilの場合は、相互TLS認証で待ち受けません。
+	clientCAs *x509.CertPool
 }
 
 // Start はサーバをスタートし、HTTPリクエストを受け付けられる状態にします。
@@ -32,32 +41,125 @@ func (s *Server) Start() {
 			s.logger.FPrintAccessLog(r, &responseLog)
 		})
 	}
-	s.logger.Println("start server.")
-	err := http.ListenAndServe(fmt.Sprintf(":%d", s.port), loggingHandler(http.DefaultServeMux))
+	mux := http.NewServeMux()
+	for _, handler := range s.handlers {
+		mux.HandleFunc(handler.Pattern, s.serve(handler.Pattern, s.applyMiddlewares(handler.HandlerFunc)))
+	}
+	if s.tlsConfig == nil {
+		s.logger.Println("start server.")
+		err := http.ListenAndServe(fmt.Sprintf(":%d", s.port), loggingHandler(mux))
+		if err != nil {
+			log.Fatal("ListenAndServe", err)
+		}
+		return
+	}
+	s.startTLS(loggingHandler(mux))
+}
+
+// startTLS は、HTTPSでリクエストを受け付けます。設定に応じて、HTTPからのリダイレクトと証明書の読み込み直しも開始します。
+func (s *Server) startTLS(handler http.Handler) {
+	if s.tlsConfig.HSTSMaxAge > 0 {
+		handler = hstsHandler(handler, s.tlsConfig.HSTSMaxAge, s.tlsConfig.HSTSIncludeSubDomains)
+	}
+	if s.tlsConfig.ReloadInterval > 0 {
+		stopWatcher := s.reloader.StartWatcher(s.tlsConfig.ReloadInterval, s.logger)
+		defer stopWatcher()
+	}
+	if s.tlsConfig.RedirectPort != 0 {
+		go func() {
+			err := http.ListenAndServe(fmt.Sprintf(":%d", s.tlsConfig.RedirectPort), redirectHandler(s.port))
+			if err != nil {
+				log.Fatal("ListenAndServe", err)
+			}
+		}()
+	}
+	if s.clientCAs != nil {
+		go func() {
+			// 検証されたクライアント証明書は、リクエストのTLS.VerifiedChainsからハンドラが参照する。
+			clientAuthServer := &http.Server{
+				Addr:    fmt.Sprintf(":%d", s.tlsConfig.ClientAuthPort),
+				Handler: handler,
+				TLSConfig: &tls.Config{
+					MinVersion:     tls.VersionTLS12,
+					GetCertificate: s.reloader.GetCertificate,
+					ClientAuth:     tls.RequireAndVerifyClientCert,
+					ClientCAs:      s.clientCAs,
+				},
+			}
+			s.logger.Println("start server with mutual TLS.")
+			err := clientAuthServer.ListenAndServeTLS("", "")
+			if err != nil {
+				log.Fatal("ListenAndServeTLS", err)
+			}
+		}()
+	}
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.port),
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: s.reloader.GetCertificate,
+		},
+	}
+	s.logger.Println("start server with TLS.")
+	err := server.ListenAndServeTLS("", "")
 	if err != nil {
-		log.Fatal("ListenAndServe", err)
+		log.Fatal("ListenAndServeTLS", err)
 	}
 }
 
-// AddHandlers は、サーバにハンドラを追加します。
+// UseTLS は、HTTPSでサーブするよう設定し、証明書と秘密鍵のファイルを読み込みます。
+// ClientAuthPortを指定した場合は、クライアント証明書を検証する認証局の証明書も読み込みます。
+func (s *Server) UseTLS(config TLSConfig) error {
+	if config.RedirectPort > 65535 || config.RedirectPort < 0 {
+		return fmt.Errorf("redirect port must be (0 ~ 65535)")
+	}
+	if config.ClientAuthPort > 65535 || config.ClientAuthPort < 0 {
+		return fmt.Errorf("client auth port must be (0 ~ 65535)")
+	}
+	reloader, err := NewCertificateReloader(config.CertFile, config.KeyFile)
+	if err != nil {
+		return err
+	}
+	var clientCAs *x509.CertPool
+	if config.ClientAuthPort != 0 {
+		if clientCAs, err = LoadClientCAs(config.ClientCAFile); err != nil {
+			return err
+		}
+	}
+	s.tlsConfig = &config
+	s.reloader = reloader
+	s.clientCAs = clientCAs
+	return nil
+}
+
+// AddHandlers は、サーバにハンドラを追加します。ハンドラは、Startを呼び出した際に登録されます。
 func (s *Server) AddHandlers(handlers []Handler) {
-	for _, handler := range handlers {
-		handleFunc := handler.HandlerFunc
-		http.HandleFunc(handler.Pattern, func(w http.ResponseWriter, r *http.Request) {
-			// CORS用設定
-			w.Header().Set("Access-Control-Allow-Origin", "http://localhost:5173")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type,Authorization")
-			w.Header().Set("Access-Control-Allow-Methods", "POST,GET,DELETE,OPTIONS,PATCH")
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+	s.handlers = append(s.handlers, handlers...)
+}
 
-			status, body := handleFunc(w, r, s.logger)
-			w.WriteHeader(status)
-			w.Header().Set("Content-Length", fmt.Sprint(len(body)))
-			w.Write(body)
-		})
+// Use は、すべてのハンドラに適用するミドルウェアを追加します。先に追加したミドルウェアほど外側で実行されます。
+func (s *Server) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+// applyMiddlewares は、ハンドラをミドルウェアでラップします。
+func (s *Server) applyMiddlewares(handleFunc HandlerFunc) HandlerFunc {
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		handleFunc = s.middlewares[i](handleFunc)
+	}
+	return handleFunc
+}
+
+// serve は、ハンドラの返却したステータスコードとレスポンスボディを書き込むhttp.HandlerFuncを返却します。
+// ミドルウェアがルートごとに処理を変えられるよう、リクエストのコンテキストにハンドラのパターンを設定します。
+func (s *Server) serve(pattern string, handleFunc HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), patternKey{}, pattern))
+		status, body := handleFunc(w, r, s.logger)
+		w.WriteHeader(status)
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Write(body)
 	}
 }
 
@@ -0,0 +1,202 @@
+package servers
+
+import (
+	"FrogNote_database/infrastructure/security"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TLSConfig は、HTTPSでサーブするための設定を表現する構造体です。
+type TLSConfig struct {
+	// CertFile は、PEM形式の証明書(中間証明書を含む)のファイルのパスです。
+	CertFile string
+	// KeyFile は、PEM形式の秘密鍵のファイルのパスです。
+	KeyFile string
+	// RedirectPort は、HTTPのリクエストをHTTPSにリダイレクトするためにHTTPで待ち受けるポート番号です。0の場合は待ち受けません。
+	RedirectPort int
+	// HSTSMaxAge は、Strict-Transport-Securityヘッダのmax-ageです。0の場合はヘッダを設定しません。
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubDomains は、Strict-Transport-SecurityヘッダにincludeSubDomainsを付与するかを表します。
+	HSTSIncludeSubDomains bool
+	// ReloadInterval は、証明書と秘密鍵のファイルの更新を確認する間隔です。0の場合は確認しません。
+	ReloadInterval time.Duration
+	// ClientAuthPort は、クライアント証明書を要求する相互TLS認証で待ち受けるポート番号です。0の場合は待ち受けません。
+	ClientAuthPort int
+	// ClientCAFile は、クライアント証明書を検証するための、PEM形式の認証局の証明書のファイルのパスです。
+	ClientCAFile string
+}
+
+// CertificateReloader は、証明書と秘密鍵のファイルを読み込み、ファイルが更新された場合に読み込み直す構造体です。
+// 読み込み直しに失敗した場合は、それまでの証明書を使用し続けます。
+type CertificateReloader struct {
+	certFile    string
+	keyFile     string
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	modTimes    [2]time.Time
+}
+
+// GetCertificate は、現在の証明書を返却します。tls.ConfigのGetCertificateに指定します。
+func (reloader *CertificateReloader) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.RLock()
+	defer reloader.mutex.RUnlock()
+	return reloader.certificate, nil
+}
+
+// Reload は、証明書と秘密鍵のファイルが前回の読み込みから更新されていれば読み込み直します。読み込み直した場合はtrueを返却します。
+func (reloader *CertificateReloader) Reload() (reloaded bool, err error) {
+	modTimes, err := reloader.getModTimes()
+	if err != nil {
+		return false, err
+	}
+	reloader.mutex.RLock()
+	unchanged := reloader.certificate != nil && modTimes == reloader.modTimes
+	reloader.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return false, err
+	}
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	reloader.certificate = &certificate
+	reloader.modTimes = modTimes
+	return true, nil
+}
+
+// StartWatcher は、証明書と秘密鍵のファイルの更新を定期的に確認し、読み込み直すゴルーチンを開始します。返却された関数を呼び出すと停止します。
+func (reloader *CertificateReloader) StartWatcher(interval time.Duration, logger *Logger) (stop func()) {
+	return security.StartTicker(interval, func() {
+		reloaded, err := reloader.Reload()
+		if err != nil {
+			logger.FPrintErrorLog(err, "could not reload the certificate")
+		} else if reloaded {
+			logger.Println("reloaded the certificate.")
+		}
+	})
+}
+
+// getModTimes は、証明書と秘密鍵のファイルの更新日時を返却します。
+func (reloader *CertificateReloader) getModTimes() (modTimes [2]time.Time, err error) {
+	for i, path := range []string{reloader.certFile, reloader.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return modTimes, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// NewCertificateReloader は、証明書と秘密鍵のファイルを読み込み、CertificateReloader構造体を返却します。
+func NewCertificateReloader(certFile string, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// LoadClientCAs は、クライアント証明書を検証するための、PEM形式の認証局の証明書をファイルから読み込みます。
+// 証明書が1つも含まれていない場合は、エラーを返却します。
+func LoadClientCAs(caFile string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// GenerateSelfSignedCertificate は、ローカルでの開発用に、localhostの自己署名証明書と秘密鍵を生成し、PEM形式でファイルに書き込みます。
+// 証明書は1年間有効です。本番環境では使用しないでください。
+func GenerateSelfSignedCertificate(certFile string, keyFile string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"FrogNote development"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDer, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// 秘密鍵を先に書き込み、証明書のみが存在する状態にならないようにする。
+	if err = writePemFile(keyFile, "PRIVATE KEY", keyDer, 0600); err != nil {
+		return err
+	}
+	return writePemFile(certFile, "CERTIFICATE", certDer, 0644)
+}
+
+// writePemFile は、derをPEM形式でファイルに書き込みます。
+func writePemFile(path string, blockType string, der []byte, perm os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm)
+}
+
+// hstsHandler は、すべてのレスポンスにStrict-Transport-Securityヘッダを設定するhttp.Handlerを返却します。
+func hstsHandler(h http.Handler, maxAge time.Duration, includeSubDomains bool) http.Handler {
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if includeSubDomains {
+		value += "; includeSubDomains"
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", value)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// redirectHandler は、HTTPのリクエストを、同じホストのポートのHTTPSにリダイレクトするhttp.Handlerを返却します。
+// メソッドとリクエストボディが維持されるよう、308 Permanent Redirectを返却します。
+func redirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = strings.Trim(r.Host, "[]")
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		http.Redirect(w, r, fmt.Sprintf("https://%s%s", host, r.URL.RequestURI()), http.StatusPermanentRedirect)
+	})
+}
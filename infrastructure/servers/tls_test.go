@@ -0,0 +1,129 @@
+package servers_test
+
+import (
+	"FrogNote_database/infrastructure/servers"
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	t.Run("自己署名証明書を生成して読み込める", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		if err := servers.GenerateSelfSignedCertificate(certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+		reloader, err := servers.NewCertificateReloader(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		certificate, _ := reloader.GetCertificate(nil)
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = leaf.VerifyHostname("localhost"); err != nil {
+			t.Error(err)
+		}
+		if info, _ := os.Stat(keyFile); info.Mode().Perm() != 0600 {
+			t.Error(info.Mode())
+		}
+	})
+
+	t.Run("ファイルが更新された場合のみ読み込み直す", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		if err := servers.GenerateSelfSignedCertificate(certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+		reloader, err := servers.NewCertificateReloader(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reloaded, err := reloader.Reload(); reloaded || err != nil {
+			t.Fatal("更新されていないファイルを読み込み直しました。", err)
+		}
+		before, _ := reloader.GetCertificate(nil)
+
+		if err = servers.GenerateSelfSignedCertificate(certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		for _, path := range []string{certFile, keyFile} {
+			if err = os.Chtimes(path, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if reloaded, err := reloader.Reload(); !reloaded || err != nil {
+			t.Fatal("更新されたファイルを読み込み直しませんでした。", err)
+		}
+		after, _ := reloader.GetCertificate(nil)
+		if bytes.Equal(before.Certificate[0], after.Certificate[0]) {
+			t.Error("証明書が変わっていません。")
+		}
+	})
+
+	t.Run("読み込み直しに失敗した場合は以前の証明書を使用し続ける", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+		if err := servers.GenerateSelfSignedCertificate(certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+		reloader, err := servers.NewCertificateReloader(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before, _ := reloader.GetCertificate(nil)
+		if err = os.WriteFile(certFile, []byte("broken"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		later := time.Now().Add(time.Minute)
+		if err = os.Chtimes(certFile, later, later); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = reloader.Reload(); err == nil {
+			t.Fatal("壊れた証明書を読み込めました。")
+		}
+		if after, _ := reloader.GetCertificate(nil); after != before {
+			t.Error("以前の証明書が破棄されました。")
+		}
+	})
+
+	t.Run("ファイルが存在しない場合はエラーになる", func(t *testing.T) {
+		dir := t.TempDir()
+		if _, err := servers.NewCertificateReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")); err == nil {
+			t.Error()
+		}
+	})
+}
+
+func TestLoadClientCAs(t *testing.T) {
+	t.Run("認証局の証明書を読み込める", func(t *testing.T) {
+		dir := t.TempDir()
+		certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "ca-key.pem")
+		if err := servers.GenerateSelfSignedCertificate(certFile, keyFile); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := servers.LoadClientCAs(certFile); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("証明書を含まないファイルはエラーになる", func(t *testing.T) {
+		dir := t.TempDir()
+		caFile := filepath.Join(dir, "ca.pem")
+		if err := os.WriteFile(caFile, []byte("broken"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := servers.LoadClientCAs(caFile); err == nil {
+			t.Error("証明書を含まないファイルを読み込めました。")
+		}
+		if _, err := servers.LoadClientCAs(filepath.Join(dir, "missing.pem")); err == nil {
+			t.Error("存在しないファイルを読み込めました。")
+		}
+	})
+}
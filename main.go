@@ -437,21 +437,329 @@ Exhibit B - "Incompatible With Secondary Licenses" Notice
   defined by the Mozilla Public License, v. 2.0.
 */
 import (
+	domainUsers "FrogNote_database/domain/users"
+	"FrogNote_database/infrastructure/configs"
+	"FrogNote_database/infrastructure/db"
+	"FrogNote_database/infrastructure/db/attempts"
+	dbBackups "FrogNote_database/infrastructure/db/backups"
+	"FrogNote_database/infrastructure/db/clientcertificates"
+	"FrogNote_database/infrastructure/db/personaltokens"
+	"FrogNote_database/infrastructure/db/sessions"
+	"FrogNote_database/infrastructure/security"
 	"FrogNote_database/infrastructure/servers"
+	"FrogNote_database/infrastructure/servers/handlers"
+	"FrogNote_database/infrastructure/servers/handlers/admin"
 	"FrogNote_database/infrastructure/servers/handlers/backups"
 	"FrogNote_database/infrastructure/servers/handlers/users"
 	"fmt"
+	"net/http"
+	"os"
+	"regexp"
 )
 
 // main は、エントリポイントです。
 func main() {
+	config, err := configs.Load(configs.GetConfigPath())
+	if err != nil {
+		fmt.Println("Could not load config.", err)
+		return
+	}
+
+	// バックアップデータを暗号化するマスター鍵を読み込む。
+	keyRing, err := newKeyRing(config)
+	if err != nil {
+		fmt.Println("Could not load master keys.", err)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rotate-master-key" {
+		rotateMasterKey(keyRing)
+		return
+	}
+	if keyRing == nil {
+		fmt.Println("Backup encryption is disabled. Configure encryption.masterKeys to encrypt backups.")
+	}
+	handlers.UseKeyRing(keyRing)
+
+	// セッションの有効期限を設定し、期限切れのセッションを定期的に破棄する。
+	sessionConfig := security.SessionConfig{
+		AbsoluteTimeout:    config.Sessions.AbsoluteTimeout.Duration,
+		IdleTimeout:        config.Sessions.IdleTimeout.Duration,
+		AccessTokenTimeout: config.Sessions.AccessTokenTimeout.Duration,
+		ReapInterval:       config.Sessions.ReapInterval.Duration,
+	}
+	tokens, err := newTokens(config, sessionConfig)
+	if err != nil {
+		fmt.Println("Could not load signing keys.", err)
+		return
+	}
+	handlers.UseTokens(tokens)
+
+	policy, err := newPolicy(config)
+	if err != nil {
+		fmt.Println("Could not load password policy.", err)
+		return
+	}
+	handlers.UsePolicy(policy)
+	stopReaper := tokens.StartReaper()
+	defer stopReaper()
+	handlers.UseChallenges(security.NewChallenges(config.Auth.ChallengeTimeout.Duration, security.SystemClock{}))
+	handlers.UseTwoFactorChallenges(security.NewChallenges(config.Auth.TwoFactorTimeout.Duration, security.SystemClock{}))
+
+	// パーソナルアクセストークンを発行・検証し、期限切れのトークンを定期的に破棄する。
+	personalTokens := newPersonalTokens(config)
+	handlers.UsePersonalTokens(personalTokens)
+	stopPersonalTokenReaper := personalTokens.StartReaper(config.Sessions.ReapInterval.Duration)
+	defer stopPersonalTokenReaper()
+
+	// 相互TLS認証で検証されたクライアント証明書を、登録したユーザに対応付ける。
+	handlers.UseClientCertificates(newClientCertificates(config))
+
+	// 認証の失敗を記録し、失敗が続いたサインインIDやリモートアドレスをロックアウトする。
+	lockout := newLockout(config)
+	handlers.UseLockout(lockout)
+	stopLockoutReaper := lockout.StartReaper()
+	defer stopLockoutReaper()
+
+	handlers.UseCookieConfig(newCookieConfig(config))
+
+	// ユーザIDやリモートアドレスごとにリクエスト数を制限し、満杯になったバケットを定期的に破棄する。
+	rateLimiter := newRateLimiter(config)
+	handlers.UseRateLimiter(rateLimiter)
+	stopRateLimiterReaper := rateLimiter.StartReaper()
+	defer stopRateLimiterReaper()
+
 	logger := servers.NewLogger()
-	server, err := servers.NewServer(8080, logger)
+	server, err := servers.NewServer(config.Port, logger)
 	if err != nil {
 		fmt.Println("Could not start server.")
 		return
 	}
+	if config.TLS.Enabled {
+		if err = useTLS(server, config); err != nil {
+			fmt.Println("Could not load TLS certificate.", err)
+			return
+		}
+	}
+	securityHeadersConfig := servers.SecurityHeadersConfig{
+		ContentSecurityPolicy: config.SecurityHeaders.ContentSecurityPolicy,
+		ReferrerPolicy:        config.SecurityHeaders.ReferrerPolicy,
+		StripHeaders:          config.SecurityHeaders.StripHeaders,
+	}
+	server.Use(
+		handlers.CacheAuthentication,
+		servers.SecurityHeaders(securityHeadersConfig, handlers.HasCredentials),
+		servers.CORS(newCORSConfig(config)),
+		handlers.BearerChallenge,
+		rateLimiter.Middleware,
+	)
 	server.AddHandlers(users.GetHandlers())
 	server.AddHandlers(backups.GetHandlers())
+	server.AddHandlers(admin.GetHandlers())
 	server.Start()
 }
+
+// newKeyRing は、設定されたマスター鍵を読み込み、キーリングを返却します。マスター鍵が設定されていない場合は、nilを返却します。
+func newKeyRing(config *configs.Config) (*security.KeyRing, error) {
+	if len(config.Encryption.MasterKeys) == 0 {
+		return nil, nil
+	}
+	keys := make([]*security.MasterKey, len(config.Encryption.MasterKeys))
+	for i, keyConfig := range config.Encryption.MasterKeys {
+		key, err := security.LoadMasterKey(keyConfig.Id, keyConfig.Path)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	return security.NewKeyRing(config.Encryption.ActiveMasterKeyId, keys)
+}
+
+// rotateMasterKey は、すべてのユーザの鍵を、有効なマスター鍵でラップし直します。暗号化する前に保存されたバックアップデータは、暗号化します。
+func rotateMasterKey(keyRing *security.KeyRing) {
+	if keyRing == nil {
+		fmt.Println("No master keys are configured.")
+		return
+	}
+	repos := dbBackups.NewBackupRepository(db.NewDBConnector(), keyRing)
+	result, err := repos.RotateMasterKey()
+	if result != nil {
+		fmt.Printf("Rewrapped %d user keys with master key '%s', rewrapped %d data keys with user keys and encrypted %d backups.\n",
+			result.RewrappedUserKeys, keyRing.ActiveKeyId(), result.RewrappedDataKeys, result.EncryptedBackups)
+	}
+	if err != nil {
+		fmt.Println("Could not rotate master key.", err)
+		os.Exit(1)
+	}
+}
+
+// newSessionStore は、設定に応じたセッションの保存先を返却します。
+func newSessionStore(config *configs.Config) security.ISessionStore {
+	if config.Sessions.Store == configs.StoreDatabase {
+		return sessions.NewSessionRepository(db.NewDBConnector())
+	}
+	return security.NewMemorySessionStore()
+}
+
+// newPersonalTokens は、設定に応じたパーソナルアクセストークンの保存先のPersonalTokensを返却します。
+func newPersonalTokens(config *configs.Config) *security.PersonalTokens {
+	var store security.IPersonalTokenStore = security.NewMemoryPersonalTokenStore()
+	if config.PersonalTokens.Store == configs.StoreDatabase {
+		store = personaltokens.NewPersonalTokenRepository(db.NewDBConnector())
+	}
+	return security.NewPersonalTokens(store, config.PersonalTokens.MaxLifetime.Duration, security.SystemClock{})
+}
+
+// newClientCertificates は、設定に応じたクライアント証明書の保存先のClientCertificatesを返却します。
+func newClientCertificates(config *configs.Config) *security.ClientCertificates {
+	var store security.IClientCertificateStore = security.NewMemoryClientCertificateStore()
+	if config.TLS.ClientAuth.Store == configs.StoreDatabase {
+		store = clientcertificates.NewClientCertificateRepository(db.NewDBConnector())
+	}
+	return security.NewClientCertificates(store, security.SystemClock{})
+}
+
+// newCookieConfig は、設定に応じたクッキーモードのクッキーの属性を返却します。
+func newCookieConfig(config *configs.Config) handlers.CookieConfig {
+	sameSite := http.SameSiteStrictMode
+	switch config.Auth.Cookie.SameSite {
+	case configs.SameSiteLax:
+		sameSite = http.SameSiteLaxMode
+	case configs.SameSiteNone:
+		sameSite = http.SameSiteNoneMode
+	}
+	return handlers.CookieConfig{Secure: config.Auth.Cookie.Secure, SameSite: sameSite}
+}
+
+// newPolicy は、設定に応じたパスワードとサインインIDのポリシーを返却します。
+func newPolicy(config *configs.Config) (*domainUsers.Policy, error) {
+	passwordConfig := config.Policy.Password
+	policy := &domainUsers.Policy{
+		Password: domainUsers.PasswordPolicy{
+			MinLength:           passwordConfig.MinLength,
+			MaxLength:           passwordConfig.MaxLength,
+			MinCharacterClasses: passwordConfig.MinCharacterClasses,
+			Denylist:            domainUsers.PasswordDenylist{},
+			ForbidSignInId:      passwordConfig.ForbidSignInId,
+		},
+		SignInId: domainUsers.SignInIdPolicy{
+			MinLength: config.Policy.SignInId.MinLength,
+			MaxLength: config.Policy.SignInId.MaxLength,
+		},
+	}
+	for _, name := range passwordConfig.RequiredCharacterClasses {
+		class, err := domainUsers.NewCharacterClass(name)
+		if err != nil {
+			return nil, err
+		}
+		policy.Password.RequiredCharacterClasses = append(policy.Password.RequiredCharacterClasses, class)
+	}
+	if passwordConfig.DenylistPath != "" {
+		file, err := os.Open(passwordConfig.DenylistPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		policy.Password.Denylist, err = domainUsers.ReadPasswordDenylist(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.Policy.SignInId.Pattern != "" {
+		pattern, err := regexp.Compile(config.Policy.SignInId.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		policy.SignInId.Pattern = pattern
+	}
+	return policy, nil
+}
+
+// newLockout は、設定に応じた認証の失敗の記録の保存先のLockoutを返却します。
+func newLockout(config *configs.Config) *security.Lockout {
+	var store security.IAttemptStore = security.NewMemoryAttemptStore()
+	if config.Auth.Lockout.Store == configs.StoreDatabase {
+		store = attempts.NewAttemptRepository(db.NewDBConnector())
+	}
+	lockoutConfig := security.LockoutConfig{
+		MaxFailuresPerSignInId: config.Auth.Lockout.MaxFailuresPerSignInId,
+		MaxFailuresPerAddress:  config.Auth.Lockout.MaxFailuresPerAddress,
+		BaseDelay:              config.Auth.Lockout.BaseDelay.Duration,
+		MaxDelay:               config.Auth.Lockout.MaxDelay.Duration,
+		ResetAfter:             config.Auth.Lockout.ResetAfter.Duration,
+		ReapInterval:           config.Sessions.ReapInterval.Duration,
+	}
+	return security.NewLockout(store, lockoutConfig, security.SystemClock{})
+}
+
+// useTLS は、設定に応じてサーバをHTTPSでサーブするよう設定します。
+// 自己署名証明書を使用する設定で、証明書のファイルが存在しない場合は、開発用の自己署名証明書を生成します。
+func useTLS(server *servers.Server, config *configs.Config) error {
+	if config.TLS.SelfSigned {
+		if _, err := os.Stat(config.TLS.CertFile); os.IsNotExist(err) {
+			if err = servers.GenerateSelfSignedCertificate(config.TLS.CertFile, config.TLS.KeyFile); err != nil {
+				return err
+			}
+			fmt.Println("Generated a self-signed certificate for development:", config.TLS.CertFile)
+		}
+	}
+	return server.UseTLS(servers.TLSConfig{
+		CertFile:              config.TLS.CertFile,
+		KeyFile:               config.TLS.KeyFile,
+		RedirectPort:          config.TLS.RedirectPort,
+		HSTSMaxAge:            config.TLS.HSTS.MaxAge.Duration,
+		HSTSIncludeSubDomains: config.TLS.HSTS.IncludeSubDomains,
+		ReloadInterval:        config.TLS.ReloadInterval.Duration,
+		ClientAuthPort:        config.TLS.ClientAuth.Port,
+		ClientCAFile:          config.TLS.ClientAuth.CAFile,
+	})
+}
+
+// newCORSConfig は、設定に応じたCORSの設定を返却します。
+func newCORSConfig(config *configs.Config) servers.CORSConfig {
+	return servers.CORSConfig{
+		AllowedOrigins:   config.CORS.AllowedOrigins,
+		AllowedMethods:   config.CORS.AllowedMethods,
+		AllowedHeaders:   config.CORS.AllowedHeaders,
+		ExposedHeaders:   config.CORS.ExposedHeaders,
+		AllowCredentials: config.CORS.AllowCredentials,
+		MaxAge:           config.CORS.MaxAge.Duration,
+	}
+}
+
+// newRateLimiter は、設定に応じたリクエスト数の制限のRateLimiterを返却します。
+func newRateLimiter(config *configs.Config) *servers.RateLimiter {
+	toRule := func(rule configs.RateLimitRuleConfig) servers.RateLimitRule {
+		return servers.RateLimitRule{Requests: rule.Requests, Per: rule.Per.Duration, Burst: rule.Burst}
+	}
+	rateLimitConfig := servers.RateLimitConfig{
+		Default:      toRule(config.RateLimit.Default),
+		Routes:       make(map[string]servers.RateLimitRule, len(config.RateLimit.Routes)),
+		ReapInterval: config.Sessions.ReapInterval.Duration,
+	}
+	for pattern, rule := range config.RateLimit.Routes {
+		rateLimitConfig.Routes[pattern] = toRule(rule)
+	}
+	return servers.NewRateLimiter(rateLimitConfig, handlers.RateLimitKey, security.SystemClock{})
+}
+
+// newTokens は、設定に応じたアクセストークンの形式のTokensを返却します。
+func newTokens(config *configs.Config, sessionConfig security.SessionConfig) (*security.Tokens, error) {
+	store := newSessionStore(config)
+	if config.Sessions.AccessTokenMode != configs.AccessTokenModeSigned {
+		return security.NewTokens(store, sessionConfig, security.SystemClock{}), nil
+	}
+
+	keys := make([]*security.SigningKey, len(config.Sessions.SigningKeys))
+	for i, keyConfig := range config.Sessions.SigningKeys {
+		key, err := security.LoadSigningKey(keyConfig.Id, keyConfig.Algorithm, keyConfig.Path)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	signer, err := security.NewTokenSigner(config.Sessions.ActiveSigningKeyId, keys)
+	if err != nil {
+		return nil, err
+	}
+	return security.NewSignedTokens(store, sessionConfig, security.SystemClock{}, signer), nil
+}